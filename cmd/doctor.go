@@ -0,0 +1,353 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tara-vision/taracode/internal/assistant"
+	"github.com/tara-vision/taracode/internal/provider"
+	"github.com/tara-vision/taracode/internal/storage"
+	"github.com/tara-vision/taracode/internal/ui"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose configuration and the connection to the LLM server",
+	Long: `Doctor walks through everything taracode needs to work and explains
+what it finds:
+
+  - which config source (flag, environment, config file) set each value
+  - DNS, TCP and TLS timing to the host
+  - which server type was detected and why
+  - the models the server lists
+  - a tiny completion and a tiny tool-call round trip
+  - model capabilities (refreshing the cache in ~/.taracode/)
+  - integrity of the project's .taracode/ directory
+
+Each failure comes with a suggested fix.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if failures := runDoctor(); failures > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctorReport collects check results and the fixes to suggest at the end
+type doctorReport struct {
+	renderer *ui.Renderer
+	fixes    []string
+	failures int
+}
+
+func (r *doctorReport) section(title string) {
+	fmt.Println()
+	fmt.Println(ui.TitleStyle.Render(title))
+}
+
+func (r *doctorReport) pass(msg string) {
+	fmt.Println("  " + r.renderer.SuccessMessage(msg))
+}
+
+func (r *doctorReport) info(msg string) {
+	fmt.Println("  " + ui.Subtle.Render(msg))
+}
+
+func (r *doctorReport) warn(msg, fix string) {
+	fmt.Println("  " + r.renderer.WarningMessage(msg))
+	if fix != "" {
+		r.fixes = append(r.fixes, fix)
+	}
+}
+
+func (r *doctorReport) fail(msg, fix string) {
+	fmt.Println("  " + ui.ToolError.Render(fmt.Sprintf("%s %s", ui.IconError, msg)))
+	r.failures++
+	if fix != "" {
+		r.fixes = append(r.fixes, fix)
+	}
+}
+
+// runDoctor runs all checks and returns the number of failures
+func runDoctor() int {
+	report := &doctorReport{renderer: ui.NewRenderer()}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if host := checkConfig(report); host != "" {
+		checkServer(ctx, report, host)
+	}
+	checkStorage(report)
+
+	report.section("Summary")
+	if report.failures == 0 && len(report.fixes) == 0 {
+		report.pass("Everything looks good")
+		return 0
+	}
+	if report.failures > 0 {
+		fmt.Println("  " + ui.ToolError.Render(fmt.Sprintf("%s %d check(s) failed", ui.IconError, report.failures)))
+	}
+	if len(report.fixes) > 0 {
+		fmt.Println("  " + ui.SessionStyle.Render(ui.IconTip+" Suggested fixes:"))
+		for _, fix := range report.fixes {
+			fmt.Println("    - " + fix)
+		}
+	}
+	return report.failures
+}
+
+// configSource reports which source supplied a setting, following viper's
+// precedence: flag, then environment, then config file, then default
+func configSource(key, flagName string) string {
+	if flag := rootCmd.PersistentFlags().Lookup(flagName); flag != nil && flag.Changed {
+		return "--" + flagName + " flag"
+	}
+	if _, ok := os.LookupEnv("TARACODE_" + strings.ToUpper(key)); ok {
+		return "TARACODE_" + strings.ToUpper(key) + " env"
+	}
+	if viper.InConfig(key) {
+		return "config file"
+	}
+	return "default"
+}
+
+// checkConfig prints where each setting came from and returns the host
+func checkConfig(report *doctorReport) string {
+	report.section("Configuration")
+
+	if file := viper.ConfigFileUsed(); file != "" {
+		if _, err := os.Stat(file); err == nil {
+			report.info("Config file: " + file)
+		} else {
+			report.warn("Config file not found: "+file, "create "+file+" or drop the --config flag")
+		}
+	} else {
+		report.info("Config file: none (~/.taracode/config.yaml not found)")
+	}
+
+	settings := []struct{ key, flag string }{
+		{"host", "host"},
+		{"key", "key"},
+		{"model", "model"},
+		{"vendor", "vendor"},
+		{"no_stream", "no-stream"},
+		{"no_spinner", "no-spinner"},
+	}
+	for _, s := range settings {
+		value := viper.GetString(s.key)
+		if s.key == "key" && value != "" {
+			value = maskSecret(value)
+		}
+		if value == "" {
+			value = "(not set)"
+		}
+		report.info(fmt.Sprintf("%-10s = %-32s from %s", s.key, value, configSource(s.key, s.flag)))
+	}
+
+	host := viper.GetString("host")
+	if host == "" {
+		report.fail("No LLM server host configured",
+			"set the host: export TARACODE_HOST=http://localhost:11434, add host: to ~/.taracode/config.yaml, or pass --host")
+		return ""
+	}
+	if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
+		report.fail(fmt.Sprintf("Host %q has no scheme", host),
+			fmt.Sprintf("use a full URL such as http://%s", host))
+		return ""
+	}
+
+	if vendor := viper.GetString("vendor"); vendor != "" && vendor != "auto" &&
+		provider.ParseVendorConfig(vendor) == provider.TypeUnknown {
+		report.warn(fmt.Sprintf("Unknown vendor %q will be auto-detected", vendor),
+			"set vendor to one of: vllm, ollama, llama.cpp, openai, anthropic (or leave it empty)")
+	}
+	return host
+}
+
+// checkServer runs the network, detection and model checks, stopping at the
+// first failure that makes the later checks meaningless
+func checkServer(ctx context.Context, report *doctorReport, host string) {
+	report.section("Network")
+	timing, err := provider.TraceHost(ctx, host)
+	if err != nil {
+		report.fail(fmt.Sprintf("Cannot reach %s: %v", host, err), networkFix(host, err))
+		return
+	}
+	if len(timing.Addresses) > 0 {
+		report.pass(fmt.Sprintf("DNS resolved to %s in %s", strings.Join(timing.Addresses, ", "), formatDuration(timing.DNS)))
+	}
+	report.pass(fmt.Sprintf("TCP connected to %s in %s", timing.RemoteAddr, formatDuration(timing.Connect)))
+	if timing.TLSVersion != "" {
+		report.pass(fmt.Sprintf("TLS handshake (%s) in %s", timing.TLSVersion, formatDuration(timing.TLS)))
+	}
+	report.pass(fmt.Sprintf("First byte after %s (HTTP %d)", formatDuration(timing.FirstByte), timing.Status))
+
+	report.section("Server")
+	providerType := provider.ParseVendorConfig(viper.GetString("vendor"))
+	if providerType == provider.TypeUnknown {
+		detection := provider.DetectWithReason(ctx, host)
+		providerType = detection.Type
+		if providerType == provider.TypeUnknown {
+			report.warn("Could not identify the server type: "+detection.Reason,
+				"set vendor explicitly (vllm, ollama, llama.cpp, openai, anthropic) with --vendor or in config")
+		} else {
+			report.pass(fmt.Sprintf("Detected %s: %s", providerType.DisplayName(), detection.Reason))
+		}
+	} else {
+		report.pass(fmt.Sprintf("Using configured vendor %s (%s)", providerType.DisplayName(), configSource("vendor", "vendor")))
+	}
+
+	prov, err := provider.NewWithType(providerType, host, viper.GetString("key"))
+	if err != nil {
+		report.fail(err.Error(), "")
+		return
+	}
+
+	report.section("Models")
+	models, err := prov.DetectModels(ctx)
+	if err != nil {
+		fix := "check that the server exposes an OpenAI-compatible /v1/models endpoint"
+		if strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "403") {
+			fix = "the server rejected the API key; set it with --key, TARACODE_KEY or key: in config"
+		}
+		report.fail(fmt.Sprintf("Could not list models: %v", err), fix)
+		return
+	}
+	if len(models) == 0 {
+		report.fail("Server lists no models", "load or pull a model on the server (e.g. ollama pull qwen3:30b)")
+		return
+	}
+	report.pass(fmt.Sprintf("%d model(s): %s", len(models), summarizeList(models, 5)))
+
+	modelName := viper.GetString("model")
+	switch {
+	case modelName == "":
+		modelName = models[0]
+		report.info("No model configured; taracode will use " + modelName)
+	case !containsString(models, modelName):
+		report.warn(fmt.Sprintf("Configured model %q is not served; taracode will fall back to %s", modelName, models[0]),
+			fmt.Sprintf("set model to one of the served models (e.g. model: %s)", models[0]))
+		modelName = models[0]
+	default:
+		report.pass("Configured model " + modelName + " is available")
+	}
+	prov.SetModel(modelName)
+	client := prov.CreateClient()
+
+	report.section("Model round trip")
+	start := time.Now()
+	reply, err := assistant.CheckCompletion(ctx, client, modelName)
+	if err != nil {
+		report.fail(fmt.Sprintf("Completion failed: %v", err),
+			"check the server logs; the model may have failed to load or run out of memory")
+		return
+	}
+	report.pass(fmt.Sprintf("Completion replied %q in %s", truncateReply(reply), formatDuration(time.Since(start))))
+
+	start = time.Now()
+	detail, err := assistant.CheckToolRoundTrip(ctx, client, modelName)
+	if err != nil {
+		report.fail(fmt.Sprintf("Tool-call round trip failed: %v", err),
+			"use a model with reliable tool calling (qwen3:30b is recommended); smaller models often ignore the tool format")
+	} else {
+		report.pass(fmt.Sprintf("Tool-call round trip: %s in %s", detail, formatDuration(time.Since(start))))
+	}
+
+	report.section("Capabilities")
+	caps := provider.Probe(ctx, prov)
+	fmt.Print(report.renderer.CapabilityReport(caps))
+	if !caps.NativeTools {
+		report.info("Native tool calls are not required: taracode's tools use the JSON format checked above")
+	}
+	if err := provider.SaveCapabilities(caps); err != nil {
+		report.warn(fmt.Sprintf("Could not cache capabilities: %v", err), "check that ~/.taracode is writable")
+	}
+}
+
+// checkStorage validates the project's .taracode directory
+func checkStorage(report *doctorReport) {
+	report.section("Project storage")
+	workingDir, err := os.Getwd()
+	if err != nil {
+		report.fail(fmt.Sprintf("Cannot determine working directory: %v", err), "")
+		return
+	}
+
+	if _, err := os.Stat(filepath.Join(workingDir, ".taracode")); os.IsNotExist(err) {
+		report.info("No .taracode/ directory yet; it is created on first run")
+		return
+	}
+
+	issues := storage.CheckIntegrity(workingDir)
+	if len(issues) == 0 {
+		report.pass(".taracode/ is consistent")
+		return
+	}
+	for _, issue := range issues {
+		report.fail(fmt.Sprintf("%s: %s", issue.Path, issue.Problem), issue.Fix)
+	}
+}
+
+// networkFix suggests a fix for a connection error
+func networkFix(host string, err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "no such host"):
+		return fmt.Sprintf("the hostname in %s does not resolve; check for typos or your DNS/VPN", host)
+	case strings.Contains(msg, "connection refused"):
+		return "nothing is listening on that port; start the server or fix the port (Ollama uses 11434, vLLM 8000, llama.cpp 8080)"
+	case strings.Contains(msg, "certificate"):
+		return "the TLS certificate is not trusted; use http:// for local servers or install the CA certificate"
+	case strings.Contains(msg, "timeout") || strings.Contains(msg, "deadline"):
+		return "the host did not answer in time; check firewalls and that the server is running"
+	default:
+		return "check that the server is running and reachable from this machine"
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
+
+func maskSecret(s string) string {
+	if len(s) <= 8 {
+		return "****"
+	}
+	return s[:4] + "…" + s[len(s)-4:]
+}
+
+func summarizeList(items []string, max int) string {
+	if len(items) <= max {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:max], ", "), len(items)-max)
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func truncateReply(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > 40 {
+		return s[:37] + "..."
+	}
+	return s
+}
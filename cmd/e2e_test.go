@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tara-vision/taracode/internal/llmtest"
+	"github.com/tara-vision/taracode/internal/provider"
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+// taracodeBin is the binary built once for the end-to-end tests
+var taracodeBin string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "taracode-e2e")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	taracodeBin = filepath.Join(dir, "taracode")
+	build := exec.Command("go", "build", "-o", taracodeBin, "github.com/tara-vision/taracode")
+	if out, err := build.CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "building taracode: %v\n%s", err, out)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// result is the outcome of one taracode run
+type result struct {
+	stdout, stderr string
+	exitCode       int
+}
+
+// runTaracode runs the binary against server in a fresh home and project
+// directory. Capabilities are pre-cached so probing does not consume
+// scripted replies.
+func runTaracode(t *testing.T, server *llmtest.Server, project, stdin string, args ...string) result {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	err := provider.SaveCapabilities(&provider.Capabilities{
+		Host:        server.URL,
+		Model:       llmtest.DefaultModel,
+		StreamUsage: true,
+		ProbedAt:    time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(taracodeBin, append([]string{"--no-spinner"}, args...)...)
+	cmd.Dir = project
+	cmd.Env = append(os.Environ(), "HOME="+home, "TARACODE_HOST="+server.URL, "NO_COLOR=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err = cmd.Run()
+	res := result{stdout: stdout.String(), stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		res.exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("running taracode: %v", err)
+	}
+	return res
+}
+
+func TestPromptModeAnswer(t *testing.T) {
+	for _, mode := range []string{"stream", "no-stream"} {
+		t.Run(mode, func(t *testing.T) {
+			server := llmtest.New(t, llmtest.Think("The user greets me.", "Hello from the fake model."))
+			args := []string{"-p", "hi there"}
+			if mode == "no-stream" {
+				args = append(args, "--no-stream")
+			}
+
+			res := runTaracode(t, server, t.TempDir(), "", args...)
+			if res.exitCode != 0 {
+				t.Fatalf("exit %d\nstdout: %s\nstderr: %s", res.exitCode, res.stdout, res.stderr)
+			}
+			if !strings.Contains(res.stdout, "Hello from the fake model.") {
+				t.Errorf("answer missing from output:\n%s", res.stdout)
+			}
+			if strings.Contains(res.stdout, "greets me") {
+				t.Errorf("reasoning shown by default:\n%s", res.stdout)
+			}
+			if got := server.LastMessage(0); got != "hi there" {
+				t.Errorf("prompt sent as %q", got)
+			}
+		})
+	}
+}
+
+func TestPromptModeToolLoop(t *testing.T) {
+	server := llmtest.New(t,
+		llmtest.Tool("write_file", map[string]any{"file_path": "notes.txt", "content": "remember this\n"}),
+		llmtest.Text("Wrote notes.txt."),
+	)
+	project := t.TempDir()
+
+	res := runTaracode(t, server, project, "", "-p", "save a note")
+	if res.exitCode != 0 {
+		t.Fatalf("exit %d\nstderr: %s", res.exitCode, res.stderr)
+	}
+	data, err := os.ReadFile(filepath.Join(project, "notes.txt"))
+	if err != nil || string(data) != "remember this\n" {
+		t.Errorf("notes.txt = %q, %v", data, err)
+	}
+	if !strings.HasPrefix(server.LastMessage(1), "Tool result:") {
+		t.Errorf("tool result not sent back: %q", server.LastMessage(1))
+	}
+	if !strings.Contains(res.stdout, "Wrote notes.txt.") {
+		t.Errorf("final answer missing:\n%s", res.stdout)
+	}
+}
+
+func TestPromptModeFromStdin(t *testing.T) {
+	server := llmtest.New(t, llmtest.Text("Got it."))
+
+	res := runTaracode(t, server, t.TempDir(), "explain this\n", "-p", "-")
+	if res.exitCode != 0 {
+		t.Fatalf("exit %d\nstderr: %s", res.exitCode, res.stderr)
+	}
+	if got := server.LastMessage(0); got != "explain this" {
+		t.Errorf("prompt sent as %q", got)
+	}
+}
+
+func TestPromptModeMalformedResponse(t *testing.T) {
+	server := llmtest.New(t, llmtest.Reply{Raw: `{"choices": [`})
+
+	res := runTaracode(t, server, t.TempDir(), "", "-p", "hi", "--no-stream")
+	if res.exitCode != 1 {
+		t.Errorf("exit %d, want 1\nstdout: %s", res.exitCode, res.stdout)
+	}
+	if !strings.Contains(res.stderr, "Error:") {
+		t.Errorf("error not reported:\n%s", res.stderr)
+	}
+}
+
+func TestPromptModeServerError(t *testing.T) {
+	server := llmtest.New(t, llmtest.Error(500, "model crashed"))
+
+	res := runTaracode(t, server, t.TempDir(), "", "-p", "hi")
+	if res.exitCode != 1 || !strings.Contains(res.stderr, "model crashed") {
+		t.Errorf("exit %d, stderr:\n%s", res.exitCode, res.stderr)
+	}
+}
+
+func TestPromptModeEstimatesMissingUsage(t *testing.T) {
+	server := llmtest.New(t, llmtest.Reply{Content: "No usage here.", OmitUsage: true})
+	project := t.TempDir()
+
+	res := runTaracode(t, server, project, "", "-p", "hi")
+	if res.exitCode != 0 {
+		t.Fatalf("exit %d\nstderr: %s", res.exitCode, res.stderr)
+	}
+
+	mgr, err := storage.NewManager(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := mgr.GetActiveSession()
+	if err != nil || session == nil {
+		t.Fatalf("no session recorded: %v", err)
+	}
+	last := session.Messages[len(session.Messages)-1]
+	if last.Usage == nil || !last.Usage.Estimated || last.Usage.CompletionTokens == 0 {
+		t.Errorf("usage not estimated: %+v", last.Usage)
+	}
+}
+
+func TestREPL(t *testing.T) {
+	server := llmtest.New(t,
+		llmtest.Tool("list_files", map[string]any{"directory": "."}),
+		llmtest.Text("The project has a main.go file."),
+	)
+	project := t.TempDir()
+	os.WriteFile(filepath.Join(project, "main.go"), []byte("package main\n"), 0644)
+
+	res := runTaracode(t, server, project, "/status\nwhat files are here?\nexit\n")
+	if res.exitCode != 0 {
+		t.Fatalf("exit %d\nstderr: %s", res.exitCode, res.stderr)
+	}
+	for _, want := range []string{"Tool loop: 10 iterations", "The project has a main.go file.", "Goodbye!"} {
+		if !strings.Contains(res.stdout, want) {
+			t.Errorf("output missing %q:\n%s", want, res.stdout)
+		}
+	}
+	if !strings.Contains(server.LastMessage(1), "main.go") {
+		t.Errorf("list_files result not sent back: %q", server.LastMessage(1))
+	}
+}
+
+func TestOllamaServer(t *testing.T) {
+	server := llmtest.NewOllama(t, llmtest.Text("Hi from Ollama."))
+
+	res := runTaracode(t, server, t.TempDir(), "", "-p", "hi")
+	if res.exitCode != 0 {
+		t.Fatalf("exit %d\nstderr: %s", res.exitCode, res.stderr)
+	}
+	if !strings.Contains(res.stdout, "Hi from Ollama.") {
+		t.Errorf("answer missing:\n%s", res.stdout)
+	}
+	if got := provider.Detect(context.Background(), server.URL); got != provider.TypeOllama {
+		t.Errorf("detected %s, want ollama", got)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server := llmtest.New(t,
+		llmtest.Tool("write_file", map[string]any{"file_path": "notes.txt", "content": "remember this\n"}),
+		llmtest.Text("Wrote notes.txt."),
+	)
+	cassettePath := filepath.Join(t.TempDir(), "session.json")
+
+	res := runTaracode(t, server, t.TempDir(), "", "-p", "save a note", "--record", cassettePath)
+	if res.exitCode != 0 {
+		t.Fatalf("record: exit %d\nstderr: %s", res.exitCode, res.stderr)
+	}
+
+	// Playing back the recording matches it without contacting the server
+	res = runTaracode(t, server, t.TempDir(), "", "replay", cassettePath)
+	if res.exitCode != 0 || !strings.Contains(res.stdout, "All turns match") {
+		t.Fatalf("playback: exit %d\nstdout: %s\nstderr: %s", res.exitCode, res.stdout, res.stderr)
+	}
+	if n := len(server.Requests()); n != 2 {
+		t.Errorf("playback sent requests: %d in total, want 2", n)
+	}
+
+	// A live model that answers differently is reported, and the recorded
+	// tool result stands in for writing the file
+	server.Enqueue(
+		llmtest.Tool("write_file", map[string]any{"file_path": "notes.txt", "content": "remember this\n"}),
+		llmtest.Text("Saved your note."),
+	)
+	project := t.TempDir()
+	res = runTaracode(t, server, project, "", "replay", "--live", cassettePath)
+	if res.exitCode != 1 {
+		t.Fatalf("live replay: exit %d, want 1\nstdout: %s\nstderr: %s", res.exitCode, res.stdout, res.stderr)
+	}
+	for _, want := range []string{"Turn 1: save a note", "Answer replayed: Saved your note.", "1 of 1 turns differ"} {
+		if !strings.Contains(res.stdout, want) {
+			t.Errorf("report missing %q:\n%s", want, res.stdout)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(project, "notes.txt")); err == nil {
+		t.Error("live replay executed a recorded tool call")
+	}
+}
+
+func TestEval(t *testing.T) {
+	server := llmtest.New(t,
+		llmtest.Tool("write_file", map[string]any{"file_path": "notes.txt", "content": "remember this\n"}),
+		llmtest.Text("Wrote notes.txt."),
+	)
+	dir := t.TempDir()
+	suite := filepath.Join(dir, "suite.yaml")
+	os.WriteFile(suite, []byte(`
+name: notes
+cases:
+  - name: write-note
+    prompt: save a note
+    checks:
+      - file_contains: {path: notes.txt, text: remember}
+      - tool_called: write_file
+`), 0644)
+	results := filepath.Join(dir, "results.json")
+
+	res := runTaracode(t, server, t.TempDir(), "", "eval", suite, "--json", results)
+	if res.exitCode != 0 {
+		t.Fatalf("exit %d\nstdout: %s\nstderr: %s", res.exitCode, res.stdout, res.stderr)
+	}
+	for _, want := range []string{"test-model write-note", "100% passed (1/1)"} {
+		if !strings.Contains(res.stdout, want) {
+			t.Errorf("output missing %q:\n%s", want, res.stdout)
+		}
+	}
+	if data, err := os.ReadFile(results); err != nil || !strings.Contains(string(data), `"passed": true`) {
+		t.Errorf("results.json = %s, %v", data, err)
+	}
+}
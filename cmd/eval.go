@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tara-vision/taracode/internal/eval"
+	"github.com/tara-vision/taracode/internal/ui"
+)
+
+var (
+	evalModels []string
+	evalJSON   string
+)
+
+var evalCmd = &cobra.Command{
+	Use:   "eval <suite.yaml>",
+	Short: "Run a task suite and report pass rate, tokens and time per model",
+	Long: `Eval runs each case of a suite in a fresh workspace: it copies the case's
+fixture directory, writes its inline files, runs its setup commands, gives
+the agent the prompt, and then runs the case's checks:
+
+  file_exists, file_missing    a path in the workspace
+  file_contains                {path, text}
+  command                      a shell command that must exit 0
+  tool_called, tool_not_called a tool name
+
+Every case runs with each model listed in the suite (or given with
+--models), and the pass rate, tokens and time are reported per model.
+Eval exits with status 1 when any case fails.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		suite, err := eval.Load(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading suite: %v\n", err)
+			os.Exit(1)
+		}
+
+		runner := &eval.Runner{
+			Options:  loadOptions(),
+			Models:   evalModels,
+			Progress: printCaseResult,
+		}
+		fmt.Println(ui.SessionStyle.Render(fmt.Sprintf("%s Running %s (%d cases)", ui.IconInfo, suite.Name, len(suite.Cases))))
+		report := runner.Run(suite)
+		fmt.Print(formatEvalSummary(report))
+
+		if evalJSON != "" {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err == nil {
+				err = os.WriteFile(evalJSON, data, 0644)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing results: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if report.Failed() {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	evalCmd.Flags().StringSliceVar(&evalModels, "models", nil, "models to compare, overriding the suite (e.g. qwen3:8b,qwen3:30b)")
+	evalCmd.Flags().StringVar(&evalJSON, "json", "", "also write the results as JSON to this file")
+	rootCmd.AddCommand(evalCmd)
+}
+
+// printCaseResult prints one line per case as the suite runs, followed by
+// the reasons it failed
+func printCaseResult(result *eval.CaseResult) {
+	stats := ui.Subtle.Render(fmt.Sprintf("(%d requests, %d tokens, %s)",
+		result.Iterations, result.Usage.TotalTokens, formatMs(result.DurationMs)))
+	line := fmt.Sprintf("%s %s", result.Model, result.Case)
+	if result.Passed {
+		fmt.Println("  " + ui.SuccessStyle.Render(ui.IconSuccess+" "+line) + " " + stats)
+		return
+	}
+
+	fmt.Println("  " + ui.ToolError.Render(ui.IconError+" "+line) + " " + stats)
+	if result.Error != "" {
+		fmt.Println("      " + ui.ToolError.Render("error: "+result.Error))
+	}
+	for _, check := range result.Checks {
+		if check.Passed {
+			continue
+		}
+		detail := strings.ReplaceAll(check.Detail, "\n", "\n        ")
+		fmt.Printf("      %s %s: %s\n", ui.IconError, check.Check, detail)
+	}
+}
+
+// formatEvalSummary formats the per-model totals
+func formatEvalSummary(report *eval.Report) string {
+	var sb strings.Builder
+	sb.WriteString("\n" + ui.SessionStyle.Render("  By model") + "\n")
+	for _, s := range report.Summaries() {
+		sb.WriteString(fmt.Sprintf("    %-30s %3.0f%% passed (%d/%d)  %10d tokens  %8s\n",
+			s.Model, 100*s.PassRate(), s.Passed, s.Total, s.Tokens, formatMs(s.DurationMs)))
+	}
+	return sb.String()
+}
+
+// formatMs formats a millisecond duration to a tenth of a second
+func formatMs(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
@@ -2,11 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/manifoldco/promptui"
+	"github.com/tara-vision/taracode/internal/ignore"
 )
 
 // isInitializedProject checks if current directory has TARACODE.md and .taracode/
@@ -72,39 +74,26 @@ func (f *FileCompleter) Do(line []rune, pos int) (newLine [][]rune, length int)
 	return candidates, len(prefix)
 }
 
-// getFilesRecursive returns all files and directories in directory recursively
+// getFilesRecursive returns all files and directories in directory
+// recursively, leaving out hidden and ignored ones
 func getFilesRecursive(dir string) ([]string, error) {
 	var items []string
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
-
-		base := filepath.Base(path)
-
+	err := ignore.New(dir).Walk(dir, func(path string, d fs.DirEntry, err error) error {
 		// Skip hidden files/dirs
-		if strings.HasPrefix(base, ".") && base != "." {
-			if info.IsDir() {
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			if d.IsDir() {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		// Skip common directories
-		skipDirs := []string{"node_modules", "vendor", "__pycache__", "dist", "build"}
-		for _, skip := range skipDirs {
-			if info.IsDir() && base == skip {
-				return filepath.SkipDir
-			}
-		}
-
 		relPath, _ := filepath.Rel(dir, path)
 		if relPath == "." {
 			return nil
 		}
 
 		// Include both files and directories
-		if info.IsDir() {
+		if d.IsDir() {
 			items = append(items, relPath+"/") // Add trailing slash for dirs
 		} else {
 			items = append(items, relPath)
@@ -159,45 +148,32 @@ func selectFile(workingDir string) (string, error) {
 	return result, nil
 }
 
-// getFilesInDirectory returns all files in a specific directory (non-recursive or recursive based on flag)
+// getFilesInDirectory returns all files in a specific directory (non-recursive or recursive based on flag),
+// leaving out hidden and ignored ones
 func getFilesInDirectory(dir string, baseDir string, recursive bool) ([]string, error) {
 	var files []string
 
-	walkFn := func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
-
-		base := filepath.Base(path)
-
+	walkFn := func(path string, d fs.DirEntry, err error) error {
 		// Skip hidden files/dirs
-		if strings.HasPrefix(base, ".") && base != "." {
-			if info.IsDir() {
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			if d.IsDir() {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		// Skip common directories
-		skipDirs := []string{"node_modules", "vendor", "__pycache__", "dist", "build"}
-		for _, skip := range skipDirs {
-			if info.IsDir() && base == skip {
-				return filepath.SkipDir
-			}
-		}
-
 		// Skip the root directory itself
 		if path == dir {
 			return nil
 		}
 
 		// For non-recursive, skip subdirectories' contents
-		if !recursive && info.IsDir() {
+		if !recursive && d.IsDir() {
 			return filepath.SkipDir
 		}
 
 		// Only include files, not directories
-		if !info.IsDir() {
+		if !d.IsDir() {
 			relPath, _ := filepath.Rel(baseDir, path)
 			files = append(files, relPath)
 		}
@@ -205,7 +181,7 @@ func getFilesInDirectory(dir string, baseDir string, recursive bool) ([]string,
 		return nil
 	}
 
-	err := filepath.Walk(dir, walkFn)
+	err := ignore.New(baseDir).Walk(dir, walkFn)
 	return files, err
 }
 
@@ -291,6 +267,9 @@ func expandFileReferences(message string, workingDir string) (string, error) {
 		}
 
 		fullPath := filepath.Join(workingDir, filePath)
+		if ignore.New(workingDir).Hidden(fullPath) {
+			return "", fmt.Errorf("%s is hidden from the assistant by %s", filePath, ignore.FileName)
+		}
 
 		// Check if path is a directory
 		info, err := os.Stat(fullPath)
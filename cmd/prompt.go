@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tara-vision/taracode/internal/assistant"
+)
+
+// runPrompt answers a single message and exits, for scripts and CI.
+// A prompt of "-" is read from stdin. Tool-loop limits end the turn since
+// there is nobody to ask whether to keep going.
+func runPrompt(prompt string) {
+	if prompt == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading prompt from stdin: %v\n", err)
+			os.Exit(1)
+		}
+		prompt = string(data)
+	}
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		fmt.Fprintln(os.Stderr, "Error: empty prompt")
+		os.Exit(1)
+	}
+
+	opts := loadOptions()
+	opts.Quiet = true
+
+	workingDir, _ := os.Getwd()
+	if strings.Contains(prompt, "@") && isInitializedProject(workingDir) {
+		expanded, err := expandFileReferences(prompt, workingDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		prompt = expanded
+	}
+
+	asst, err := assistant.New(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing assistant: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := asst.ProcessMessage(prompt); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
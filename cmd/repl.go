@@ -10,35 +10,18 @@ import (
 	"github.com/chzyer/readline"
 	"github.com/spf13/viper"
 	"github.com/tara-vision/taracode/internal/assistant"
+	"github.com/tara-vision/taracode/internal/storage"
 	"github.com/tara-vision/taracode/internal/ui"
 )
 
-func startREPL() {
-	// Get configuration from config or environment
-	host := viper.GetString("host")
-	if host == "" {
-		fmt.Fprintln(os.Stderr, "Error: LLM server host not found.")
-		fmt.Fprintln(os.Stderr, "Set it via:")
-		fmt.Fprintln(os.Stderr, "  - Environment variable: export TARACODE_HOST=http://ollama.tara.lab")
-		fmt.Fprintln(os.Stderr, "  - Config file: ~/.taracode/config.yaml")
-		fmt.Fprintln(os.Stderr, "  - Command flag: --host http://ollama.tara.lab")
-		os.Exit(1)
-	}
-
-	// API key is optional for local servers
-	apiKey := viper.GetString("key")
-
-	// Model is optional - will be auto-detected from server
-	model := viper.GetString("model")
-
-	// Vendor is optional - will be auto-detected from host URL
-	vendor := viper.GetString("vendor")
+// replPrompt is the readline prompt for user input
+const replPrompt = "\033[34m❯\033[0m "
 
-	// Streaming is enabled by default (--no-stream to disable)
-	streaming := !viper.GetBool("no_stream")
+// planModePrompt replaces replPrompt while in plan mode
+const planModePrompt = "\033[33mplan ❯\033[0m "
 
-	// Spinner is enabled by default (--no-spinner to disable)
-	enableSpinner := !viper.GetBool("no_spinner")
+func startREPL() {
+	opts := loadOptions()
 
 	// Get working directory
 	workingDir, _ := os.Getwd()
@@ -57,8 +40,26 @@ func startREPL() {
 	}
 	fmt.Print(renderer.ProjectContextMessage(projectLoaded))
 
+	// Setup readline for interactive input with @ file completion
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          replPrompt,
+		HistoryFile:     os.Getenv("HOME") + "/.taracode/history",
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+		AutoComplete:    NewFileCompleter(workingDir),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting up readline: %v\n", err)
+		os.Exit(1)
+	}
+	defer rl.Close()
+
+	opts.Confirm = func(question string) bool {
+		return confirm(rl, question)
+	}
+
 	// Initialize the assistant
-	asst, err := assistant.New(host, apiKey, model, vendor, streaming, enableSpinner)
+	asst, err := assistant.New(opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing assistant: %v\n", err)
 		os.Exit(1)
@@ -75,22 +76,13 @@ func startREPL() {
 	}
 	fmt.Println()
 
-	// Setup readline for interactive input with @ file completion
-	rl, err := readline.NewEx(&readline.Config{
-		Prompt:          "\033[34m❯\033[0m ",
-		HistoryFile:     os.Getenv("HOME") + "/.taracode/history",
-		InterruptPrompt: "^C",
-		EOFPrompt:       "exit",
-		AutoComplete:    NewFileCompleter(workingDir),
-	})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error setting up readline: %v\n", err)
-		os.Exit(1)
-	}
-	defer rl.Close()
-
 	// Main REPL loop
 	for {
+		if asst.Mode() == assistant.ModePlan {
+			rl.SetPrompt(planModePrompt)
+		} else {
+			rl.SetPrompt(replPrompt)
+		}
 		line, err := rl.Readline()
 		if err != nil { // io.EOF or Ctrl+C
 			fmt.Println("\nGoodbye!")
@@ -119,7 +111,7 @@ func startREPL() {
 
 		// Handle built-in commands
 		if strings.HasPrefix(line, "/") {
-			handleCommand(line, workingDir, &asst, host, apiKey, model, vendor, streaming, enableSpinner)
+			handleCommand(line, workingDir, &asst, opts)
 			continue
 		}
 
@@ -136,7 +128,7 @@ func startREPL() {
 	}
 }
 
-func handleCommand(cmd string, workingDir string, asst **assistant.Assistant, host, apiKey, model, vendor string, streaming bool, enableSpinner bool) {
+func handleCommand(cmd string, workingDir string, asst **assistant.Assistant, opts assistant.Options) {
 	// Handle commands with arguments
 	parts := strings.Fields(cmd)
 	baseCmd := parts[0]
@@ -149,7 +141,7 @@ func handleCommand(cmd string, workingDir string, asst **assistant.Assistant, ho
 			return
 		}
 		// Reinitialize assistant to pick up new context
-		newAsst, err := assistant.New(host, apiKey, model, vendor, streaming, enableSpinner)
+		newAsst, err := assistant.New(opts)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error reinitializing assistant: %v\n", err)
 			return
@@ -175,6 +167,35 @@ func handleCommand(cmd string, workingDir string, asst **assistant.Assistant, ho
 		fmt.Println()
 		fmt.Println("  Plans:")
 		fmt.Println("    /plan         - Show active task plan")
+		fmt.Println("    /plan new <title> - Start a new plan (archives the active one)")
+		fmt.Println("    /plan add <task> - Add a task to the active plan")
+		fmt.Println("    /plan done <n> - Mark task n (or n.m) completed")
+		fmt.Println("    /plan edit <n> <task> - Change a task's text")
+		fmt.Println("    /plan rm <n>  - Remove a task")
+		fmt.Println("    /plan archive - Archive the active plan")
+		fmt.Println("    /mode plan    - Plan mode: read-only tools, the model proposes a plan")
+		fmt.Println("    /mode execute - Leave plan mode without approving")
+		fmt.Println("    /approve      - Approve the plan and start working through it")
+		fmt.Println()
+		fmt.Println("  Memory:")
+		fmt.Println("    /memory       - List remembered facts (project and user)")
+		fmt.Println("    /memory add [--user] <fact> [#tag...] - Remember a fact")
+		fmt.Println("    /memory edit <id> <fact> - Change a fact")
+		fmt.Println("    /memory forget <id> - Forget a fact")
+		fmt.Println()
+		fmt.Println("  Generation:")
+		fmt.Println("    /set          - Show generation parameters")
+		fmt.Println("    /set <param> <value> - Override a parameter (e.g., /set temperature 0.2)")
+		fmt.Println("    /set <param> default - Clear an override")
+		fmt.Println("    /set reset    - Clear all overrides")
+		fmt.Println("    /think on|off - Toggle model reasoning (Qwen3 thinking mode)")
+		fmt.Println("    /think show [full] - Show reasoning dimmed (collapsed or in full)")
+		fmt.Println("    /think hide   - Hide reasoning")
+		fmt.Println()
+		fmt.Println("  Verification:")
+		fmt.Println("    /verify       - Show the build check run after a turn's edits")
+		fmt.Println("    /verify on [command] - Check edits (default: the project's build, e.g. go build and go vet)")
+		fmt.Println("    /verify off   - Stop checking edits")
 		fmt.Println()
 		fmt.Println("  Other:")
 		fmt.Println("    /usage        - Show token usage statistics")
@@ -190,9 +211,12 @@ func handleCommand(cmd string, workingDir string, asst **assistant.Assistant, ho
 		usage := (*asst).GetUsage()
 		r := ui.NewRenderer()
 		fmt.Println(r.FormatUsage(usage))
+		if session := (*asst).GetSession(); session != nil {
+			fmt.Print(r.FormatUsageBreakdown(session.UsageByTurn(), 10))
+		}
 
 	case "/reload":
-		newAsst, err := assistant.New(host, apiKey, model, vendor, streaming, enableSpinner)
+		newAsst, err := assistant.New(opts)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error reloading: %v\n", err)
 			return
@@ -204,7 +228,7 @@ func handleCommand(cmd string, workingDir string, asst **assistant.Assistant, ho
 	case "/clear":
 		if err := (*asst).NewSession(""); err != nil {
 			// Fallback to creating new assistant
-			newAsst, err := assistant.New(host, apiKey, model, vendor, streaming, enableSpinner)
+			newAsst, err := assistant.New(opts)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error clearing: %v\n", err)
 				return
@@ -248,7 +272,34 @@ func handleCommand(cmd string, workingDir string, asst **assistant.Assistant, ho
 		handleStatus(*asst, workingDir)
 
 	case "/plan":
-		handleShowPlan(*asst)
+		handlePlan(*asst, args)
+
+	case "/mode":
+		handleMode(*asst, args)
+
+	case "/approve":
+		plan, err := (*asst).ApprovePlan()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Approved plan: %s\n\n", plan.Title)
+		if err := (*asst).ProcessMessage(assistant.ApprovedPlanMessage); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		fmt.Println()
+
+	case "/set":
+		handleSet(*asst, args)
+
+	case "/think":
+		handleThink(*asst, args)
+
+	case "/verify":
+		handleVerify(*asst, args)
+
+	case "/memory":
+		handleMemory(*asst, workingDir, args)
 
 	default:
 		fmt.Printf("Unknown command: %s\n", cmd)
@@ -257,6 +308,292 @@ func handleCommand(cmd string, workingDir string, asst **assistant.Assistant, ho
 	}
 }
 
+// handleMemory lists, adds, edits and forgets remembered facts
+func handleMemory(asst *assistant.Assistant, workingDir string, args []string) {
+	project := storage.ProjectMemory(workingDir)
+	user, err := storage.UserMemory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	stores := []*storage.MemoryStore{project, user}
+
+	switch {
+	case len(args) == 0:
+		for _, store := range stores {
+			memories, err := store.List()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return
+			}
+			label := "Project"
+			if store.Scope() == storage.MemoryUser {
+				label = "User"
+			}
+			fmt.Printf("%s memory:\n", label)
+			if len(memories) == 0 {
+				fmt.Println("  (none)")
+			}
+			for _, m := range memories {
+				tags := ""
+				if len(m.Tags) > 0 {
+					tags = " #" + strings.Join(m.Tags, " #")
+				}
+				fmt.Printf("  [%s] %s%s\n", m.ID, m.Content, tags)
+			}
+		}
+		fmt.Println()
+		return
+
+	case args[0] == "add" && len(args) > 1:
+		store := project
+		var words, tags []string
+		for _, arg := range args[1:] {
+			switch {
+			case arg == "--user":
+				store = user
+			case strings.HasPrefix(arg, "#") && len(arg) > 1:
+				tags = append(tags, arg[1:])
+			default:
+				words = append(words, arg)
+			}
+		}
+		m, err := store.Add(strings.Join(words, " "), tags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Remembered in %s memory [%s].\n", store.Scope(), m.ID)
+
+	case args[0] == "edit" && len(args) > 2:
+		var updated *storage.Memory
+		for _, store := range stores {
+			if updated, err = store.Update(args[1], strings.Join(args[2:], " ")); err != nil || updated != nil {
+				break
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return
+		}
+		if updated == nil {
+			fmt.Printf("No memory with id %s.\n\n", args[1])
+			return
+		}
+		fmt.Printf("Updated [%s].\n", updated.ID)
+
+	case (args[0] == "forget" || args[0] == "rm") && len(args) == 2:
+		var removed *storage.Memory
+		for _, store := range stores {
+			if removed, err = store.Remove(args[1]); err != nil || removed != nil {
+				break
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return
+		}
+		if removed == nil {
+			fmt.Printf("No memory with id %s.\n\n", args[1])
+			return
+		}
+		fmt.Printf("Forgot [%s] %s\n", removed.ID, removed.Content)
+
+	default:
+		fmt.Println("Usage: /memory [add [--user] <fact> [#tag...] | edit <id> <fact> | forget <id>]")
+		fmt.Println()
+		return
+	}
+
+	// Apply the change to the rest of this session
+	asst.RefreshSystemPrompt()
+	fmt.Println()
+}
+
+// handleSet shows or overrides generation parameters
+func handleSet(asst *assistant.Assistant, args []string) {
+	switch {
+	case len(args) == 0:
+		fmt.Printf("Generation parameters: %s\n", assistant.FormatParams(asst.GenerationParams()))
+		fmt.Printf("Session overrides:     %s\n", assistant.FormatParams(asst.SessionParams()))
+	case args[0] == "reset":
+		if err := asst.ResetParams(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return
+		}
+		fmt.Println("Generation overrides cleared.")
+	default:
+		value := ""
+		if len(args) > 1 {
+			value = strings.Join(args[1:], " ")
+		}
+		if err := asst.SetParam(args[0], value); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Generation parameters: %s\n", assistant.FormatParams(asst.GenerationParams()))
+	}
+	fmt.Println()
+}
+
+// handleThink shows or changes reasoning mode and display
+func handleThink(asst *assistant.Assistant, args []string) {
+	var err error
+	switch {
+	case len(args) == 0:
+	case args[0] == "on" || args[0] == "off":
+		err = asst.SetThinking(args[0] == "on")
+	case args[0] == "show" && len(args) > 1 && args[1] == "full":
+		err = asst.SetReasoningDisplay(assistant.ReasoningFull)
+	case args[0] == "show":
+		err = asst.SetReasoningDisplay(assistant.ReasoningCollapsed)
+	case args[0] == "hide":
+		err = asst.SetReasoningDisplay(assistant.ReasoningHide)
+	default:
+		fmt.Println("Usage: /think [on|off|show [full]|hide]")
+		fmt.Println()
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+
+	on, mechanism := asst.Thinking()
+	state := "on"
+	if !on {
+		state = "off"
+	}
+	if mechanism == assistant.ThinkingSwitchNone {
+		fmt.Printf("Thinking: %s (this model has no thinking switch; set thinking_switch in config to force one)\n", state)
+	} else {
+		fmt.Printf("Thinking: %s (via %s switch)\n", state, mechanism)
+	}
+	fmt.Printf("Reasoning display: %s\n", asst.ReasoningDisplay())
+	fmt.Println()
+}
+
+// handleVerify shows or changes the build check run after a turn's edits
+func handleVerify(asst *assistant.Assistant, args []string) {
+	var err error
+	switch {
+	case len(args) == 0:
+	case args[0] == "on":
+		err = asst.SetVerification(true, strings.Join(args[1:], " "))
+	case args[0] == "off" && len(args) == 1:
+		on, _ := asst.Verification()
+		if on {
+			err = asst.SetVerification(false, "")
+		}
+	default:
+		fmt.Println("Usage: /verify [on [command|default]|off]")
+		fmt.Println()
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+
+	on, command := asst.Verification()
+	switch {
+	case on:
+		fmt.Printf("Verification: on (%s after each turn that edits files)\n", command)
+	case command != "":
+		fmt.Printf("Verification: off (/verify on runs %s)\n", command)
+	default:
+		fmt.Println("Verification: off (no default check for this project; use /verify on <command>)")
+	}
+	fmt.Println()
+}
+
+// loadOptions builds assistant options from flags, environment and config,
+// exiting with setup instructions when no host is configured
+func loadOptions() assistant.Options {
+	// Get configuration from config or environment
+	host := viper.GetString("host")
+	if host == "" {
+		fmt.Fprintln(os.Stderr, "Error: LLM server host not found.")
+		fmt.Fprintln(os.Stderr, "Set it via:")
+		fmt.Fprintln(os.Stderr, "  - Environment variable: export TARACODE_HOST=http://ollama.tara.lab")
+		fmt.Fprintln(os.Stderr, "  - Config file: ~/.taracode/config.yaml")
+		fmt.Fprintln(os.Stderr, "  - Command flag: --host http://ollama.tara.lab")
+		os.Exit(1)
+	}
+
+	// Generation parameters are optional - server defaults apply otherwise
+	generation, models, err := loadGenerationConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading generation config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Tool-loop limits are optional - defaults apply otherwise
+	limits, err := loadLimitsConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading limits config: %v\n", err)
+		os.Exit(1)
+	}
+
+	return assistant.Options{
+		Host:          host,
+		APIKey:        viper.GetString("key"),             // Optional for local servers
+		Model:         viper.GetString("model"),           // Auto-detected from server when empty
+		EmbedModel:    viper.GetString("embedding_model"), // semantic_search; the chat model when empty
+		Vendor:        viper.GetString("vendor"),          // Auto-detected from the server when empty
+		Streaming:     !viper.GetBool("no_stream"),        // --no-stream to disable
+		EnableSpinner: !viper.GetBool("no_spinner"),       // --no-spinner to disable
+		Generation:    generation,
+		Models:        models,
+		Limits:        limits,
+		Record:        record, // --record to save a cassette
+	}
+}
+
+// loadGenerationConfig reads the generation: defaults and the per-model
+// models: list from config
+func loadGenerationConfig() (*storage.GenerationParams, []assistant.ModelConfig, error) {
+	var generation *storage.GenerationParams
+	if viper.IsSet("generation") {
+		generation = &storage.GenerationParams{}
+		if err := viper.UnmarshalKey("generation", generation); err != nil {
+			return nil, nil, fmt.Errorf("generation: %w", err)
+		}
+	}
+
+	var models []assistant.ModelConfig
+	if err := viper.UnmarshalKey("models", &models); err != nil {
+		return nil, nil, fmt.Errorf("models: %w", err)
+	}
+	return generation, models, nil
+}
+
+// loadLimitsConfig reads the tool-loop limits: block from config
+func loadLimitsConfig() (assistant.Limits, error) {
+	var limits assistant.Limits
+	if err := viper.UnmarshalKey("limits", &limits); err != nil {
+		return limits, fmt.Errorf("limits: %w", err)
+	}
+	return limits, nil
+}
+
+// confirm asks a yes/no question on the REPL's input line, defaulting to no
+func confirm(rl *readline.Instance, question string) bool {
+	rl.SetPrompt(question + " [y/N] ")
+	rl.HistoryDisable()
+	defer func() {
+		rl.SetPrompt(replPrompt)
+		rl.HistoryEnable()
+	}()
+
+	answer, err := rl.Readline()
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
 // handleSessionInfo displays current session information
 func handleSessionInfo(asst *assistant.Assistant) {
 	session := asst.GetSession()
@@ -314,7 +651,11 @@ func handleStatus(asst *assistant.Assistant, workingDir string) {
 		fmt.Printf("  Provider: %s (%s)\n", providerInfo.Name, providerInfo.Type)
 		fmt.Printf("  Host: %s\n", providerInfo.Host)
 		fmt.Printf("  Model: %s\n", providerInfo.Model)
+		fmt.Printf("  Generation: %s\n", assistant.FormatParams(asst.GenerationParams()))
 	}
+	limits := asst.Limits()
+	fmt.Printf("  Tool loop: %d iterations, %s per turn, %d identical calls\n", limits.MaxIterations, limits.TurnTimeout, limits.MaxRepeats)
+	fmt.Printf("  Tool output: %d bytes per result\n", limits.MaxToolOutput)
 
 	// Project info
 	taracodeFile := filepath.Join(workingDir, "TARACODE.md")
@@ -349,37 +690,129 @@ func handleStatus(asst *assistant.Assistant, workingDir string) {
 	fmt.Println()
 }
 
-// handleShowPlan displays the active task plan
-func handleShowPlan(asst *assistant.Assistant) {
-	storage := asst.GetStorage()
-	if storage == nil {
+// handleMode shows or switches between plan and execution mode
+func handleMode(asst *assistant.Assistant, args []string) {
+	if len(args) == 0 {
+		fmt.Printf("Mode: %s\n", asst.Mode())
+		fmt.Println("Usage: /mode plan|execute")
+		fmt.Println()
+		return
+	}
+
+	mode, err := assistant.ParseMode(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	asst.SetMode(mode)
+	if mode == assistant.ModePlan {
+		fmt.Println("Plan mode: only read-only tools will run. Describe the change; the model")
+		fmt.Println("investigates and proposes a plan. Edit it with /plan, then /approve it.")
+	} else {
+		fmt.Println("Execution mode: all tools are available.")
+	}
+	fmt.Println()
+}
+
+// handlePlan shows the active task plan or changes it with /plan new <title>,
+// /plan add <task>, /plan edit <n> <task>, /plan rm <n>, /plan done <n> and
+// /plan archive
+func handlePlan(asst *assistant.Assistant, args []string) {
+	plans := asst.GetStorage()
+	if plans == nil {
 		fmt.Println("Storage not initialized. Run /init first.")
 		fmt.Println()
 		return
 	}
 
-	plan, err := storage.GetActivePlan()
-	if err != nil || plan == nil {
-		fmt.Println("No active plan.")
+	var plan *storage.Plan
+	var err error
+	switch {
+	case len(args) == 0:
+		plan, err = plans.GetActivePlan()
+		if err != nil || plan == nil {
+			fmt.Println("No active plan. Create one with /plan new <title>.")
+			fmt.Println()
+			return
+		}
+
+	case args[0] == "new" && len(args) > 1:
+		if previous, _ := plans.GetActivePlan(); previous != nil {
+			if err := plans.ArchivePlan(previous.ID); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return
+			}
+			fmt.Printf("Archived plan: %s\n", previous.Title)
+		}
+		plan, err = plans.CreatePlanWithTasks(strings.Join(args[1:], " "), "", nil)
+
+	case args[0] == "add" && len(args) > 1:
+		plan, err = plans.UpdateActivePlan(func(plan *storage.Plan) error {
+			plan.Tasks = append(plan.Tasks, storage.NewTask(strings.Join(args[1:], " "), ""))
+			return nil
+		})
+
+	case args[0] == "edit" && len(args) > 2:
+		plan, err = plans.UpdateActivePlan(func(plan *storage.Plan) error {
+			task, _, err := plan.TaskAt(args[1])
+			if err == nil {
+				task.Content = strings.Join(args[2:], " ")
+			}
+			return err
+		})
+
+	case args[0] == "rm" && len(args) == 2:
+		plan, err = plans.UpdateActivePlan(func(plan *storage.Plan) error {
+			_, err := plan.RemoveTask(args[1])
+			return err
+		})
+
+	case args[0] == "done" && len(args) == 2:
+		plan, err = plans.UpdateActivePlan(func(plan *storage.Plan) error {
+			_, err := plan.SetTaskStatus(args[1], storage.TaskStatusCompleted)
+			return err
+		})
+
+	case args[0] == "archive" && len(args) == 1:
+		plan, err = plans.GetActivePlan()
+		if err == nil && plan == nil {
+			err = fmt.Errorf("no active plan")
+		}
+		if err == nil {
+			err = plans.ArchivePlan(plan.ID)
+		}
+		if err == nil {
+			fmt.Printf("Archived plan: %s\n\n", plan.Title)
+			asst.RefreshSystemPrompt()
+			return
+		}
+
+	default:
+		fmt.Println("Usage: /plan [new <title>|add <task>|edit <n> <task>|rm <n>|done <n>|archive]")
 		fmt.Println()
 		return
 	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	if len(args) > 0 {
+		asst.RefreshSystemPrompt()
+	}
 
-	fmt.Printf("Plan: %s\n", plan.Title)
-	fmt.Printf("Status: %s\n", plan.Status)
+	done, total := plan.Progress()
+	fmt.Printf("Plan: %s (%d/%d done)\n", plan.Title, done, total)
+	if plan.Description != "" {
+		fmt.Println(plan.Description)
+	}
 	fmt.Println()
-
-	for i, task := range plan.Tasks {
-		status := "[ ]"
-		switch task.Status {
-		case "completed":
-			status = "[x]"
-		case "in_progress":
-			status = "[>]"
-		case "skipped":
-			status = "[-]"
-		}
-		fmt.Printf("  %d. %s %s\n", i+1, status, task.Content)
+	if total == 0 {
+		fmt.Println("  No tasks yet. Add one with /plan add <task>.")
+	}
+	for _, line := range strings.Split(strings.TrimRight(plan.Outline(), "\n"), "\n") {
+		if line != "" {
+			fmt.Printf("  %s\n", line)
+		}
 	}
 	fmt.Println()
 }
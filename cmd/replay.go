@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tara-vision/taracode/internal/assistant"
+	"github.com/tara-vision/taracode/internal/cassette"
+	"github.com/tara-vision/taracode/internal/ui"
+)
+
+var (
+	replayLive     bool
+	replayRunTools bool
+	replayOut      string
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <cassette>",
+	Short: "Re-run a recorded session and report what changed",
+	Long: `Replay re-runs the user inputs of a session recorded with --record and
+compares the tool calls made and final answers with the recording.
+
+By default the recorded model responses are played back without contacting
+a server, which checks that taracode still handles them the same way. With
+--live the inputs are sent to the configured model instead, to see how a
+new model or system prompt behaves on the same session.
+
+Tool calls are answered with their recorded results so replays never change
+the workspace. A call with no recorded result fails unless --run-tools is
+given. Replay exits with status 1 when any turn differs.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		recorded, err := cassette.Load(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading cassette: %v\n", err)
+			os.Exit(1)
+		}
+
+		var opts assistant.Options
+		if replayLive {
+			opts = loadOptions()
+		}
+		replayed, err := assistant.Replay(recorded, opts, assistant.ReplayOptions{
+			Live:     replayLive,
+			RunTools: replayRunTools,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error replaying: %v\n", err)
+			os.Exit(1)
+		}
+
+		if replayOut != "" {
+			if err := replayed.Save(replayOut); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving replay: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		report := cassette.Compare(recorded, replayed)
+		fmt.Print(ui.NewRenderer().FormatReplayReport(report))
+		if report.Differences() > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	replayCmd.Flags().BoolVar(&replayLive, "live", false, "send the inputs to the configured model instead of playing back recorded responses")
+	replayCmd.Flags().BoolVar(&replayRunTools, "run-tools", false, "execute tool calls that have no recorded result")
+	replayCmd.Flags().StringVar(&replayOut, "out", "", "save the replay as a cassette to this file")
+	rootCmd.AddCommand(replayCmd)
+}
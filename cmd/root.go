@@ -16,6 +16,8 @@ var (
 	vendor    string
 	noStream  bool
 	noSpinner bool
+	prompt    string
+	record    string
 	Version   = "dev"
 )
 
@@ -26,6 +28,11 @@ var rootCmd = &cobra.Command{
 	Long: `Tara Code is a Claude Code-like CLI tool that provides an interactive
 AI-powered assistant for software development tasks.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		// One-shot mode answers a single prompt and exits
+		if cmd.Flags().Changed("prompt") {
+			runPrompt(prompt)
+			return
+		}
 		// Start interactive REPL mode
 		startREPL()
 	},
@@ -42,9 +49,11 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&host, "host", "", "LLM server URL (e.g., http://ollama.tara.lab)")
 	rootCmd.PersistentFlags().StringVar(&apiKey, "key", "", "API key (optional for local servers)")
 	rootCmd.PersistentFlags().StringVar(&model, "model", "", "model name (optional, auto-detected from server)")
-	rootCmd.PersistentFlags().StringVar(&vendor, "vendor", "", "LLM vendor (auto, vllm, ollama, llama.cpp)")
+	rootCmd.PersistentFlags().StringVar(&vendor, "vendor", "", "LLM vendor (auto, vllm, ollama, llama.cpp, openai, anthropic)")
 	rootCmd.PersistentFlags().BoolVar(&noStream, "no-stream", false, "disable streaming output (show response all at once)")
 	rootCmd.PersistentFlags().BoolVar(&noSpinner, "no-spinner", false, "disable spinner animations")
+	rootCmd.Flags().StringVar(&record, "record", "", "record model requests, responses and tool results to a cassette file (see replay)")
+	rootCmd.Flags().StringVarP(&prompt, "prompt", "p", "", "answer a single prompt and exit (\"-\" reads it from stdin)")
 
 	viper.BindPFlag("host", rootCmd.PersistentFlags().Lookup("host"))
 	viper.BindPFlag("key", rootCmd.PersistentFlags().Lookup("key"))
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tara-vision/taracode/internal/storage"
+	"github.com/tara-vision/taracode/internal/ui"
+)
+
+var usageSince string
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report token usage across sessions in this project",
+	Long: `Usage aggregates the token usage recorded in .taracode/history for the
+current project, broken down by model and by day.
+
+--since accepts a duration (30m, 24h, 7d, 2w) or a date (2006-01-02).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		since, err := parseSince(usageSince, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		workingDir, _ := os.Getwd()
+		storageMgr, err := storage.NewManager(workingDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening storage: %v\n", err)
+			os.Exit(1)
+		}
+
+		report, err := storageMgr.UsageSince(since)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(ui.NewRenderer().FormatUsageReport(report))
+	},
+}
+
+func init() {
+	usageCmd.Flags().StringVar(&usageSince, "since", "7d", "report usage since a duration ago (e.g. 24h, 7d, 2w) or a date (YYYY-MM-DD)")
+	rootCmd.AddCommand(usageCmd)
+}
+
+// parseSince converts a --since value into a start time relative to now
+func parseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(value, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				break
+			}
+			return now.Add(-time.Duration(count) * unit), nil
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid --since %q (use e.g. 24h, 7d, 2w or 2006-01-02)", value)
+	}
+	return now.Add(-d), nil
+}
@@ -16,9 +16,12 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/tara-vision/taracode/internal/cassette"
 	"github.com/tara-vision/taracode/internal/context"
 	"github.com/tara-vision/taracode/internal/provider"
+	"github.com/tara-vision/taracode/internal/semantic"
 	"github.com/tara-vision/taracode/internal/storage"
+	"github.com/tara-vision/taracode/internal/tokens"
 	"github.com/tara-vision/taracode/internal/tools"
 	"github.com/tara-vision/taracode/internal/ui"
 	openai "github.com/sashabaranov/go-openai"
@@ -28,6 +31,7 @@ import (
 const (
 	defaultConnectTimeout = 10 * time.Second
 	providerInitTimeout   = 2 * time.Minute // Timeout for provider initialization with retries
+	probeTimeout          = 3 * time.Minute // Timeout for first-run capability probing
 	maxRetries            = 3
 	initialBackoff        = 1 * time.Second
 	maxBackoff            = 30 * time.Second
@@ -125,6 +129,44 @@ type Assistant struct {
 
 	// Token usage tracking
 	sessionUsage *storage.TokenUsage
+	counter      *tokens.Counter // Token counting for estimates and context budgeting
+	turnStart    int             // Index in conversation of the current user message
+	out          outputSink      // Where turn output is shown
+
+	// Recording and replay (see replay.go)
+	recorder      *cassette.Recorder // Records the session when --record is set
+	player        *cassette.Player   // Serves recorded tool results during a replay
+	runUnrecorded bool               // Execute tool calls the replay has no result for
+
+	options  Options // Options the assistant was created with
+	thinking bool    // Reasoning mode on (see /think)
+	mode     Mode    // Plan or execution mode (see mode.go)
+
+	// Post-edit verification state for the current turn (see verify.go)
+	turnEdited   bool // Files changed since the last check
+	verifyRounds int  // Checks that failed this turn
+}
+
+// Options configures a new Assistant
+type Options struct {
+	Host          string
+	APIKey        string
+	Model         string // Preferred model; auto-detected when empty or unavailable
+	EmbedModel    string // Model for semantic_search embeddings; the chat model, if it embeds, when empty
+	Vendor        string // Empty or "auto" to auto-detect
+	Streaming     bool
+	EnableSpinner bool
+	Quiet         bool   // Skip informational startup messages (one-shot mode)
+	Ephemeral     bool   // Keep no session history (replays and evals)
+	Silent        bool   // Show no turn output (evals)
+	Record        string // Record requests, responses and tool results to this cassette file
+	WorkingDir    string // Project directory; the current directory when empty
+
+	Generation *storage.GenerationParams // Generation defaults for all models
+	Models     []ModelConfig             // Per-model generation settings
+
+	Limits  Limits                     // Tool-loop limits per turn
+	Confirm func(question string) bool // Asks the user a yes/no question; nil means no
 }
 
 // StreamFilter handles real-time filtering of think tags during streaming
@@ -219,7 +261,7 @@ Example - "explain project in a file called DOC.md":
 Use tools by outputting JSON: {"tool": "name", "params": {...}}
 
 FILE TOOLS:
-- read_file: {"tool": "read_file", "params": {"file_path": "path"}}
+- read_file: {"tool": "read_file", "params": {"file_path": "path"}} (optional "start_line": 100, "end_line": 200 for part of a file; required for files over 256 KB. Images are shown to you when the model accepts them, other binary files are only described. Long tool results are truncated with a notice saying how to see the rest)
 - write_file: {"tool": "write_file", "params": {"file_path": "path", "content": "..."}}
 - edit_file: {"tool": "edit_file", "params": {"file_path": "path", "old_string": "find", "new_string": "replace"}}
 - append_file: {"tool": "append_file", "params": {"file_path": "path", "content": "..."}}
@@ -232,10 +274,22 @@ FILE TOOLS:
 - insert_lines: {"tool": "insert_lines", "params": {"file_path": "path", "line_number": 5, "content": "..."}}
 - replace_lines: {"tool": "replace_lines", "params": {"file_path": "path", "start_line": 1, "end_line": 5, "content": "..."}}
 - delete_lines: {"tool": "delete_lines", "params": {"file_path": "path", "start_line": 1, "end_line": 5}}
+Files are formatted after every edit (gofmt for Go, and the formatters the project configures for other languages). A "rejected" error means the file no longer parses: fix it before moving on.
 
 SEARCH:
-- search_files: {"tool": "search_files", "params": {"pattern": "term", "directory": "."}}
+- search_files: {"tool": "search_files", "params": {"pattern": "term", "directory": "."}} (literal text, results grouped by file; "regex": true for RE2 syntax, "case_insensitive": true, "file_types": [".go"], "context_lines": 2, "max_results": 100. Skips .gitignored, dependency and binary files)
+- semantic_search: {"tool": "semantic_search", "params": {"query": "where are expired sessions cleaned up"}} (finds code by meaning when you don't know the names to grep for; returns ranked snippets with file:line ranges. Optional "path": "internal/auth", "limit": 8. The first search indexes the project, later ones re-embed only changed files)
 - execute_command: {"tool": "execute_command", "params": {"command": "go build"}}
+- run_tests: {"tool": "run_tests", "params": {}} (go test, pytest, jest or cargo test; returns counts and each failure with file:line and message. Optional "package": "./internal/server" or a test file, "test": "TestStart", "timeout": 600 seconds. Prefer this over execute_command for tests)
+
+GO (type-aware; prefer these over search_files and edit_file for Go code):
+- go_symbols: {"tool": "go_symbols", "params": {"path": "internal/server"}} (file or package directory: funcs, methods, types with signatures and line ranges)
+- go_definition: {"tool": "go_definition", "params": {"symbol": "Server.Start"}} (or {"file": "main.go", "line": 12, "name": "run"} for the identifier used there)
+- go_references: {"tool": "go_references", "params": {"symbol": "Server.Start"}} (every use across the module, tests included)
+- go_replace_symbol: {"tool": "go_replace_symbol", "params": {"symbol": "Server.Start", "content": "func (s *Server) Start() error {\n\t...\n}"}} (replaces the whole declaration; add "file" when the name is declared more than once)
+- go_rename: {"tool": "go_rename", "params": {"symbol": "Server.Start", "new_name": "Run"}} (renames every reference in the module; refused if the result would not type-check)
+- go_organize_imports: {"tool": "go_organize_imports", "params": {"path": "server.go"}} (adds missing and removes unused imports; file or package directory)
+- go_move_decl: {"tool": "go_move_decl", "params": {"symbol": "Config", "to": "internal/server/config.go"}} (moves a declaration to another file of the same package, fixing imports)
 
 GIT (status/diff/log are free, add/commit require user permission):
 - git_status: {"tool": "git_status", "params": {}}
@@ -245,6 +299,17 @@ GIT (status/diff/log are free, add/commit require user permission):
 - git_commit: {"tool": "git_commit", "params": {"message": "feat: message"}} (ASK FIRST)
 - git_branch: {"tool": "git_branch", "params": {}}
 
+MEMORY (facts kept across sessions; when the user says "remember" or states a lasting preference, save it):
+- remember: {"tool": "remember", "params": {"content": "Run tests with make test", "scope": "project"}} (scope "user" for preferences across all projects; optional "tags": ["go"] limits a user memory to matching projects)
+- recall: {"tool": "recall", "params": {"query": "tests"}}
+- forget: {"tool": "forget", "params": {"id": "a1b2c3d4"}}
+
+PLAN (for multi-step work; the active plan is shown below and tasks are numbered like 2 or 2.1):
+- plan_create: {"tool": "plan_create", "params": {"title": "Add auth", "tasks": ["Add middleware", {"content": "Write tests", "notes": "cover expiry", "subtasks": ["Unit", "Integration"]}]}}
+- plan_update_task: {"tool": "plan_update_task", "params": {"task": "2.1", "status": "completed", "notes": "optional"}} (status: pending, in_progress, completed, skipped)
+- plan_add_task: {"tool": "plan_add_task", "params": {"content": "Update docs", "parent": "2"}} (parent optional)
+- plan_complete: {"tool": "plan_complete", "params": {}}
+
 ## MULTIPLE TOOLS
 
 Call multiple tools at once for efficiency:
@@ -319,8 +384,11 @@ func detectModel(ctx gocontext.Context, httpClient *http.Client, host, apiKey st
 	})
 }
 
-func New(host, apiKey, configModel, vendor string, streaming bool, enableSpinner bool) (*Assistant, error) {
+// New creates an assistant connected to the configured LLM server
+func New(opts Options) (*Assistant, error) {
 	renderer := ui.NewRenderer()
+	host, apiKey, configModel, vendor := opts.Host, opts.APIKey, opts.Model, opts.Vendor
+	streaming, enableSpinner := opts.Streaming, opts.EnableSpinner
 
 	// Create context with timeout for provider initialization
 	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), providerInitTimeout)
@@ -358,14 +426,18 @@ func New(host, apiKey, configModel, vendor string, streaming bool, enableSpinner
 			}
 			if configModelFound {
 				model = configModel
-				fmt.Println(renderer.SuccessMessage(fmt.Sprintf("Using configured model: %s", model)))
+				if !opts.Quiet {
+					fmt.Println(renderer.SuccessMessage(fmt.Sprintf("Using configured model: %s", model)))
+				}
 			} else {
 				model = models[0]
 				fmt.Println(renderer.WarningMessage(fmt.Sprintf("Configured model '%s' not available on server. Available: %v. Using: %s", configModel, models, model)))
 			}
 		} else {
 			model = models[0]
-			fmt.Println(renderer.SuccessMessage(fmt.Sprintf("Auto-detected model: %s", model)))
+			if !opts.Quiet {
+				fmt.Println(renderer.SuccessMessage(fmt.Sprintf("Auto-detected model: %s", model)))
+			}
 		}
 	} else {
 		if configModel != "" {
@@ -378,9 +450,15 @@ func New(host, apiKey, configModel, vendor string, streaming bool, enableSpinner
 	// Update provider with selected model
 	prov.SetModel(model)
 
-	workingDir, err := os.Getwd()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	// Load cached capabilities for this host+model, probing on first use
+	loadCapabilities(prov, renderer, enableSpinner)
+
+	workingDir := opts.WorkingDir
+	if workingDir == "" {
+		workingDir, err = os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
 	}
 
 	// Initialize storage manager (non-fatal if fails)
@@ -394,29 +472,38 @@ func New(host, apiKey, configModel, vendor string, streaming bool, enableSpinner
 		fmt.Println(renderer.WarningMessage(fmt.Sprintf("Could not initialize storage: %v", err)))
 	} else {
 		// Try to load or create active session
-		session, _ = storageMgr.GetActiveSession()
-		if session == nil {
-			session, _ = storageMgr.CreateSession("")
+		if !opts.Ephemeral {
+			session, _ = storageMgr.GetActiveSession()
+			if session == nil {
+				session, _ = storageMgr.CreateSession("")
+			}
 		}
 
 		// Load project context if available
 		projectCtx, _ = storageMgr.LoadProjectContext()
 	}
 
+	// semantic_search needs somewhere to keep its index and a model to
+	// embed with
+	registry := newToolRegistry(storageMgr)
+	if embedModel := semanticEmbedModel(opts, prov, model); storageMgr != nil && embedModel != "" {
+		registry.RegisterSemanticSearch(semantic.NewEmbedder(client, embedModel))
+	}
+
 	// Build system prompt with project context if available
-	systemPrompt := buildSystemPrompt(workingDir, storageMgr)
+	systemPrompt := buildSystemPrompt(workingDir, storageMgr, registry)
 
 	systemMessage := openai.ChatCompletionMessage{
 		Role:    openai.ChatMessageRoleSystem,
 		Content: systemPrompt,
 	}
 
-	return &Assistant{
+	asst := &Assistant{
 		provider:      prov,
 		client:        client,
 		model:         model,
 		conversation:  []openai.ChatCompletionMessage{systemMessage},
-		toolRegistry:  tools.NewRegistry(),
+		toolRegistry:  registry,
 		workingDir:    workingDir,
 		streaming:     streaming,
 		enableSpinner: enableSpinner,
@@ -425,11 +512,90 @@ func New(host, apiKey, configModel, vendor string, streaming bool, enableSpinner
 		session:       session,
 		projectCtx:    projectCtx,
 		sessionUsage:  &storage.TokenUsage{},
-	}, nil
+		counter:       tokens.NewCounter(prov),
+		options:       opts,
+		thinking:      thinkingFromPreferences(storageMgr),
+	}
+	asst.out = &terminalSink{a: asst}
+	if opts.Silent {
+		asst.out = quietSink{}
+	}
+	if opts.Record != "" {
+		asst.recorder = cassette.NewRecorder(opts.Record, prov.Info().Host, model, promptHash(systemPrompt))
+	}
+	return asst, nil
 }
 
-// GetSession returns the current session
+// semanticEmbedModel returns the model semantic_search embeds with:
+// embedding_model when configured, otherwise the chat model when the
+// capability probe found the server embeds with it. It is "" when there is
+// none, which leaves semantic_search out.
+func semanticEmbedModel(opts Options, prov provider.Provider, model string) string {
+	if opts.EmbedModel != "" {
+		return opts.EmbedModel
+	}
+	if caps := prov.Info().Capabilities; caps != nil && caps.Embeddings {
+		return model
+	}
+	return ""
+}
+
+// newToolRegistry returns the tool registry, including the plan tools when
+// project storage is available to keep plans and truncated tool results in
+func newToolRegistry(storageMgr *storage.Manager) *tools.Registry {
+	registry := tools.NewRegistry()
+	if storageMgr != nil {
+		registry.RegisterPlanTools(storageMgr)
+		registry.SetOutputDir(filepath.Join(storageMgr.GetRootDir(), "outputs"))
+	}
+	return registry
+}
+
+// loadCapabilities applies cached capabilities to the provider, running the
+// capability probe when none are cached for the active model
+func loadCapabilities(prov provider.Provider, renderer *ui.Renderer, enableSpinner bool) {
+	info := prov.Info()
+	if caps := provider.LoadCachedCapabilities(info.Host, info.Model); caps != nil {
+		caps.Apply(info)
+		return
+	}
+
+	var spinner *ui.Spinner
+	if enableSpinner {
+		spinner = ui.NewSpinner()
+		spinner.Start("Probing model capabilities...")
+	}
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), probeTimeout)
+	defer cancel()
+	caps := provider.Probe(ctx, prov)
+
+	if spinner != nil {
+		spinner.Stop()
+	}
+
+	caps.Apply(info)
+	if err := provider.SaveCapabilities(caps); err != nil {
+		fmt.Println(renderer.WarningMessage(fmt.Sprintf("Could not cache capabilities: %v", err)))
+	}
+}
+
+// streamOptions requests a usage chunk unless the endpoint is known to
+// reject or ignore stream_options
+func (a *Assistant) streamOptions() *openai.StreamOptions {
+	if caps := a.provider.Info().Capabilities; caps != nil && !caps.StreamUsage {
+		return nil
+	}
+	return &openai.StreamOptions{IncludeUsage: true}
+}
+
+// GetSession returns the current session, refreshed from storage
 func (a *Assistant) GetSession() *storage.Session {
+	if a.storage != nil && a.session != nil {
+		if session, err := a.storage.GetSession(a.session.ID); err == nil {
+			a.session = session
+		}
+	}
 	return a.session
 }
 
@@ -438,11 +604,37 @@ func (a *Assistant) GetStorage() *storage.Manager {
 	return a.storage
 }
 
-// GetUsage returns the current session token usage
+// GetUsage returns token usage for the current session, including turns
+// from previous runs when the session is persisted
 func (a *Assistant) GetUsage() *storage.TokenUsage {
+	if session := a.GetSession(); session != nil && session.TotalUsage != nil {
+		return session.TotalUsage
+	}
 	return a.sessionUsage
 }
 
+// recordUsage stamps a response's usage with its wall time and adds it to
+// the in-memory total. When the server reported no usage it is counted
+// locally from the prompt and raw completion and marked as estimated.
+func (a *Assistant) recordUsage(ctx gocontext.Context, usage *storage.TokenUsage, prompt []openai.ChatCompletionMessage, completion string, start time.Time) *storage.TokenUsage {
+	duration := time.Since(start).Milliseconds()
+	if usage == nil || usage.TotalTokens == 0 {
+		// Even with the server tokenizer the chat template overhead is
+		// approximated, so local counts are always marked as estimates
+		promptTokens, _ := a.counter.CountMessages(ctx, prompt)
+		completionTokens, _ := a.counter.Count(ctx, completion)
+		usage = &storage.TokenUsage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      promptTokens + completionTokens,
+			Estimated:        true,
+		}
+	}
+	usage.DurationMs = duration
+	a.sessionUsage.Add(usage)
+	return usage
+}
+
 // GetProviderInfo returns information about the current LLM provider
 func (a *Assistant) GetProviderInfo() *provider.Info {
 	if a.provider == nil {
@@ -473,7 +665,7 @@ func (a *Assistant) NewSession(name string) error {
 	a.session = session
 
 	// Reset conversation to just system message
-	systemPrompt := buildSystemPrompt(a.workingDir, a.storage)
+	systemPrompt := buildSystemPrompt(a.workingDir, a.storage, a.toolRegistry)
 	a.conversation = []openai.ChatCompletionMessage{{
 		Role:    openai.ChatMessageRoleSystem,
 		Content: systemPrompt,
@@ -497,7 +689,7 @@ func (a *Assistant) LoadSession(id string) error {
 	a.storage.SetActiveSession(id)
 
 	// Rebuild conversation from session messages
-	systemPrompt := buildSystemPrompt(a.workingDir, a.storage)
+	systemPrompt := buildSystemPrompt(a.workingDir, a.storage, a.toolRegistry)
 	a.conversation = []openai.ChatCompletionMessage{{
 		Role:    openai.ChatMessageRoleSystem,
 		Content: systemPrompt,
@@ -506,12 +698,25 @@ func (a *Assistant) LoadSession(id string) error {
 	// Add messages from session
 	for _, msg := range session.Messages {
 		role := openai.ChatMessageRoleUser
-		if msg.Role == "assistant" {
+		content := msg.Content
+		switch msg.Role {
+		case "user":
+		case "assistant":
 			role = openai.ChatMessageRoleAssistant
+			// Older sessions stored reasoning inline
+			_, content = splitReasoning(content)
+		case "tool":
+			// Verification runs; only the diagnostics of a failed check
+			// went to the model, as a user message
+			if content == "" {
+				continue
+			}
+		default:
+			continue
 		}
 		a.conversation = append(a.conversation, openai.ChatCompletionMessage{
 			Role:    role,
-			Content: msg.Content,
+			Content: content,
 		})
 	}
 
@@ -519,8 +724,8 @@ func (a *Assistant) LoadSession(id string) error {
 }
 
 // buildSystemPrompt creates the system prompt, including project context if available
-func buildSystemPrompt(workingDir string, storageMgr *storage.Manager) string {
-	prompt := baseSystemPrompt
+func buildSystemPrompt(workingDir string, storageMgr *storage.Manager, registry *tools.Registry) string {
+	prompt := advertisedTools(baseSystemPrompt, registry)
 
 	// Check for TARACODE.md in current directory
 	taracodeFile := filepath.Join(workingDir, "TARACODE.md")
@@ -528,21 +733,20 @@ func buildSystemPrompt(workingDir string, storageMgr *storage.Manager) string {
 		prompt += fmt.Sprintf("\n\n## PROJECT CONTEXT\nThe following is project-specific guidance from TARACODE.md:\n\n%s", string(content))
 	}
 
+	// Include project rules and remembered facts
+	prompt += memoryPrompt(workingDir, storageMgr)
+
 	// Include active plan if exists
 	if storageMgr != nil {
 		if plan, err := storageMgr.GetActivePlan(); err == nil && plan != nil {
+			done, total := plan.Progress()
 			prompt += "\n\n## ACTIVE PLAN\n"
-			prompt += fmt.Sprintf("**%s**\n", plan.Title)
-			for i, task := range plan.Tasks {
-				status := "[ ]"
-				if task.Status == storage.TaskStatusCompleted {
-					status = "[x]"
-				} else if task.Status == storage.TaskStatusInProgress {
-					status = "[>]"
-				}
-				prompt += fmt.Sprintf("%d. %s %s\n", i+1, status, task.Content)
+			prompt += fmt.Sprintf("**%s** (%d/%d done)\n", plan.Title, done, total)
+			if plan.Description != "" {
+				prompt += plan.Description + "\n"
 			}
-			prompt += "\nUpdate task status as you complete them."
+			prompt += plan.Outline()
+			prompt += "\nUpdate tasks with plan_update_task as you work and call plan_complete when all are done."
 		}
 	}
 
@@ -552,6 +756,23 @@ func buildSystemPrompt(workingDir string, storageMgr *storage.Manager) string {
 	return prompt
 }
 
+// toolLine matches a tool's entry in the prompt's tool list
+var toolLine = regexp.MustCompile(`^- ([a-z_]+): \{"tool"`)
+
+// advertisedTools leaves out the prompt entries of tools the registry does
+// not have, such as the plan tools without project storage
+func advertisedTools(prompt string, registry *tools.Registry) string {
+	lines := strings.Split(prompt, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if m := toolLine.FindStringSubmatch(line); m != nil && !registry.Has(m[1]) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
 // InitProject analyzes the project and creates TARACODE.md with comprehensive context
 func InitProject(workingDir string) error {
 	fmt.Println("Analyzing project structure...")
@@ -1106,494 +1327,17 @@ func parseToolCalls(response string) ([]*ToolCall, string) {
 	return toolCalls, textBefore
 }
 
-// formatToolStatus returns a concise, human-friendly status for tool execution
-func formatToolStatus(tool string, params map[string]interface{}, result string, isError bool) string {
-	gray := "\033[90m"
-	green := "\033[32m"
-	red := "\033[31m"
-	reset := "\033[0m"
-
-	if isError {
-		return fmt.Sprintf("%s✗ %s failed%s", red, tool, reset)
-	}
-
-	switch tool {
-	case "read_file":
-		filePath, _ := params["file_path"].(string)
-		lines := strings.Count(result, "\n") + 1
-		return fmt.Sprintf("%s→ Read %s (%d lines)%s", gray, filepath.Base(filePath), lines, reset)
-
-	case "search_files":
-		pattern, _ := params["pattern"].(string)
-		matches := strings.Count(result, "\n")
-		if strings.Contains(result, "No matches") {
-			return fmt.Sprintf("%s→ Searched for \"%s\" (no matches)%s", gray, pattern, reset)
-		}
-		return fmt.Sprintf("%s→ Searched for \"%s\" (%d matches)%s", gray, pattern, matches, reset)
-
-	case "list_files":
-		dir, _ := params["directory"].(string)
-		if dir == "" || dir == "." {
-			dir = "current directory"
-		}
-		items := strings.Count(result, "\n")
-		return fmt.Sprintf("%s→ Listed %s (%d items)%s", gray, dir, items, reset)
-
-	case "execute_command":
-		cmd, _ := params["command"].(string)
-		if len(cmd) > 40 {
-			cmd = cmd[:37] + "..."
-		}
-		return fmt.Sprintf("%s→ Executed: %s%s", gray, cmd, reset)
-
-	case "write_file":
-		filePath, _ := params["file_path"].(string)
-		return fmt.Sprintf("%s✓ Wrote %s%s", green, filepath.Base(filePath), reset)
-
-	case "append_file":
-		filePath, _ := params["file_path"].(string)
-		return fmt.Sprintf("%s✓ Appended to %s%s", green, filepath.Base(filePath), reset)
-
-	case "edit_file":
-		filePath, _ := params["file_path"].(string)
-		return fmt.Sprintf("%s✓ Edited %s%s", green, filepath.Base(filePath), reset)
-
-	case "insert_lines":
-		filePath, _ := params["file_path"].(string)
-		lineNum, _ := params["line_number"].(float64)
-		return fmt.Sprintf("%s✓ Inserted at line %d in %s%s", green, int(lineNum), filepath.Base(filePath), reset)
-
-	case "replace_lines":
-		filePath, _ := params["file_path"].(string)
-		startLine, _ := params["start_line"].(float64)
-		endLine, _ := params["end_line"].(float64)
-		return fmt.Sprintf("%s✓ Replaced lines %d-%d in %s%s", green, int(startLine), int(endLine), filepath.Base(filePath), reset)
-
-	case "delete_lines":
-		filePath, _ := params["file_path"].(string)
-		startLine, _ := params["start_line"].(float64)
-		endLine, _ := params["end_line"].(float64)
-		return fmt.Sprintf("%s✓ Deleted lines %d-%d from %s%s", green, int(startLine), int(endLine), filepath.Base(filePath), reset)
-
-	case "copy_file":
-		src, _ := params["source_path"].(string)
-		dst, _ := params["dest_path"].(string)
-		return fmt.Sprintf("%s✓ Copied %s to %s%s", green, filepath.Base(src), filepath.Base(dst), reset)
-
-	case "move_file":
-		src, _ := params["source_path"].(string)
-		dst, _ := params["dest_path"].(string)
-		return fmt.Sprintf("%s✓ Moved %s to %s%s", green, filepath.Base(src), filepath.Base(dst), reset)
-
-	case "delete_file":
-		filePath, _ := params["file_path"].(string)
-		recursive, _ := params["recursive"].(bool)
-		if recursive {
-			return fmt.Sprintf("%s✓ Deleted %s (recursive)%s", green, filepath.Base(filePath), reset)
-		}
-		return fmt.Sprintf("%s✓ Deleted %s%s", green, filepath.Base(filePath), reset)
-
-	case "create_directory":
-		dirPath, _ := params["path"].(string)
-		return fmt.Sprintf("%s✓ Created directory %s%s", green, filepath.Base(dirPath), reset)
-
-	case "find_files":
-		pattern, _ := params["pattern"].(string)
-		matches := strings.Count(result, "\n")
-		if strings.Contains(result, "No files found") {
-			return fmt.Sprintf("%s→ Find \"%s\" (no matches)%s", gray, pattern, reset)
-		}
-		return fmt.Sprintf("%s→ Find \"%s\" (%d files)%s", gray, pattern, matches, reset)
-
-	case "git_status":
-		if strings.Contains(result, "clean") {
-			return fmt.Sprintf("%s→ Git status: clean%s", gray, reset)
-		}
-		changes := strings.Count(result, "\n")
-		return fmt.Sprintf("%s→ Git status: %d changes%s", gray, changes, reset)
-
-	case "git_diff":
-		if strings.Contains(result, "No changes") {
-			return fmt.Sprintf("%s→ Git diff: no changes%s", gray, reset)
-		}
-		lines := strings.Count(result, "\n")
-		return fmt.Sprintf("%s→ Git diff: %d lines%s", gray, lines, reset)
-
-	case "git_log":
-		commits := strings.Count(result, "\n") + 1
-		return fmt.Sprintf("%s→ Git log: %d commits%s", gray, commits, reset)
-
-	case "git_add":
-		return fmt.Sprintf("%s✓ Git: staged files%s", green, reset)
-
-	case "git_commit":
-		return fmt.Sprintf("%s✓ Git: commit created%s", green, reset)
-
-	case "git_branch":
-		branches := strings.Count(result, "\n") + 1
-		return fmt.Sprintf("%s→ Git branches: %d%s", gray, branches, reset)
-
-	default:
-		return fmt.Sprintf("%s→ %s completed%s", gray, tool, reset)
-	}
-}
-
+// ProcessMessage sends a user message and runs the resulting turn,
+// streaming the response unless streaming is disabled
 func (a *Assistant) ProcessMessage(userMessage string) error {
-	if a.streaming {
-		return a.processMessageStreaming(userMessage)
-	}
-	return a.processMessageNonStreaming(userMessage)
-}
-
-// processMessageStreaming handles messages with real-time streaming output
-func (a *Assistant) processMessageStreaming(userMessage string) error {
-	// Record user message to session
-	if a.storage != nil && a.session != nil {
-		userMsg := storage.ConversationMessage{
-			Role:      "user",
-			Content:   userMessage,
-			Timestamp: time.Now(),
-		}
-		a.storage.AddMessage(a.session.ID, userMsg)
-	}
-
-	a.conversation = append(a.conversation, openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleUser,
-		Content: userMessage,
-	})
-
-	// Create context with timeout for API response
-	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), apiResponseTimeout)
-	defer cancel()
-
-	maxIterations := 10
-
-	for i := 0; i < maxIterations; i++ {
-		// Start thinking spinner
-		var thinkingSpinner *ui.Spinner
-		if a.enableSpinner {
-			thinkingSpinner = ui.NewSpinner()
-			thinkingSpinner.Start("Thinking...")
-		}
-
-		stream, err := a.client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
-			Model:    a.model,
-			Messages: a.conversation,
-			StreamOptions: &openai.StreamOptions{
-				IncludeUsage: true,
-			},
-		})
-		if err != nil {
-			if thinkingSpinner != nil {
-				thinkingSpinner.Stop()
-			}
-			return fmt.Errorf("failed to create stream: %w", err)
-		}
-
-		filter := NewStreamFilter()
-
-		// Buffer the response while showing spinner (Claude Code style)
-		for {
-			chunk, err := stream.Recv()
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			if err != nil {
-				if thinkingSpinner != nil {
-					thinkingSpinner.Stop()
-				}
-				stream.Close()
-				return fmt.Errorf("stream error: %w", err)
-			}
-
-			if len(chunk.Choices) > 0 {
-				delta := chunk.Choices[0].Delta.Content
-				filter.Process(delta)
-			}
-
-			// Capture usage from final chunk (when StreamOptions.IncludeUsage is true)
-			if chunk.Usage != nil {
-				a.sessionUsage.PromptTokens += chunk.Usage.PromptTokens
-				a.sessionUsage.CompletionTokens += chunk.Usage.CompletionTokens
-				a.sessionUsage.TotalTokens += chunk.Usage.TotalTokens
-			}
-		}
-		stream.Close()
-
-		// Stop spinner now that response is complete
-		if thinkingSpinner != nil {
-			thinkingSpinner.Stop()
-		}
-
-		// Flush any remaining buffered content
-		filter.Flush()
-
-		fullResponse := filter.FullContent()
-
-		// Parse for tool calls from content (supports multiple)
-		toolCalls, displayText := parseToolCalls(fullResponse)
-
-		if len(toolCalls) == 0 {
-			// No tool calls - render the response with Glamour
-			displayedText := cleanResponse(fullResponse)
-			if displayedText != "" {
-				fmt.Println(ui.RenderMarkdown(displayedText))
-			}
-			a.conversation = append(a.conversation, openai.ChatCompletionMessage{
-				Role:    openai.ChatMessageRoleAssistant,
-				Content: fullResponse,
-			})
-
-			// Save assistant response to session
-			if a.storage != nil && a.session != nil {
-				assistantMsg := storage.ConversationMessage{
-					Role:      "assistant",
-					Content:   fullResponse,
-					Timestamp: time.Now(),
-				}
-				a.storage.AddMessage(a.session.ID, assistantMsg)
-			}
-			break
-		}
-
-		// Display any text before tool calls
-		if displayText != "" {
-			fmt.Println(ui.RenderMarkdown(displayText))
-		}
-
-		// Tool calls detected - add assistant response to conversation
-		a.conversation = append(a.conversation, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleAssistant,
-			Content: fullResponse,
-		})
-
-		// Execute all tool calls and aggregate results
-		var allResults strings.Builder
-		totalTools := len(toolCalls)
-
-		for idx, toolCall := range toolCalls {
-			// Start tool execution spinner with progress
-			var toolSpinner *ui.Spinner
-			if a.enableSpinner {
-				toolSpinner = ui.NewSpinner()
-				if totalTools > 1 {
-					toolSpinner.Start(fmt.Sprintf("Running %s (%d/%d)...", toolCall.Tool, idx+1, totalTools))
-				} else {
-					toolSpinner.Start(fmt.Sprintf("Running %s...", toolCall.Tool))
-				}
-			}
-
-			// Execute the tool
-			startTime := time.Now()
-			result, err := a.toolRegistry.ExecuteTool(toolCall.Tool, toolCall.Params, a.workingDir)
-			duration := time.Since(startTime).Milliseconds()
-			isError := err != nil
-			if isError {
-				result = fmt.Sprintf("Error: %v", err)
-			}
-
-			// Stop tool spinner
-			if toolSpinner != nil {
-				toolSpinner.Stop()
-			}
-
-			// Print concise tool status using renderer
-			fmt.Println(a.renderer.FormatToolStatus(toolCall.Tool, toolCall.Params, result, isError))
-
-			// Aggregate results for sending back to LLM
-			if totalTools > 1 {
-				allResults.WriteString(fmt.Sprintf("[%d] %s result:\n%s\n\n", idx+1, toolCall.Tool, result))
-			} else {
-				allResults.WriteString(fmt.Sprintf("Tool result:\n%s", result))
-			}
-
-			// Save tool call to session
-			if a.storage != nil && a.session != nil {
-				toolMsg := storage.ConversationMessage{
-					Role:      "assistant",
-					Content:   fullResponse,
-					Timestamp: time.Now(),
-					ToolCall: &storage.ToolCallRecord{
-						Tool:     toolCall.Tool,
-						Params:   toolCall.Params,
-						Result:   result,
-						Duration: duration,
-						Success:  !isError,
-					},
-				}
-				a.storage.AddMessage(a.session.ID, toolMsg)
-			}
-		}
-
-		// Add all tool results to conversation in one message
-		a.conversation = append(a.conversation, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleUser,
-			Content: allResults.String(),
-		})
-	}
-
-	return nil
+	return a.runTurn(userMessage, a.responseSource())
 }
 
-// processMessageNonStreaming handles messages without streaming
-func (a *Assistant) processMessageNonStreaming(userMessage string) error {
-	// Record user message to session
-	if a.storage != nil && a.session != nil {
-		userMsg := storage.ConversationMessage{
-			Role:      "user",
-			Content:   userMessage,
-			Timestamp: time.Now(),
-		}
-		a.storage.AddMessage(a.session.ID, userMsg)
-	}
-
-	a.conversation = append(a.conversation, openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleUser,
-		Content: userMessage,
-	})
-
-	// Create context with timeout for API response
-	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), apiResponseTimeout)
-	defer cancel()
-
-	maxIterations := 10
-
-	for i := 0; i < maxIterations; i++ {
-		// Start thinking spinner
-		var thinkingSpinner *ui.Spinner
-		if a.enableSpinner {
-			thinkingSpinner = ui.NewSpinner()
-			thinkingSpinner.Start("Thinking...")
-		}
-
-		resp, err := a.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-			Model:    a.model,
-			Messages: a.conversation,
-		})
-
-		// Stop spinner
-		if thinkingSpinner != nil {
-			thinkingSpinner.Stop()
-		}
-
-		if err != nil {
-			return fmt.Errorf("failed to get response: %w", err)
-		}
-
-		if len(resp.Choices) == 0 {
-			return fmt.Errorf("no response choices returned")
-		}
-
-		// Track token usage
-		if resp.Usage.TotalTokens > 0 {
-			a.sessionUsage.PromptTokens += resp.Usage.PromptTokens
-			a.sessionUsage.CompletionTokens += resp.Usage.CompletionTokens
-			a.sessionUsage.TotalTokens += resp.Usage.TotalTokens
-		}
-
-		assistantResponse := resp.Choices[0].Message.Content
-
-		// Parse for tool calls from content (supports multiple)
-		toolCalls, displayText := parseToolCalls(assistantResponse)
-
-		// If no tool calls, render and print response
-		if len(toolCalls) == 0 {
-			if displayText != "" {
-				// Render with Glamour for syntax highlighting
-				fmt.Println(ui.RenderMarkdown(displayText))
-			}
-			a.conversation = append(a.conversation, openai.ChatCompletionMessage{
-				Role:    openai.ChatMessageRoleAssistant,
-				Content: assistantResponse,
-			})
-
-			// Save assistant response to session
-			if a.storage != nil && a.session != nil {
-				assistantMsg := storage.ConversationMessage{
-					Role:      "assistant",
-					Content:   assistantResponse,
-					Timestamp: time.Now(),
-				}
-				a.storage.AddMessage(a.session.ID, assistantMsg)
-			}
-			break
-		}
-
-		// Display any text before tool calls
-		if displayText != "" {
-			fmt.Println(ui.RenderMarkdown(displayText))
-		}
-
-		// Add assistant response to conversation
-		a.conversation = append(a.conversation, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleAssistant,
-			Content: assistantResponse,
-		})
-
-		// Execute all tool calls and aggregate results
-		var allResults strings.Builder
-		totalTools := len(toolCalls)
-
-		for idx, toolCall := range toolCalls {
-			// Start tool execution spinner with progress
-			var toolSpinner *ui.Spinner
-			if a.enableSpinner {
-				toolSpinner = ui.NewSpinner()
-				if totalTools > 1 {
-					toolSpinner.Start(fmt.Sprintf("Running %s (%d/%d)...", toolCall.Tool, idx+1, totalTools))
-				} else {
-					toolSpinner.Start(fmt.Sprintf("Running %s...", toolCall.Tool))
-				}
-			}
-
-			// Execute the tool
-			startTime := time.Now()
-			result, err := a.toolRegistry.ExecuteTool(toolCall.Tool, toolCall.Params, a.workingDir)
-			duration := time.Since(startTime).Milliseconds()
-			isError := err != nil
-			if isError {
-				result = fmt.Sprintf("Error: %v", err)
-			}
-
-			// Stop tool spinner
-			if toolSpinner != nil {
-				toolSpinner.Stop()
-			}
-
-			// Print concise tool status using renderer
-			fmt.Println(a.renderer.FormatToolStatus(toolCall.Tool, toolCall.Params, result, isError))
-
-			// Aggregate results for sending back to LLM
-			if totalTools > 1 {
-				allResults.WriteString(fmt.Sprintf("[%d] %s result:\n%s\n\n", idx+1, toolCall.Tool, result))
-			} else {
-				allResults.WriteString(fmt.Sprintf("Tool result:\n%s", result))
-			}
-
-			// Save tool call to session
-			if a.storage != nil && a.session != nil {
-				toolMsg := storage.ConversationMessage{
-					Role:      "assistant",
-					Content:   assistantResponse,
-					Timestamp: time.Now(),
-					ToolCall: &storage.ToolCallRecord{
-						Tool:     toolCall.Tool,
-						Params:   toolCall.Params,
-						Result:   result,
-						Duration: duration,
-						Success:  !isError,
-					},
-				}
-				a.storage.AddMessage(a.session.ID, toolMsg)
-			}
-		}
-
-		// Add all tool results to conversation in one message
-		a.conversation = append(a.conversation, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleUser,
-			Content: allResults.String(),
-		})
+// responseSource returns how responses are fetched: streamed unless
+// streaming is disabled
+func (a *Assistant) responseSource() responseSource {
+	if a.streaming {
+		return streamSource{options: a.streamOptions()}
 	}
-
-	return nil
+	return completionSource{}
 }
@@ -0,0 +1,82 @@
+package assistant
+
+import (
+	gocontext "context"
+	"fmt"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/tara-vision/taracode/internal/storage"
+	"github.com/tara-vision/taracode/internal/tokens"
+)
+
+// defaultReplyReserve caps the tokens held back for the reply when
+// max_tokens is not set
+const defaultReplyReserve = 4096
+
+// replyReserve returns how many tokens of the window to keep free for the
+// model's reply
+func replyReserve(window int, params *storage.GenerationParams) int {
+	if params != nil && params.MaxTokens != nil {
+		return *params.MaxTokens
+	}
+	return min(defaultReplyReserve, window/4)
+}
+
+// contextWindow returns the model's context size in tokens, or 0 if unknown
+func (a *Assistant) contextWindow() int {
+	if caps := a.provider.Info().Capabilities; caps != nil {
+		return caps.MaxContext
+	}
+	return 0
+}
+
+// trimConversation drops the oldest messages after the system prompt until
+// the conversation fits in budget tokens. Messages are dropped up to the
+// next user message so the history never starts mid-response, and nothing
+// from turnStart on is dropped. It returns the trimmed conversation, the new
+// turnStart, the number of messages dropped and the remaining token count.
+func trimConversation(ctx gocontext.Context, counter *tokens.Counter, conversation []openai.ChatCompletionMessage, turnStart, budget int) ([]openai.ChatCompletionMessage, int, int, int) {
+	used, _ := counter.CountMessages(ctx, conversation)
+	dropped := 0
+
+	for used > budget {
+		end := 2
+		for end < turnStart && conversation[end].Role != openai.ChatMessageRoleUser {
+			end++
+		}
+		if end > turnStart {
+			break // Only the system prompt and current turn are left
+		}
+
+		for _, msg := range conversation[1:end] {
+			n, _ := counter.CountMessage(ctx, msg)
+			used -= n
+		}
+		conversation = append(conversation[:1], conversation[end:]...)
+		turnStart -= end - 1
+		dropped += end - 1
+	}
+
+	return conversation, turnStart, dropped, used
+}
+
+// fitContext keeps the conversation within the model's context window by
+// dropping the oldest turns, warning when it does. It does nothing when the
+// window size is unknown.
+func (a *Assistant) fitContext(ctx gocontext.Context) {
+	window := a.contextWindow()
+	if window <= 0 || len(a.conversation) < 2 {
+		return
+	}
+	budget := window - replyReserve(window, a.GenerationParams())
+
+	var dropped, used int
+	a.conversation, a.turnStart, dropped, used = trimConversation(ctx, a.counter, a.conversation, a.turnStart, budget)
+
+	if dropped > 0 {
+		a.out.warn(fmt.Sprintf("Context window nearly full: dropped %d older messages (~%d of %d tokens in use)", dropped, used, window))
+	}
+	if used > budget {
+		a.out.warn(fmt.Sprintf("Current turn (~%d tokens) exceeds the %d-token context budget; the server may truncate or reject it", used, budget))
+	}
+}
@@ -0,0 +1,55 @@
+package assistant
+
+import (
+	gocontext "context"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+func TestReplyReserve(t *testing.T) {
+	if got := replyReserve(32768, nil); got != 4096 {
+		t.Errorf("replyReserve(32768) = %d, want 4096", got)
+	}
+	if got := replyReserve(8192, nil); got != 2048 {
+		t.Errorf("replyReserve(8192) = %d, want 2048", got)
+	}
+	maxTokens := 1000
+	if got := replyReserve(8192, &storage.GenerationParams{MaxTokens: &maxTokens}); got != 1000 {
+		t.Errorf("replyReserve with max_tokens = %d, want 1000", got)
+	}
+}
+
+func TestTrimConversation(t *testing.T) {
+	msg := func(role, content string) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: role, Content: content}
+	}
+	conversation := []openai.ChatCompletionMessage{
+		msg(openai.ChatMessageRoleSystem, "system prompt"),
+		msg(openai.ChatMessageRoleUser, "first question"),
+		msg(openai.ChatMessageRoleAssistant, "first answer"),
+		msg(openai.ChatMessageRoleUser, "second question"),
+		msg(openai.ChatMessageRoleAssistant, "second answer"),
+		msg(openai.ChatMessageRoleUser, "current question"),
+	}
+	ctx := gocontext.Background()
+
+	// Enough room for everything: nothing dropped
+	trimmed, turnStart, dropped, _ := trimConversation(ctx, nil, append([]openai.ChatCompletionMessage(nil), conversation...), 5, 1000)
+	if dropped != 0 || len(trimmed) != 6 || turnStart != 5 {
+		t.Fatalf("trim with room: dropped %d, len %d, turnStart %d", dropped, len(trimmed), turnStart)
+	}
+
+	// Room for roughly one old turn: the first is dropped as a unit
+	trimmed, turnStart, dropped, _ = trimConversation(ctx, nil, append([]openai.ChatCompletionMessage(nil), conversation...), 5, 35)
+	if dropped != 2 || trimmed[1].Content != "second question" || turnStart != 3 {
+		t.Errorf("trim one turn: dropped %d, first %q, turnStart %d", dropped, trimmed[1].Content, turnStart)
+	}
+
+	// No room at all: the system prompt and current turn are kept
+	trimmed, turnStart, _, _ = trimConversation(ctx, nil, append([]openai.ChatCompletionMessage(nil), conversation...), 5, 1)
+	if len(trimmed) != 2 || trimmed[1].Content != "current question" || turnStart != 1 {
+		t.Errorf("trim everything: len %d, turnStart %d", len(trimmed), turnStart)
+	}
+}
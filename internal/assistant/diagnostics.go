@@ -0,0 +1,90 @@
+package assistant
+
+import (
+	gocontext "context"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// CheckCompletion sends a minimal chat completion and returns the reply
+func CheckCompletion(ctx gocontext.Context, client *openai.Client, model string) (string, error) {
+	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:     model,
+		MaxTokens: 256,
+		Messages: []openai.ChatCompletionMessage{{
+			Role:    openai.ChatMessageRoleUser,
+			Content: "Reply with the single word OK.",
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response choices returned")
+	}
+	reply := cleanResponse(resp.Choices[0].Message.Content)
+	if reply == "" {
+		return "", fmt.Errorf("model returned an empty reply")
+	}
+	return reply, nil
+}
+
+// CheckToolRoundTrip verifies the model can drive taracode's tool protocol:
+// it must emit a list_files call, accept a canned tool result, and then
+// answer without calling another tool. Returns a short description of the
+// exchange.
+func CheckToolRoundTrip(ctx gocontext.Context, client *openai.Client, model string) (string, error) {
+	conversation := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: baseSystemPrompt},
+		{Role: openai.ChatMessageRoleUser, Content: "How many files are in the current directory? Use the list_files tool."},
+	}
+
+	reply, err := diagnosticTurn(ctx, client, model, conversation)
+	if err != nil {
+		return "", err
+	}
+	toolCalls, _ := parseToolCalls(reply)
+	if len(toolCalls) == 0 {
+		return "", fmt.Errorf("model did not emit a tool call in the expected JSON format")
+	}
+	if toolCalls[0].Tool != "list_files" {
+		return "", fmt.Errorf("model called %s instead of list_files", toolCalls[0].Tool)
+	}
+
+	conversation = append(conversation,
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: reply},
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "Tool result:\nmain.go\ngo.mod\nREADME.md"},
+	)
+
+	final, err := diagnosticTurn(ctx, client, model, conversation)
+	if err != nil {
+		return "", fmt.Errorf("follow-up after tool result failed: %w", err)
+	}
+	if more, _ := parseToolCalls(final); len(more) > 0 {
+		return "", fmt.Errorf("model kept calling tools (%s) after receiving the result", more[0].Tool)
+	}
+	answer := cleanResponse(final)
+	if answer == "" {
+		return "", fmt.Errorf("model returned an empty answer after the tool result")
+	}
+	if !strings.Contains(answer, "3") && !strings.Contains(strings.ToLower(answer), "three") {
+		return "list_files called, but the answer ignored the tool result", nil
+	}
+	return "list_files called and result used in the answer", nil
+}
+
+func diagnosticTurn(ctx gocontext.Context, client *openai.Client, model string, messages []openai.ChatCompletionMessage) (string, error) {
+	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:    model,
+		Messages: messages,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response choices returned")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,64 @@
+package assistant
+
+import (
+	gocontext "context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tara-vision/taracode/internal/llmtest"
+	"github.com/tara-vision/taracode/internal/provider"
+)
+
+func TestCheckCompletion(t *testing.T) {
+	server := llmtest.New(t,
+		llmtest.Think("The user wants one word.", "OK"),
+		llmtest.Text(""),
+		llmtest.Error(http.StatusInternalServerError, "model not loaded"),
+	)
+	client := provider.NewOpenAIProvider(server.URL+"/v1", "").CreateClient()
+	ctx := gocontext.Background()
+
+	if reply, err := CheckCompletion(ctx, client, llmtest.DefaultModel); err != nil || reply != "OK" {
+		t.Errorf("CheckCompletion = %q, %v", reply, err)
+	}
+	if _, err := CheckCompletion(ctx, client, llmtest.DefaultModel); err == nil || !strings.Contains(err.Error(), "empty reply") {
+		t.Errorf("empty reply: err = %v", err)
+	}
+	if _, err := CheckCompletion(ctx, client, llmtest.DefaultModel); err == nil || !strings.Contains(err.Error(), "model not loaded") {
+		t.Errorf("server error: err = %v", err)
+	}
+}
+
+func TestCheckToolRoundTrip(t *testing.T) {
+	ctx := gocontext.Background()
+
+	t.Run("calls list_files", func(t *testing.T) {
+		server := llmtest.New(t,
+			llmtest.Tool("list_files", map[string]any{"directory": "."}),
+			llmtest.Text("There are 3 files: main.go, go.mod and README.md."),
+		)
+		client := provider.NewOpenAIProvider(server.URL+"/v1", "").CreateClient()
+
+		detail, err := CheckToolRoundTrip(ctx, client, llmtest.DefaultModel)
+		if err != nil || detail != "list_files called and result used in the answer" {
+			t.Errorf("CheckToolRoundTrip = %q, %v", detail, err)
+		}
+		if msg := server.LastMessage(1); !strings.HasPrefix(msg, "Tool result:\n") {
+			t.Errorf("follow-up message = %q", msg)
+		}
+	})
+
+	t.Run("never calls a tool", func(t *testing.T) {
+		server := llmtest.New(t, llmtest.Text("I cannot see your files, but there are probably a few."))
+		client := provider.NewOpenAIProvider(server.URL+"/v1", "").CreateClient()
+
+		_, err := CheckToolRoundTrip(ctx, client, llmtest.DefaultModel)
+		if err == nil || !strings.Contains(err.Error(), "did not emit a tool call") {
+			t.Errorf("err = %v", err)
+		}
+		if n := len(server.Requests()); n != 1 {
+			t.Errorf("got %d requests, want 1", n)
+		}
+	})
+}
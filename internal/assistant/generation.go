@@ -0,0 +1,246 @@
+package assistant
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+// ModelConfig holds per-model generation settings from the models: list in
+// config. Name may end in * to match a model prefix (e.g. "qwen3*").
+// Thinking and NonThinking blocks apply on top of the base settings
+// depending on whether reasoning mode is on.
+type ModelConfig struct {
+	Name                     string `mapstructure:"name"`
+	storage.GenerationParams `mapstructure:",squash"`
+	Thinking                 *storage.GenerationParams `mapstructure:"thinking"`
+	NonThinking              *storage.GenerationParams `mapstructure:"non_thinking"`
+	ThinkingSwitch           string                    `mapstructure:"thinking_switch"` // auto, soft, kwarg or none
+}
+
+// matches reports whether the config applies to the given model name
+func (c ModelConfig) matches(model string) bool {
+	if prefix, ok := strings.CutSuffix(c.Name, "*"); ok {
+		return strings.HasPrefix(model, prefix)
+	}
+	return c.Name == model
+}
+
+// generationParamNames lists the parameters accepted by /set
+var generationParamNames = []string{"temperature", "top_p", "max_tokens", "stop", "seed", "presence_penalty"}
+
+// mergeParams returns a copy of base with every field set in over applied
+func mergeParams(base, over *storage.GenerationParams) *storage.GenerationParams {
+	merged := &storage.GenerationParams{}
+	if base != nil {
+		*merged = *base
+	}
+	if over == nil {
+		return merged
+	}
+	if over.Temperature != nil {
+		merged.Temperature = over.Temperature
+	}
+	if over.TopP != nil {
+		merged.TopP = over.TopP
+	}
+	if over.MaxTokens != nil {
+		merged.MaxTokens = over.MaxTokens
+	}
+	if over.Stop != nil {
+		merged.Stop = over.Stop
+	}
+	if over.Seed != nil {
+		merged.Seed = over.Seed
+	}
+	if over.PresencePenalty != nil {
+		merged.PresencePenalty = over.PresencePenalty
+	}
+	return merged
+}
+
+// resolveParams layers generation settings from lowest to highest priority:
+// global config, matching model config, its thinking/non_thinking block,
+// then session overrides
+func resolveParams(defaults *storage.GenerationParams, models []ModelConfig, model string, thinking bool, overrides *storage.GenerationParams) *storage.GenerationParams {
+	params := mergeParams(nil, defaults)
+	for _, mc := range models {
+		if !mc.matches(model) {
+			continue
+		}
+		base := mc.GenerationParams
+		params = mergeParams(params, &base)
+		if thinking {
+			params = mergeParams(params, mc.Thinking)
+		} else {
+			params = mergeParams(params, mc.NonThinking)
+		}
+		break
+	}
+	return mergeParams(params, overrides)
+}
+
+// applyParams copies generation parameters onto a chat request
+func applyParams(req *openai.ChatCompletionRequest, params *storage.GenerationParams) {
+	if params == nil {
+		return
+	}
+	if params.Temperature != nil {
+		req.Temperature = *params.Temperature
+		// go-openai omits a zero temperature, which would fall back to the
+		// server default; send the smallest non-zero value for greedy decoding
+		if req.Temperature == 0 {
+			req.Temperature = math.SmallestNonzeroFloat32
+		}
+	}
+	if params.TopP != nil {
+		req.TopP = *params.TopP
+	}
+	if params.MaxTokens != nil {
+		req.MaxTokens = *params.MaxTokens
+	}
+	if params.Stop != nil {
+		req.Stop = params.Stop
+	}
+	if params.Seed != nil {
+		seed := *params.Seed
+		req.Seed = &seed
+	}
+	if params.PresencePenalty != nil {
+		req.PresencePenalty = *params.PresencePenalty
+	}
+}
+
+// setParam parses value and sets the named parameter on params. An empty
+// value or "default" clears it back to the server default.
+func setParam(params *storage.GenerationParams, name, value string) error {
+	clear := value == "" || value == "default"
+
+	parseFloat := func(min, max float64) (*float32, error) {
+		if clear {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(value, 32)
+		if err != nil || f < min || f > max {
+			return nil, fmt.Errorf("%s must be a number between %g and %g", name, min, max)
+		}
+		f32 := float32(f)
+		return &f32, nil
+	}
+	parseInt := func(min int) (*int, error) {
+		if clear {
+			return nil, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < min {
+			return nil, fmt.Errorf("%s must be an integer >= %d", name, min)
+		}
+		return &n, nil
+	}
+
+	var err error
+	switch name {
+	case "temperature":
+		params.Temperature, err = parseFloat(0, 2)
+	case "top_p":
+		params.TopP, err = parseFloat(0, 1)
+	case "max_tokens":
+		params.MaxTokens, err = parseInt(1)
+	case "seed":
+		params.Seed, err = parseInt(math.MinInt)
+	case "presence_penalty":
+		params.PresencePenalty, err = parseFloat(-2, 2)
+	case "stop":
+		if clear {
+			params.Stop = nil
+		} else {
+			params.Stop = strings.Split(value, ",")
+		}
+	default:
+		return fmt.Errorf("unknown parameter %q (available: %s)", name, strings.Join(generationParamNames, ", "))
+	}
+	return err
+}
+
+// FormatParams renders generation parameters as "name=value" pairs
+func FormatParams(params *storage.GenerationParams) string {
+	if params == nil {
+		return "server defaults"
+	}
+
+	var parts []string
+	if params.Temperature != nil {
+		parts = append(parts, fmt.Sprintf("temperature=%g", *params.Temperature))
+	}
+	if params.TopP != nil {
+		parts = append(parts, fmt.Sprintf("top_p=%g", *params.TopP))
+	}
+	if params.MaxTokens != nil {
+		parts = append(parts, fmt.Sprintf("max_tokens=%d", *params.MaxTokens))
+	}
+	if params.Stop != nil {
+		parts = append(parts, fmt.Sprintf("stop=%q", strings.Join(params.Stop, ",")))
+	}
+	if params.Seed != nil {
+		parts = append(parts, fmt.Sprintf("seed=%d", *params.Seed))
+	}
+	if params.PresencePenalty != nil {
+		parts = append(parts, fmt.Sprintf("presence_penalty=%g", *params.PresencePenalty))
+	}
+	if len(parts) == 0 {
+		return "server defaults"
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// GenerationParams returns the parameters sent with the next request
+func (a *Assistant) GenerationParams() *storage.GenerationParams {
+	var overrides *storage.GenerationParams
+	if a.storage != nil {
+		overrides = a.storage.GetPreferences().Generation
+	}
+	return resolveParams(a.options.Generation, a.options.Models, a.model, a.thinking, overrides)
+}
+
+// SessionParams returns the overrides set with /set, or nil if none
+func (a *Assistant) SessionParams() *storage.GenerationParams {
+	if a.storage == nil {
+		return nil
+	}
+	return a.storage.GetPreferences().Generation
+}
+
+// SetParam overrides a generation parameter and persists it in the
+// project preferences
+func (a *Assistant) SetParam(name, value string) error {
+	if a.storage == nil {
+		return fmt.Errorf("storage not initialized")
+	}
+
+	prefs := *a.storage.GetPreferences()
+	overrides := mergeParams(nil, prefs.Generation)
+	if err := setParam(overrides, name, value); err != nil {
+		return err
+	}
+	if FormatParams(overrides) == "server defaults" {
+		overrides = nil
+	}
+	prefs.Generation = overrides
+	return a.storage.SavePreferences(&prefs)
+}
+
+// ResetParams clears all /set overrides
+func (a *Assistant) ResetParams() error {
+	if a.storage == nil {
+		return fmt.Errorf("storage not initialized")
+	}
+	prefs := *a.storage.GetPreferences()
+	prefs.Generation = nil
+	return a.storage.SavePreferences(&prefs)
+}
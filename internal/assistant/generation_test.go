@@ -0,0 +1,79 @@
+package assistant
+
+import (
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+func float32Ptr(f float32) *float32 { return &f }
+
+func TestResolveParamsLayering(t *testing.T) {
+	defaults := &storage.GenerationParams{Temperature: float32Ptr(0.7), TopP: float32Ptr(0.9)}
+	models := []ModelConfig{
+		{Name: "llama*", GenerationParams: storage.GenerationParams{Temperature: float32Ptr(0.1)}},
+		{
+			Name:             "qwen3*",
+			GenerationParams: storage.GenerationParams{TopP: float32Ptr(0.8)},
+			Thinking:         &storage.GenerationParams{Temperature: float32Ptr(0.6), TopP: float32Ptr(0.95)},
+			NonThinking:      &storage.GenerationParams{Temperature: float32Ptr(0.7)},
+		},
+	}
+
+	thinking := resolveParams(defaults, models, "qwen3:30b", true, nil)
+	if *thinking.Temperature != 0.6 || *thinking.TopP != 0.95 {
+		t.Errorf("Thinking block not applied: %s", FormatParams(thinking))
+	}
+
+	nonThinking := resolveParams(defaults, models, "qwen3:30b", false, nil)
+	if *nonThinking.Temperature != 0.7 || *nonThinking.TopP != 0.8 {
+		t.Errorf("Non-thinking block not applied: %s", FormatParams(nonThinking))
+	}
+
+	overridden := resolveParams(defaults, models, "qwen3:30b", true, &storage.GenerationParams{Temperature: float32Ptr(0.2)})
+	if *overridden.Temperature != 0.2 || *overridden.TopP != 0.95 {
+		t.Errorf("Session override not applied: %s", FormatParams(overridden))
+	}
+
+	other := resolveParams(defaults, models, "mistral", true, nil)
+	if *other.Temperature != 0.7 {
+		t.Errorf("Unmatched model should use defaults: %s", FormatParams(other))
+	}
+	if *defaults.Temperature != 0.7 {
+		t.Errorf("Defaults were mutated")
+	}
+}
+
+func TestSetParam(t *testing.T) {
+	params := &storage.GenerationParams{}
+	for name, value := range map[string]string{
+		"temperature": "0.2", "top_p": "0.9", "max_tokens": "512",
+		"stop": "</done>,END", "seed": "42", "presence_penalty": "-0.5",
+	} {
+		if err := setParam(params, name, value); err != nil {
+			t.Fatalf("setParam(%s, %s) failed: %v", name, value, err)
+		}
+	}
+	if got := FormatParams(params); got != `max_tokens=512 presence_penalty=-0.5 seed=42 stop="</done>,END" temperature=0.2 top_p=0.9` {
+		t.Errorf("Unexpected params: %s", got)
+	}
+
+	if err := setParam(params, "temperature", "default"); err != nil || params.Temperature != nil {
+		t.Errorf("Expected temperature to be cleared")
+	}
+	if err := setParam(params, "temperature", "3"); err == nil {
+		t.Errorf("Expected out-of-range temperature to fail")
+	}
+	if err := setParam(params, "top_k", "5"); err == nil {
+		t.Errorf("Expected unknown parameter to fail")
+	}
+}
+
+func TestApplyParamsZeroTemperature(t *testing.T) {
+	req := openai.ChatCompletionRequest{}
+	applyParams(&req, &storage.GenerationParams{Temperature: float32Ptr(0)})
+	if req.Temperature == 0 {
+		t.Errorf("Zero temperature would be omitted from the request")
+	}
+}
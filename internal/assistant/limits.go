@@ -0,0 +1,128 @@
+package assistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tara-vision/taracode/internal/tools"
+)
+
+// Default tool-loop limits
+const (
+	DefaultMaxIterations = 10
+	DefaultTurnTimeout   = 10 * time.Minute
+	DefaultMaxRepeats    = 3
+	DefaultMaxToolOutput = tools.DefaultOutputLimit
+)
+
+// Limits bounds how long a single turn's tool loop may run before the user
+// is asked whether to keep going, and how much of each tool result the
+// model sees. Zero values fall back to the defaults.
+type Limits struct {
+	MaxIterations int           `mapstructure:"max_iterations"`  // Model responses per turn
+	TurnTimeout   time.Duration `mapstructure:"turn_timeout"`    // Wall-clock time per turn
+	MaxRepeats    int           `mapstructure:"max_repeats"`     // Identical tool calls per turn
+	MaxToolOutput int           `mapstructure:"max_tool_output"` // Bytes of a tool result sent to the model
+}
+
+// withDefaults returns l with unset fields filled in
+func (l Limits) withDefaults() Limits {
+	if l.MaxIterations <= 0 {
+		l.MaxIterations = DefaultMaxIterations
+	}
+	if l.TurnTimeout <= 0 {
+		l.TurnTimeout = DefaultTurnTimeout
+	}
+	if l.MaxRepeats <= 0 {
+		l.MaxRepeats = DefaultMaxRepeats
+	}
+	if l.MaxToolOutput <= 0 {
+		l.MaxToolOutput = DefaultMaxToolOutput
+	}
+	return l
+}
+
+// turnBudget tracks a turn's progress against its limits. Extending the
+// budget after the user chooses to keep going grants a fresh allowance.
+type turnBudget struct {
+	limits    Limits
+	start     time.Time
+	firstIter int            // Iteration the current allowance started at
+	calls     map[string]int // Tool call signature -> times called
+}
+
+func newTurnBudget(limits Limits) *turnBudget {
+	return &turnBudget{
+		limits: limits.withDefaults(),
+		start:  time.Now(),
+		calls:  make(map[string]int),
+	}
+}
+
+// exceeded returns why the turn must pause before iteration i, or "" if it
+// is still within budget
+func (b *turnBudget) exceeded(i int) string {
+	if n := i - b.firstIter; n >= b.limits.MaxIterations {
+		return fmt.Sprintf("Reached the limit of %d tool-loop iterations for this turn", n)
+	}
+	if elapsed := time.Since(b.start); elapsed >= b.limits.TurnTimeout {
+		return fmt.Sprintf("Turn has been running for %s (limit %s)", elapsed.Round(time.Second), b.limits.TurnTimeout)
+	}
+	return ""
+}
+
+// repeated records a tool call and returns why the model looks stuck when
+// it has made the identical call too many times this turn, or ""
+func (b *turnBudget) repeated(tool string, params map[string]interface{}) string {
+	sig := toolSignature(tool, params)
+	b.calls[sig]++
+	if n := b.calls[sig]; n >= b.limits.MaxRepeats {
+		return fmt.Sprintf("The model called %s with identical parameters %d times", tool, n)
+	}
+	return ""
+}
+
+// extend grants a fresh allowance starting at iteration i
+func (b *turnBudget) extend(i int) {
+	b.start = time.Now()
+	b.firstIter = i
+	b.calls = make(map[string]int)
+}
+
+// toolSignature identifies a tool call by name and parameters. JSON encoding
+// sorts map keys, so equal parameters give equal signatures.
+func toolSignature(tool string, params map[string]interface{}) string {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Sprintf("%s %v", tool, params)
+	}
+	return tool + " " + string(data)
+}
+
+// keepGoing reports a reached limit and asks whether to continue the turn.
+// Without a Confirm callback the turn stops.
+func (a *Assistant) keepGoing(reason string) bool {
+	a.out.warn(reason)
+	if a.options.Confirm != nil && a.options.Confirm("Keep going?") {
+		return true
+	}
+	a.out.info("Turn stopped. Send a message to continue or redirect the model.")
+	return false
+}
+
+// Limits returns the tool-loop limits in effect
+func (a *Assistant) Limits() Limits {
+	return a.options.Limits.withDefaults()
+}
+
+// toolOutputLimit returns the size in bytes of the largest tool result sent
+// to the model whole: the configured limit, and at most about a quarter of
+// the context window at four bytes per token
+func (a *Assistant) toolOutputLimit() int {
+	limit := a.Limits().MaxToolOutput
+	if window := a.contextWindow(); window > 0 {
+		limit = min(limit, window)
+	}
+	return limit
+}
@@ -0,0 +1,59 @@
+package assistant
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTurnBudgetIterations(t *testing.T) {
+	b := newTurnBudget(Limits{MaxIterations: 3})
+	for i := 0; i < 3; i++ {
+		if reason := b.exceeded(i); reason != "" {
+			t.Fatalf("iteration %d: unexpected limit %q", i, reason)
+		}
+	}
+	if b.exceeded(3) == "" {
+		t.Fatal("expected iteration limit at 3")
+	}
+
+	b.extend(3)
+	if reason := b.exceeded(5); reason != "" {
+		t.Errorf("after extend: unexpected limit %q", reason)
+	}
+	if b.exceeded(6) == "" {
+		t.Error("expected iteration limit at 6 after extend")
+	}
+}
+
+func TestTurnBudgetTimeout(t *testing.T) {
+	b := newTurnBudget(Limits{TurnTimeout: time.Minute})
+	b.start = time.Now().Add(-2 * time.Minute)
+	if b.exceeded(1) == "" {
+		t.Error("expected wall-clock limit")
+	}
+	b.extend(1)
+	if reason := b.exceeded(1); reason != "" {
+		t.Errorf("after extend: unexpected limit %q", reason)
+	}
+}
+
+func TestTurnBudgetRepeats(t *testing.T) {
+	b := newTurnBudget(Limits{MaxRepeats: 2})
+	read := map[string]interface{}{"file_path": "main.go", "start_line": float64(1)}
+	reordered := map[string]interface{}{"start_line": float64(1), "file_path": "main.go"}
+
+	if reason := b.repeated("read_file", read); reason != "" {
+		t.Fatalf("first call flagged: %q", reason)
+	}
+	if reason := b.repeated("read_file", map[string]interface{}{"file_path": "other.go"}); reason != "" {
+		t.Fatalf("different params flagged: %q", reason)
+	}
+	if b.repeated("read_file", reordered) == "" {
+		t.Error("identical call not flagged")
+	}
+
+	b.extend(4)
+	if reason := b.repeated("read_file", read); reason != "" {
+		t.Errorf("after extend: call flagged: %q", reason)
+	}
+}
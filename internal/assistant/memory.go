@@ -0,0 +1,85 @@
+package assistant
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/tara-vision/taracode/internal/context"
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+// maxPromptMemories caps the memories included in the system prompt
+const maxPromptMemories = 40
+
+// memoryPrompt returns the system prompt section listing remembered facts
+// relevant to the project and the project's custom prompt rules, or ""
+// when there are none
+func memoryPrompt(workingDir string, storageMgr *storage.Manager) string {
+	project, _ := storage.ProjectMemory(workingDir).List()
+	var user []storage.Memory
+	if store, err := storage.UserMemory(); err == nil {
+		user, _ = store.List()
+	}
+	memories := storage.RelevantMemories(project, user, projectKeywords(workingDir), maxPromptMemories)
+
+	var rules []string
+	if storageMgr != nil {
+		rules = storageMgr.GetPreferences().CustomPromptRules
+	}
+
+	var sb strings.Builder
+	if len(rules) > 0 {
+		sb.WriteString("\n\n## PROJECT RULES\n")
+		for _, rule := range rules {
+			sb.WriteString(fmt.Sprintf("- %s\n", rule))
+		}
+	}
+	if len(memories) > 0 {
+		sb.WriteString("\n\n## MEMORY\nFacts remembered from earlier sessions. Follow them; use forget with the id if one is wrong.\n")
+		for _, m := range memories {
+			sb.WriteString(fmt.Sprintf("- [%s] %s\n", m.ID, m.Content))
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// projectKeywords describes the project for matching tagged user memories:
+// its directory name, language, module name and dependencies
+func projectKeywords(workingDir string) []string {
+	projectCtx := &context.ProjectContext{}
+	detectProjectType(workingDir, projectCtx)
+
+	keywords := []string{filepath.Base(workingDir)}
+	if projectCtx.ProjectType != "" {
+		// "Node.js" also matches the tag "node"
+		projectType := strings.ToLower(projectCtx.ProjectType)
+		keywords = append(keywords, projectType, strings.Split(projectType, ".")[0])
+	}
+	if projectCtx.ModuleName != "" {
+		keywords = append(keywords, projectCtx.ModuleName, path.Base(projectCtx.ModuleName))
+	}
+	for _, dep := range projectCtx.Dependencies {
+		keywords = append(keywords, dep, path.Base(dep))
+	}
+	return keywords
+}
+
+// RefreshSystemPrompt rebuilds the system prompt so memory, rule, plan and
+// mode changes apply to the rest of the session
+func (a *Assistant) RefreshSystemPrompt() {
+	if len(a.conversation) > 0 {
+		a.conversation[0].Content = buildSystemPrompt(a.workingDir, a.storage, a.toolRegistry) + a.modePrompt()
+	}
+}
+
+// changesPrompt reports whether a tool changes what buildSystemPrompt
+// includes, so the prompt is refreshed after it succeeds
+func changesPrompt(tool string) bool {
+	switch tool {
+	case "remember", "forget", "plan_create", "plan_update_task", "plan_add_task", "plan_complete":
+		return true
+	}
+	return false
+}
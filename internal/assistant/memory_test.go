@@ -0,0 +1,69 @@
+package assistant
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tara-vision/taracode/internal/semantic"
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+func TestMemoryPrompt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.23\n"), 0644)
+
+	storageMgr, err := storage.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt := buildSystemPrompt(dir, storageMgr, newToolRegistry(storageMgr)); strings.Contains(prompt, "## MEMORY") {
+		t.Error("memory section shown with no memories")
+	}
+
+	storage.ProjectMemory(dir).Add("Orders are stored in cents", nil)
+	user, _ := storage.UserMemory()
+	user.Add("Prefer table-driven tests", []string{"go"})
+	user.Add("Use black for formatting", []string{"python"})
+	prefs := storageMgr.GetPreferences()
+	prefs.CustomPromptRules = []string{"Never edit generated files"}
+	storageMgr.SavePreferences(prefs)
+
+	prompt := buildSystemPrompt(dir, storageMgr, newToolRegistry(storageMgr))
+	for _, want := range []string{"## PROJECT RULES\n- Never edit generated files", "Orders are stored in cents", "Prefer table-driven tests"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q", want)
+		}
+	}
+	if strings.Contains(prompt, "black") {
+		t.Error("memory tagged for another language included")
+	}
+	if strings.Index(prompt, "Orders") > strings.Index(prompt, "table-driven") {
+		t.Error("project memories should come before user memories")
+	}
+}
+
+func TestPromptAdvertisesRegisteredTools(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	storageMgr, err := storage.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := newToolRegistry(storageMgr)
+	prompt := buildSystemPrompt(dir, storageMgr, registry)
+	if strings.Contains(prompt, "- semantic_search:") {
+		t.Error("semantic_search offered without an embedding model")
+	}
+	if !strings.Contains(prompt, "- read_file:") {
+		t.Error("prompt missing read_file")
+	}
+
+	registry.RegisterSemanticSearch(semantic.NewEmbedder(nil, "nomic-embed-text"))
+	if prompt := buildSystemPrompt(dir, storageMgr, registry); !strings.Contains(prompt, "- semantic_search:") {
+		t.Error("prompt missing semantic_search once registered")
+	}
+}
@@ -0,0 +1,82 @@
+package assistant
+
+import (
+	"fmt"
+
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+// Mode controls what the assistant may do with the workspace
+type Mode string
+
+const (
+	// ModeExecute is the default: all tools are available
+	ModeExecute Mode = "execute"
+	// ModePlan allows only read-only tools; the model investigates and
+	// proposes a plan for the user to review before anything changes
+	ModePlan Mode = "plan"
+)
+
+// ParseMode validates a mode name
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case ModeExecute, ModePlan:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("unknown mode %q (use plan or execute)", s)
+}
+
+// planModePrompt is added to the system prompt in plan mode
+const planModePrompt = `
+
+## PLAN MODE
+You are in plan mode. Only read-only tools run: investigate the code with read_file, search_files, find_files, list_files and the git tools, but do not try to edit files or run commands.
+When you understand the change, write it down with plan_create: a short description and concrete tasks in the order they should be done, using subtasks and notes for detail (files to touch, risks, how to verify). Revise it with plan_add_task and plan_update_task when the user asks for changes.
+Then summarize the plan and ask the user to review it. They approve it with /approve, which switches to execution mode.`
+
+// ApprovedPlanMessage is sent to the model to start working through an
+// approved plan
+const ApprovedPlanMessage = "The plan is approved. Work through its tasks in order: mark each one in_progress with plan_update_task before starting it and completed when it is done, then call plan_complete once every task is finished."
+
+// Mode returns the current mode
+func (a *Assistant) Mode() Mode {
+	if a.mode == "" {
+		return ModeExecute
+	}
+	return a.mode
+}
+
+// SetMode switches between plan and execution mode. Plan mode restricts
+// the tools to read-only ones and tells the model to propose a plan.
+func (a *Assistant) SetMode(mode Mode) {
+	a.mode = mode
+	a.toolRegistry.SetReadOnly(mode == ModePlan)
+	a.RefreshSystemPrompt()
+}
+
+// ApprovePlan switches from plan mode to execution mode once there is a
+// plan to carry out. The user may have edited the plan before approving
+// it; the model sees the current version in the system prompt.
+func (a *Assistant) ApprovePlan() (*storage.Plan, error) {
+	if a.storage == nil {
+		return nil, fmt.Errorf("storage not initialized")
+	}
+	plan, err := a.storage.GetActivePlan()
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil || len(plan.Tasks) == 0 {
+		return nil, fmt.Errorf("no plan to approve: ask for one in plan mode or create it with /plan new")
+	}
+
+	a.SetMode(ModeExecute)
+	return plan, nil
+}
+
+// modePrompt returns the system prompt section for the current mode
+func (a *Assistant) modePrompt() string {
+	if a.Mode() == ModePlan {
+		return planModePrompt
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package assistant
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+func TestPlanMode(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := scripted(t, func(n int) string {
+		switch n {
+		case 0:
+			return `{"tool": "read_file", "params": {"file_path": "main.go"}} {"tool": "write_file", "params": {"file_path": "main.go", "content": "changed"}}`
+		case 1:
+			return `{"tool": "plan_create", "params": {"title": "Add greeting", "tasks": ["Add main function"]}}`
+		}
+		return "Here is the plan. Approve it with /approve."
+	})
+	a, sink := newTestAssistant(t, server, Limits{})
+
+	if _, err := a.ApprovePlan(); err == nil {
+		t.Error("ApprovePlan succeeded without a plan")
+	}
+
+	a.SetMode(ModePlan)
+	if !strings.Contains(a.conversation[0].Content, "## PLAN MODE") {
+		t.Error("plan mode instructions missing from system prompt")
+	}
+	if err := a.runTurn("print a greeting", completionSource{}); err != nil {
+		t.Fatalf("runTurn: %v", err)
+	}
+
+	// Reads and the plan run; writes are refused and the file is untouched
+	if got := strings.Join(sink.tools, ","); got != "read_file,write_file!,plan_create" {
+		t.Errorf("tool output = %s", got)
+	}
+	if !strings.Contains(server.LastMessage(1), "not available in plan mode") {
+		t.Errorf("refusal not reported to the model:\n%s", server.LastMessage(1))
+	}
+	if data, _ := os.ReadFile(filepath.Join(a.workingDir, "main.go")); string(data) != "package main\n" {
+		t.Errorf("main.go changed in plan mode: %q", data)
+	}
+
+	plan, err := a.ApprovePlan()
+	if err != nil || plan.Title != "Add greeting" {
+		t.Fatalf("ApprovePlan = %+v, %v", plan, err)
+	}
+	if a.Mode() != ModeExecute || strings.Contains(a.conversation[0].Content, "## PLAN MODE") {
+		t.Error("approval did not switch to execution mode")
+	}
+	if _, err := a.toolRegistry.ExecuteTool("write_file", map[string]interface{}{"file_path": "main.go", "content": "package main\n"}, a.workingDir); err != nil {
+		t.Errorf("write_file refused after approval: %v", err)
+	}
+	if active, _ := a.storage.GetActivePlan(); active == nil || active.Tasks[0].Status != storage.TaskStatusPending {
+		t.Errorf("approved plan not kept active: %+v", active)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if mode, err := ParseMode("plan"); err != nil || mode != ModePlan {
+		t.Errorf("ParseMode(plan) = %q, %v", mode, err)
+	}
+	if _, err := ParseMode("review"); err == nil {
+		t.Error("ParseMode accepted an unknown mode")
+	}
+}
@@ -0,0 +1,140 @@
+package assistant
+
+import (
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/tara-vision/taracode/internal/cassette"
+	"github.com/tara-vision/taracode/internal/provider"
+	"github.com/tara-vision/taracode/internal/storage"
+	"github.com/tara-vision/taracode/internal/tokens"
+	"github.com/tara-vision/taracode/internal/ui"
+)
+
+// ReplayOptions configures Replay
+type ReplayOptions struct {
+	Live     bool // Ask the model configured in Options instead of replaying recorded responses
+	RunTools bool // Execute tool calls that have no recorded result instead of failing them
+}
+
+// promptHash identifies a system prompt so replays can tell it changed
+func promptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// playbackSource serves recorded responses instead of querying a model
+type playbackSource struct {
+	player *cassette.Player
+}
+
+func (s playbackSource) fetch(ctx gocontext.Context, client *openai.Client, req openai.ChatCompletionRequest) (string, *storage.TokenUsage, error) {
+	return s.player.Next()
+}
+
+// quietSink discards turn output for replays and evals, which report
+// results instead
+type quietSink struct{}
+
+func (quietSink) spin(string) func()                 { return func() {} }
+func (quietSink) progress(string)                    {}
+func (quietSink) reasoning(string)                   {}
+func (quietSink) text(string)                        {}
+func (quietSink) toolResult(*ToolCall, string, bool) {}
+func (quietSink) warn(string)                        {}
+func (quietSink) info(string)                        {}
+
+// executeTool runs a tool call. During a replay the recorded result of an
+// identical call is used instead, so replays never touch the workspace
+// unless asked to.
+func (a *Assistant) executeTool(call *ToolCall) (string, error) {
+	if a.player != nil {
+		if result, isError, ok := a.player.ToolResult(call.Tool, call.Params); ok {
+			if isError {
+				return "", errors.New(strings.TrimPrefix(result, "Error: "))
+			}
+			return result, nil
+		}
+		if !a.runUnrecorded {
+			return "", fmt.Errorf("no recorded result for this call (replay with --run-tools to execute it)")
+		}
+	}
+	return a.toolRegistry.ExecuteTool(call.Tool, call.Params, a.workingDir)
+}
+
+// Replay runs the user inputs of a recorded session again and returns the
+// new recording for comparison with cassette.Compare. By default the
+// recorded responses are played back, which checks taracode's own handling
+// of them; with Live set the inputs go to the model configured in opts.
+// Tool calls are answered from the recording either way.
+func Replay(c *cassette.Cassette, opts Options, replay ReplayOptions) (*cassette.Cassette, error) {
+	var a *Assistant
+	var err error
+	if replay.Live {
+		opts.Quiet = true
+		opts.Ephemeral = true
+		opts.Record = ""
+		a, err = New(opts)
+	} else {
+		a, err = newPlayback(c, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	player := cassette.NewPlayer(c)
+	a.player = player
+	a.runUnrecorded = replay.RunTools
+	a.out = quietSink{}
+	a.recorder = cassette.NewRecorder("", a.provider.Info().Host, a.model, promptHash(a.conversation[0].Content))
+
+	var source responseSource = playbackSource{player: player}
+	if replay.Live {
+		source = a.responseSource()
+	}
+
+	// A failed turn is recorded and compared like any other
+	for _, turn := range c.Turns {
+		player.BeginTurn()
+		a.runTurn(turn.Input, source)
+	}
+	return a.recorder.Cassette(), nil
+}
+
+// newPlayback creates an assistant for deterministic replay: it never
+// contacts a server and keeps no session history
+func newPlayback(c *cassette.Cassette, opts Options) (*Assistant, error) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	// The provider only supplies metadata; playback sends no requests
+	prov := provider.NewOpenAIProvider(c.Host, "")
+	prov.SetModel(c.Model)
+
+	// Project storage shapes the system prompt but no session is kept
+	storageMgr, _ := storage.NewManager(workingDir)
+
+	registry := newToolRegistry(storageMgr)
+	a := &Assistant{
+		provider:     prov,
+		model:        c.Model,
+		conversation: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleSystem, Content: buildSystemPrompt(workingDir, storageMgr, registry)}},
+		toolRegistry: registry,
+		workingDir:   workingDir,
+		renderer:     ui.NewRenderer(),
+		storage:      storageMgr,
+		sessionUsage: &storage.TokenUsage{},
+		counter:      tokens.NewCounter(prov),
+		options:      opts,
+		thinking:     thinkingFromPreferences(storageMgr),
+	}
+	return a, nil
+}
@@ -0,0 +1,172 @@
+package assistant
+
+import (
+	gocontext "context"
+	"fmt"
+	"strings"
+
+	"github.com/tara-vision/taracode/internal/provider"
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+// Thinking switch mechanisms
+const (
+	ThinkingSwitchAuto  = "auto"  // Pick per model and server
+	ThinkingSwitchSoft  = "soft"  // Append /think or /no_think to user messages
+	ThinkingSwitchKwarg = "kwarg" // Send chat_template_kwargs.enable_thinking
+	ThinkingSwitchNone  = "none"  // Model has no thinking switch
+)
+
+// Reasoning display modes
+const (
+	ReasoningHide      = "hide"
+	ReasoningCollapsed = "collapsed"
+	ReasoningFull      = "full"
+)
+
+// collapsedReasoningLines is how many reasoning lines are shown when collapsed
+const collapsedReasoningLines = 6
+
+// splitReasoning separates a <think> block from the answer. Qwen3 chat
+// templates may open the block in the prompt, so a lone </think> marks
+// everything before it as reasoning.
+func splitReasoning(response string) (reasoning, answer string) {
+	end := strings.Index(response, "</think>")
+	if end == -1 {
+		return "", strings.TrimSpace(response)
+	}
+
+	head := response[:end]
+	if start := strings.Index(head, "<think>"); start != -1 {
+		reasoning = head[start+len("<think>"):]
+		head = head[:start]
+	} else {
+		reasoning, head = head, ""
+	}
+
+	answer = head + response[end+len("</think>"):]
+	return strings.TrimSpace(reasoning), strings.TrimSpace(answer)
+}
+
+// modelConfig returns the config entry matching the active model, if any
+func (a *Assistant) modelConfig() *ModelConfig {
+	for i := range a.options.Models {
+		if a.options.Models[i].matches(a.model) {
+			return &a.options.Models[i]
+		}
+	}
+	return nil
+}
+
+// thinkingSwitch decides how reasoning mode is toggled for the active model.
+// Qwen3 on vLLM and llama.cpp honors the enable_thinking template kwarg;
+// elsewhere the /think and /no_think soft switches are used.
+func (a *Assistant) thinkingSwitch() string {
+	if mc := a.modelConfig(); mc != nil && mc.ThinkingSwitch != "" && mc.ThinkingSwitch != ThinkingSwitchAuto {
+		return mc.ThinkingSwitch
+	}
+	if !strings.Contains(strings.ToLower(a.model), "qwen3") {
+		return ThinkingSwitchNone
+	}
+	switch a.provider.Info().Type {
+	case provider.TypeVLLM, provider.TypeLlamaCpp:
+		return ThinkingSwitchKwarg
+	default:
+		return ThinkingSwitchSoft
+	}
+}
+
+// thinkingPreference returns the /think setting, or nil if never set
+func (a *Assistant) thinkingPreference() *bool {
+	if a.storage == nil {
+		return nil
+	}
+	return a.storage.GetPreferences().Thinking
+}
+
+// Thinking reports whether reasoning mode is on and how it is switched
+func (a *Assistant) Thinking() (on bool, mechanism string) {
+	return a.thinking, a.thinkingSwitch()
+}
+
+// SetThinking turns reasoning mode on or off and persists the choice
+func (a *Assistant) SetThinking(on bool) error {
+	a.thinking = on
+	if a.storage == nil {
+		return nil
+	}
+	prefs := *a.storage.GetPreferences()
+	prefs.Thinking = &on
+	return a.storage.SavePreferences(&prefs)
+}
+
+// ReasoningDisplay returns how reasoning is shown in the terminal
+func (a *Assistant) ReasoningDisplay() string {
+	if a.storage != nil {
+		if mode := a.storage.GetPreferences().ReasoningDisplay; mode != "" {
+			return mode
+		}
+	}
+	return ReasoningHide
+}
+
+// SetReasoningDisplay sets how reasoning is shown and persists the choice
+func (a *Assistant) SetReasoningDisplay(mode string) error {
+	switch mode {
+	case ReasoningHide, ReasoningCollapsed, ReasoningFull:
+	default:
+		return fmt.Errorf("unknown reasoning display %q (use hide, collapsed or full)", mode)
+	}
+	if a.storage == nil {
+		return fmt.Errorf("storage not initialized")
+	}
+	prefs := *a.storage.GetPreferences()
+	prefs.ReasoningDisplay = mode
+	return a.storage.SavePreferences(&prefs)
+}
+
+// requestContext attaches the enable_thinking template kwarg when the
+// user has set /think and the server supports it
+func (a *Assistant) requestContext(ctx gocontext.Context) gocontext.Context {
+	if a.thinkingPreference() == nil || a.thinkingSwitch() != ThinkingSwitchKwarg {
+		return ctx
+	}
+	return provider.WithExtraBody(ctx, map[string]any{
+		"chat_template_kwargs": map[string]any{"enable_thinking": a.thinking},
+	})
+}
+
+// modelUserContent appends the Qwen3 soft switch to a user message when the
+// user has set /think and the soft switch is in use
+func (a *Assistant) modelUserContent(message string) string {
+	if a.thinkingPreference() == nil || a.thinkingSwitch() != ThinkingSwitchSoft {
+		return message
+	}
+	if a.thinking {
+		return message + " /think"
+	}
+	return message + " /no_think"
+}
+
+// showReasoning prints reasoning dimmed according to the display mode
+func (a *Assistant) showReasoning(reasoning string) {
+	mode := a.ReasoningDisplay()
+	if reasoning == "" || mode == ReasoningHide {
+		return
+	}
+	maxLines := 0
+	if mode == ReasoningCollapsed {
+		maxLines = collapsedReasoningLines
+	}
+	fmt.Println(a.renderer.FormatReasoning(reasoning, maxLines))
+}
+
+// thinkingFromPreferences returns the initial reasoning mode for a project
+func thinkingFromPreferences(storageMgr *storage.Manager) bool {
+	if storageMgr != nil {
+		if on := storageMgr.GetPreferences().Thinking; on != nil {
+			return *on
+		}
+	}
+	return true // Qwen3 thinks by default
+}
@@ -0,0 +1,21 @@
+package assistant
+
+import "testing"
+
+func TestSplitReasoning(t *testing.T) {
+	cases := []struct {
+		name, input, reasoning, answer string
+	}{
+		{"no reasoning", "Hello", "", "Hello"},
+		{"think block", "<think>\nplan it\n</think>\n\nDone.", "plan it", "Done."},
+		{"empty block", "<think>\n\n</think>\nDone.", "", "Done."},
+		{"opened in prompt", "plan it\n</think>\nDone.", "plan it", "Done."},
+		{"text before block", "Sure. <think>hmm</think> Done.", "hmm", "Sure.  Done."},
+	}
+	for _, tc := range cases {
+		reasoning, answer := splitReasoning(tc.input)
+		if reasoning != tc.reasoning || answer != tc.answer {
+			t.Errorf("%s: splitReasoning = (%q, %q), want (%q, %q)", tc.name, reasoning, answer, tc.reasoning, tc.answer)
+		}
+	}
+}
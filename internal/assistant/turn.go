@@ -0,0 +1,369 @@
+package assistant
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/tara-vision/taracode/internal/storage"
+	"github.com/tara-vision/taracode/internal/tools"
+	"github.com/tara-vision/taracode/internal/ui"
+)
+
+// responseSource fetches one complete model response. Sources differ only
+// in transport; the turn engine handles parsing, tools and recording.
+type responseSource interface {
+	fetch(ctx gocontext.Context, client *openai.Client, req openai.ChatCompletionRequest) (content string, usage *storage.TokenUsage, err error)
+}
+
+// streamSource reads the response as a server-sent event stream
+type streamSource struct {
+	options *openai.StreamOptions // Requests a final usage chunk when set
+}
+
+func (s streamSource) fetch(ctx gocontext.Context, client *openai.Client, req openai.ChatCompletionRequest) (string, *storage.TokenUsage, error) {
+	req.StreamOptions = s.options
+	stream, err := client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create stream: %w", err)
+	}
+	defer stream.Close()
+
+	// Buffer the response while the spinner runs; it is rendered whole
+	filter := NewStreamFilter()
+	var usage *storage.TokenUsage
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("stream error: %w", err)
+		}
+
+		if len(chunk.Choices) > 0 {
+			filter.Process(chunk.Choices[0].Delta.Content)
+		}
+
+		// Capture usage from final chunk (when StreamOptions.IncludeUsage is true)
+		if chunk.Usage != nil {
+			usage = &storage.TokenUsage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}
+		}
+	}
+	return filter.FullContent(), usage, nil
+}
+
+// completionSource requests a single non-streamed completion
+type completionSource struct{}
+
+func (completionSource) fetch(ctx gocontext.Context, client *openai.Client, req openai.ChatCompletionRequest) (string, *storage.TokenUsage, error) {
+	resp, err := client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get response: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", nil, fmt.Errorf("no response choices returned")
+	}
+
+	var usage *storage.TokenUsage
+	if resp.Usage.TotalTokens > 0 {
+		usage = &storage.TokenUsage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		}
+	}
+	return resp.Choices[0].Message.Content, usage, nil
+}
+
+// outputSink receives everything a turn shows the user
+type outputSink interface {
+	spin(message string) (stop func())
+	progress(message string) // Replaces the running spinner's message
+	reasoning(text string)
+	text(markdown string)
+	toolResult(call *ToolCall, result string, isError bool)
+	warn(msg string)
+	info(msg string)
+}
+
+// terminalSink renders turn output to the terminal
+type terminalSink struct {
+	a       *Assistant
+	spinner *ui.Spinner // The running spinner, if any
+}
+
+func (t *terminalSink) spin(message string) func() {
+	if !t.a.enableSpinner {
+		return func() {}
+	}
+	spinner := ui.NewSpinner()
+	spinner.Start(message)
+	t.spinner = spinner
+	return func() {
+		spinner.Stop()
+		if t.spinner == spinner {
+			t.spinner = nil
+		}
+	}
+}
+
+func (t *terminalSink) progress(message string) {
+	if t.spinner != nil {
+		t.spinner.UpdateMessage(message)
+	}
+}
+
+func (t *terminalSink) reasoning(text string) {
+	t.a.showReasoning(text)
+}
+
+func (t *terminalSink) text(markdown string) {
+	fmt.Println(ui.RenderMarkdown(markdown))
+}
+
+func (t *terminalSink) toolResult(call *ToolCall, result string, isError bool) {
+	fmt.Println(t.a.renderer.FormatToolStatus(call.Tool, call.Params, result, isError))
+}
+
+func (t *terminalSink) warn(msg string) {
+	fmt.Println(t.a.renderer.WarningMessage(msg))
+}
+
+func (t *terminalSink) info(msg string) {
+	fmt.Println(t.a.renderer.InfoMessage(msg))
+}
+
+// recordMessage saves a message to the active session, if any
+func (a *Assistant) recordMessage(msg storage.ConversationMessage) {
+	if a.storage != nil && a.session != nil {
+		a.storage.AddMessage(a.session.ID, msg)
+	}
+}
+
+// runTurn sends a user message and runs the tool loop until the model
+// answers without calling tools or a limit stops the turn
+func (a *Assistant) runTurn(userMessage string, source responseSource) (err error) {
+	a.recorder.BeginTurn(userMessage)
+	defer func() {
+		a.recorder.Fail(err)
+		if saveErr := a.recorder.Save(); saveErr != nil {
+			a.out.warn(fmt.Sprintf("Could not save recording: %v", saveErr))
+		}
+	}()
+
+	a.recordMessage(storage.ConversationMessage{
+		Role:      "user",
+		Content:   userMessage,
+		Timestamp: time.Now(),
+	})
+
+	a.turnStart = len(a.conversation)
+	a.conversation = append(a.conversation, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: a.modelUserContent(userMessage),
+	})
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	defer cancel()
+
+	budget := newTurnBudget(a.options.Limits)
+	a.turnEdited, a.verifyRounds = false, 0
+
+	for i := 0; ; i++ {
+		if reason := budget.exceeded(i); reason != "" {
+			if !a.keepGoing(reason) {
+				return nil
+			}
+			budget.extend(i)
+		}
+
+		a.fitContext(ctx)
+
+		done, err := a.runIteration(ctx, source, budget, i)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// runIteration requests one model response and executes any tool calls in
+// it. It reports done when the turn is over.
+func (a *Assistant) runIteration(ctx gocontext.Context, source responseSource, budget *turnBudget, i int) (done bool, err error) {
+	params := a.GenerationParams()
+	req := openai.ChatCompletionRequest{
+		Model:    a.model,
+		Messages: a.conversation,
+	}
+	applyParams(&req, params)
+
+	// Each request gets its own response timeout
+	stopSpinner := a.out.spin("Thinking...")
+	requestStart := time.Now()
+	reqCtx, cancelReq := gocontext.WithTimeout(a.requestContext(ctx), apiResponseTimeout)
+	content, usage, err := source.fetch(reqCtx, a.client, req)
+	cancelReq()
+	stopSpinner()
+	a.recorder.Response(req, content, usage, err)
+	if err != nil {
+		return true, err
+	}
+	usage = a.recordUsage(ctx, usage, req.Messages, content, requestStart)
+
+	// Keep reasoning out of the conversation fed back to the model
+	reasoning, response := splitReasoning(content)
+	a.out.reasoning(reasoning)
+
+	// Parse for tool calls from content (supports multiple)
+	toolCalls, displayText := parseToolCalls(response)
+	if displayText != "" {
+		a.out.text(displayText)
+	}
+	a.conversation = append(a.conversation, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleAssistant,
+		Content: response,
+	})
+
+	record := storage.ConversationMessage{
+		Role:      "assistant",
+		Content:   response,
+		Model:     a.model,
+		Params:    params,
+		Reasoning: reasoning,
+		Usage:     usage,
+		Iteration: i + 1,
+	}
+
+	if len(toolCalls) == 0 {
+		a.recorder.Answer(displayText)
+		record.Timestamp = time.Now()
+		a.recordMessage(record)
+
+		// Check the turn's edits before handing back; failures go to the
+		// model to fix
+		if diagnostics := a.verifyEdits(); diagnostics != "" {
+			a.conversation = append(a.conversation, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleUser,
+				Content: diagnostics,
+			})
+			return false, nil
+		}
+		return true, nil
+	}
+
+	results, stuck := a.runTools(toolCalls, budget, record)
+
+	// Add all tool results to conversation in one message, with the images
+	// read_file attached
+	a.conversation = append(a.conversation, toolResultsMessage(results, a.toolRegistry.TakeImages()))
+
+	if stuck != "" {
+		if !a.keepGoing(stuck) {
+			return true, nil
+		}
+		budget.extend(i + 1)
+	}
+	return false, nil
+}
+
+// runTools executes a response's tool calls, recording each one against
+// the response, and returns the aggregated results for the model. Calls the
+// model keeps repeating are skipped and reported as stuck.
+func (a *Assistant) runTools(toolCalls []*ToolCall, budget *turnBudget, record storage.ConversationMessage) (results string, stuck string) {
+	var allResults strings.Builder
+	totalTools := len(toolCalls)
+	a.toolRegistry.SetOutputLimit(a.toolOutputLimit())
+	a.toolRegistry.SetVision(a.acceptsImages())
+
+	for idx, toolCall := range toolCalls {
+		message := fmt.Sprintf("Running %s...", toolCall.Tool)
+		if totalTools > 1 {
+			message = fmt.Sprintf("Running %s (%d/%d)...", toolCall.Tool, idx+1, totalTools)
+		}
+		stopSpinner := a.out.spin(message)
+		a.toolRegistry.SetProgress(func(status string) {
+			a.out.progress(message + " " + status)
+		})
+
+		// Execute the tool unless the model keeps repeating it
+		startTime := time.Now()
+		var result string
+		var err error
+		if reason := budget.repeated(toolCall.Tool, toolCall.Params); reason != "" {
+			stuck = reason
+			err = fmt.Errorf("identical call already made this turn; use the earlier result or try a different approach")
+		} else {
+			result, err = a.executeTool(toolCall)
+		}
+		duration := time.Since(startTime).Milliseconds()
+		isError := err != nil
+		if isError {
+			result = fmt.Sprintf("Error: %v", err)
+		}
+
+		stopSpinner()
+		a.out.toolResult(toolCall, result, isError)
+		if !isError && changesPrompt(toolCall.Tool) {
+			a.RefreshSystemPrompt()
+		}
+		if !isError && tools.ChangesFiles(toolCall.Tool) {
+			a.turnEdited = true
+		}
+		a.recorder.ToolResult(toolCall.Tool, toolCall.Params, result, isError)
+
+		// Aggregate results for sending back to LLM
+		if totalTools > 1 {
+			allResults.WriteString(fmt.Sprintf("[%d] %s result:\n%s\n\n", idx+1, toolCall.Tool, result))
+		} else {
+			allResults.WriteString(fmt.Sprintf("Tool result:\n%s", result))
+		}
+
+		toolMsg := record
+		toolMsg.Timestamp = time.Now()
+		toolMsg.ToolCall = &storage.ToolCallRecord{
+			Tool:     toolCall.Tool,
+			Params:   toolCall.Params,
+			Result:   result,
+			Duration: duration,
+			Success:  !isError,
+		}
+		a.recordMessage(toolMsg)
+
+		// Reasoning and usage are recorded once per response
+		record.Reasoning, record.Usage = "", nil
+	}
+
+	return allResults.String(), stuck
+}
+
+// toolResultsMessage returns the message carrying a response's tool
+// results back to the model. Attached images follow the text as image
+// parts.
+func toolResultsMessage(results string, images []tools.Image) openai.ChatCompletionMessage {
+	if len(images) == 0 {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: results}
+	}
+	parts := []openai.ChatMessagePart{{Type: openai.ChatMessagePartTypeText, Text: results}}
+	for _, img := range images {
+		parts = append(parts, openai.ChatMessagePart{
+			Type:     openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{URL: img.DataURL(), Detail: openai.ImageURLDetailAuto},
+		})
+	}
+	return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, MultiContent: parts}
+}
+
+// acceptsImages reports whether the active model was probed to accept
+// image input
+func (a *Assistant) acceptsImages() bool {
+	caps := a.provider.Info().Capabilities
+	return caps != nil && caps.Vision
+}
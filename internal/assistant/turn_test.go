@@ -0,0 +1,281 @@
+package assistant
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/tara-vision/taracode/internal/llmtest"
+	"github.com/tara-vision/taracode/internal/provider"
+	"github.com/tara-vision/taracode/internal/storage"
+	"github.com/tara-vision/taracode/internal/tokens"
+	"github.com/tara-vision/taracode/internal/ui"
+)
+
+// testUsage is reported for every scripted reply
+var testUsage = openai.Usage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110}
+
+// scripted starts a fake server answering the nth request with reply(n)
+func scripted(t *testing.T, reply func(n int) string) *llmtest.Server {
+	server := llmtest.New(t)
+	server.Respond(func(n int, req openai.ChatCompletionRequest) llmtest.Reply {
+		return llmtest.Reply{Content: reply(n), Usage: &testUsage}
+	})
+	return server
+}
+
+// recordingSink captures turn output for assertions
+type recordingSink struct {
+	texts    []string
+	tools    []string // "tool" or "tool!" for failures
+	warnings []string
+}
+
+func (r *recordingSink) spin(string) func()   { return func() {} }
+func (r *recordingSink) progress(string)      {}
+func (r *recordingSink) reasoning(string)     {}
+func (r *recordingSink) text(markdown string) { r.texts = append(r.texts, markdown) }
+func (r *recordingSink) warn(msg string)      { r.warnings = append(r.warnings, msg) }
+func (r *recordingSink) info(string)          {}
+
+func (r *recordingSink) toolResult(call *ToolCall, result string, isError bool) {
+	name := call.Tool
+	if isError {
+		name += "!"
+	}
+	r.tools = append(r.tools, name)
+}
+
+// newTestAssistant creates an assistant talking to server, working in a
+// temporary project with session storage
+func newTestAssistant(t *testing.T, server *llmtest.Server, limits Limits) (*Assistant, *recordingSink) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prov := provider.NewOpenAIProvider(server.URL+"/v1", "")
+	prov.SetModel(llmtest.DefaultModel)
+	storageMgr, err := storage.NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := storageMgr.CreateSession("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &recordingSink{}
+	a := &Assistant{
+		provider:     prov,
+		client:       prov.CreateClient(),
+		model:        llmtest.DefaultModel,
+		conversation: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleSystem, Content: "system"}},
+		toolRegistry: newToolRegistry(storageMgr),
+		workingDir:   dir,
+		renderer:     ui.NewRenderer(),
+		storage:      storageMgr,
+		session:      session,
+		sessionUsage: &storage.TokenUsage{},
+		counter:      tokens.NewCounter(prov),
+		out:          sink,
+		options:      Options{Limits: limits},
+	}
+	return a, sink
+}
+
+var testSources = map[string]responseSource{
+	"streaming":     streamSource{options: &openai.StreamOptions{IncludeUsage: true}},
+	"non-streaming": completionSource{},
+}
+
+func TestTurnMultiToolResponse(t *testing.T) {
+	for name, source := range testSources {
+		t.Run(name, func(t *testing.T) {
+			server := scripted(t, func(n int) string {
+				if n == 0 {
+					return `Let me look. {"tool": "list_files", "params": {"directory": "."}} {"tool": "read_file", "params": {"file_path": "main.go"}}`
+				}
+				return "It is a Go program."
+			})
+			a, sink := newTestAssistant(t, server, Limits{})
+
+			if err := a.runTurn("what is this?", source); err != nil {
+				t.Fatalf("runTurn: %v", err)
+			}
+
+			if len(server.Requests()) != 2 {
+				t.Fatalf("got %d requests, want 2", len(server.Requests()))
+			}
+			results := server.LastMessage(1)
+			if !strings.Contains(results, "[1] list_files result:") || !strings.Contains(results, "[2] read_file result:\n") || !strings.Contains(results, "package main") {
+				t.Errorf("unexpected tool results message:\n%s", results)
+			}
+			if strings.Join(sink.tools, ",") != "list_files,read_file" {
+				t.Errorf("tool output = %v", sink.tools)
+			}
+			if len(sink.texts) != 2 || sink.texts[0] != "Let me look." || sink.texts[1] != "It is a Go program." {
+				t.Errorf("text output = %q", sink.texts)
+			}
+
+			// user, two tool records for the first response, final answer
+			msgs := a.GetSession().Messages
+			if len(msgs) != 4 {
+				t.Fatalf("session has %d messages, want 4", len(msgs))
+			}
+			if msgs[1].ToolCall == nil || msgs[1].ToolCall.Tool != "list_files" || msgs[2].ToolCall == nil || msgs[2].ToolCall.Tool != "read_file" {
+				t.Errorf("tool calls not recorded in order: %+v, %+v", msgs[1].ToolCall, msgs[2].ToolCall)
+			}
+			if msgs[1].Usage == nil || msgs[1].Usage.TotalTokens != 110 || msgs[2].Usage != nil {
+				t.Errorf("usage should be recorded once per response: %+v, %+v", msgs[1].Usage, msgs[2].Usage)
+			}
+			if msgs[1].Iteration != 1 || msgs[2].Iteration != 1 || msgs[3].Iteration != 2 {
+				t.Errorf("iterations = %d, %d, %d", msgs[1].Iteration, msgs[2].Iteration, msgs[3].Iteration)
+			}
+			if got := a.GetUsage().TotalTokens; got != 220 {
+				t.Errorf("session usage = %d, want 220", got)
+			}
+		})
+	}
+}
+
+func TestTurnToolError(t *testing.T) {
+	server := scripted(t, func(n int) string {
+		if n == 0 {
+			return `{"tool": "read_file", "params": {"file_path": "missing.go"}}`
+		}
+		return "That file does not exist."
+	})
+	a, sink := newTestAssistant(t, server, Limits{})
+
+	if err := a.runTurn("read missing.go", completionSource{}); err != nil {
+		t.Fatalf("runTurn: %v", err)
+	}
+	if !strings.HasPrefix(server.LastMessage(1), "Tool result:\nError:") {
+		t.Errorf("tool error not sent to model: %q", server.LastMessage(1))
+	}
+	if len(sink.tools) != 1 || sink.tools[0] != "read_file!" {
+		t.Errorf("tool output = %v", sink.tools)
+	}
+	if msgs := a.GetSession().Messages; msgs[1].ToolCall == nil || msgs[1].ToolCall.Success {
+		t.Errorf("failed tool call not recorded as failure: %+v", msgs[1].ToolCall)
+	}
+}
+
+func TestTurnAttachesImages(t *testing.T) {
+	server := scripted(t, func(n int) string {
+		if n%2 == 0 {
+			return `{"tool": "read_file", "params": {"file_path": "logo.gif"}}`
+		}
+		return "A tiny image."
+	})
+	a, _ := newTestAssistant(t, server, Limits{})
+	gif := "GIF89a\x01\x00\x01\x00\x00\x00\x00;"
+	if err := os.WriteFile(filepath.Join(a.workingDir, "logo.gif"), []byte(gif), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without vision the image is only described
+	if err := a.runTurn("what is in logo.gif?", completionSource{}); err != nil {
+		t.Fatalf("runTurn: %v", err)
+	}
+	if got := server.LastMessage(1); !strings.Contains(got, "logo.gif is an image (GIF image, 1x1, ") {
+		t.Errorf("tool results = %q", got)
+	}
+
+	a.provider.Info().Capabilities = &provider.Capabilities{Vision: true}
+	if err := a.runTurn("what is in logo.gif?", completionSource{}); err != nil {
+		t.Fatalf("runTurn: %v", err)
+	}
+	msgs := server.Requests()[3].Messages
+	parts := msgs[len(msgs)-1].MultiContent
+	if len(parts) != 2 || !strings.Contains(parts[0].Text, "Attached logo.gif") || parts[1].ImageURL == nil || !strings.HasPrefix(parts[1].ImageURL.URL, "data:image/gif;base64,R0lGOD") {
+		t.Errorf("tool results parts = %+v", parts)
+	}
+}
+
+func TestTurnIterationLimit(t *testing.T) {
+	server := scripted(t, func(n int) string {
+		return fmt.Sprintf(`{"tool": "list_files", "params": {"directory": "dir%d"}}`, n)
+	})
+
+	a, sink := newTestAssistant(t, server, Limits{MaxIterations: 3})
+	if err := a.runTurn("loop forever", completionSource{}); err != nil {
+		t.Fatalf("runTurn: %v", err)
+	}
+	if len(server.Requests()) != 3 {
+		t.Errorf("got %d requests, want 3", len(server.Requests()))
+	}
+	if len(sink.warnings) != 1 || !strings.Contains(sink.warnings[0], "3 tool-loop iterations") {
+		t.Errorf("warnings = %q", sink.warnings)
+	}
+
+	// Agreeing to keep going once grants another allowance
+	server = scripted(t, func(n int) string {
+		return fmt.Sprintf(`{"tool": "list_files", "params": {"directory": "dir%d"}}`, n)
+	})
+	asked := 0
+	a, _ = newTestAssistant(t, server, Limits{MaxIterations: 3})
+	a.options.Confirm = func(string) bool {
+		asked++
+		return asked == 1
+	}
+	if err := a.runTurn("loop forever", completionSource{}); err != nil {
+		t.Fatalf("runTurn: %v", err)
+	}
+	if asked != 2 || len(server.Requests()) != 6 {
+		t.Errorf("asked %d times with %d requests, want 2 and 6", asked, len(server.Requests()))
+	}
+}
+
+func TestTurnRepeatedToolCall(t *testing.T) {
+	server := scripted(t, func(n int) string {
+		return `{"tool": "list_files", "params": {"directory": "."}}`
+	})
+	a, sink := newTestAssistant(t, server, Limits{MaxRepeats: 3})
+
+	if err := a.runTurn("list files", streamSource{}); err != nil {
+		t.Fatalf("runTurn: %v", err)
+	}
+	if len(server.Requests()) != 3 {
+		t.Errorf("got %d requests, want 3", len(server.Requests()))
+	}
+	if strings.Join(sink.tools, ",") != "list_files,list_files,list_files!" {
+		t.Errorf("tool output = %v", sink.tools)
+	}
+	if len(sink.warnings) != 1 || !strings.Contains(sink.warnings[0], "identical parameters 3 times") {
+		t.Errorf("warnings = %q", sink.warnings)
+	}
+}
+
+func TestTurnRefreshesPlanInPrompt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := scripted(t, func(n int) string {
+		if n == 0 {
+			return `{"tool": "plan_create", "params": {"title": "Ship it", "tasks": ["Build", "Release"]}}`
+		}
+		return "Plan ready."
+	})
+	a, sink := newTestAssistant(t, server, Limits{})
+
+	if err := a.runTurn("plan the release", completionSource{}); err != nil {
+		t.Fatalf("runTurn: %v", err)
+	}
+	if strings.Join(sink.tools, ",") != "plan_create" {
+		t.Fatalf("tool output = %v", sink.tools)
+	}
+
+	// The request after the tool call already sees the new plan
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	system := requests[1].Messages[0].Content
+	if !strings.Contains(system, "## ACTIVE PLAN\n**Ship it** (0/2 done)") || !strings.Contains(system, "2. [ ] Release") {
+		t.Errorf("plan missing from refreshed system prompt:\n%s", system)
+	}
+}
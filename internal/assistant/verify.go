@@ -0,0 +1,189 @@
+package assistant
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tara-vision/taracode/internal/context"
+	"github.com/tara-vision/taracode/internal/storage"
+	"github.com/tara-vision/taracode/internal/tools"
+)
+
+// verifyTool names verification runs in session history, recordings and
+// tool status lines. It is not a tool the model can call.
+const verifyTool = "verify"
+
+// maxVerifyRounds is how many failed checks a turn hands back to the model
+// before returning to the user with the build still broken
+const maxVerifyRounds = 3
+
+// maxDiagnostics caps the diagnostics sent back after a failed check
+const maxDiagnostics = 30
+
+// DefaultVerifyCommand returns the build check for a project: go build and
+// go vet for Go, cargo check for Rust, or make build when the Makefile has
+// a build target. It is "" when there is no obvious check.
+func DefaultVerifyCommand(workingDir string, projectCtx *context.ProjectContext) string {
+	if projectCtx == nil {
+		projectCtx = &context.ProjectContext{}
+		detectProjectType(workingDir, projectCtx)
+		extractBuildCommands(workingDir, projectCtx)
+	}
+
+	switch projectCtx.ProjectType {
+	case "Go":
+		return "go build ./... && go vet ./..."
+	case "Rust":
+		return "cargo check --quiet"
+	}
+	if slices.Contains(projectCtx.BuildCommands, "make build") {
+		return "make build"
+	}
+	if _, err := os.Stat(filepath.Join(workingDir, "tsconfig.json")); err == nil {
+		return "npx tsc --noEmit"
+	}
+	return ""
+}
+
+// Verification reports whether edits are checked at the end of a turn and
+// the command that checks them
+func (a *Assistant) Verification() (on bool, command string) {
+	if a.storage == nil {
+		return false, DefaultVerifyCommand(a.workingDir, a.projectCtx)
+	}
+	return a.verificationWith(a.storage.GetPreferences())
+}
+
+// SetVerification turns post-edit verification on or off and persists the
+// choice. An empty command keeps the current one; "default" goes back to
+// the project default.
+func (a *Assistant) SetVerification(on bool, command string) error {
+	if a.storage == nil {
+		return fmt.Errorf("storage not initialized")
+	}
+	prefs := *a.storage.GetPreferences()
+	prefs.Verify = on
+	switch command {
+	case "":
+	case "default":
+		prefs.VerifyCommand = ""
+	default:
+		prefs.VerifyCommand = command
+	}
+	if _, effective := a.verificationWith(&prefs); on && effective == "" {
+		return fmt.Errorf("no default check for this project: give one with /verify on <command>")
+	}
+	return a.storage.SavePreferences(&prefs)
+}
+
+// verificationWith resolves the check for a set of preferences
+func (a *Assistant) verificationWith(prefs *storage.Preferences) (bool, string) {
+	if prefs.VerifyCommand != "" {
+		return prefs.Verify, prefs.VerifyCommand
+	}
+	return prefs.Verify, DefaultVerifyCommand(a.workingDir, a.projectCtx)
+}
+
+// verifyEdits runs the verification command when the turn has changed
+// files since the last check. It returns the diagnostics to hand back to
+// the model, or "" when the check passed, is off or has run out of rounds.
+func (a *Assistant) verifyEdits() string {
+	if !a.turnEdited {
+		return ""
+	}
+	a.turnEdited = false
+	on, command := a.Verification()
+	if !on || command == "" {
+		return ""
+	}
+
+	call := &ToolCall{Tool: verifyTool, Params: map[string]interface{}{"command": command}}
+	stopSpinner := a.out.spin("Verifying edits...")
+	startTime := time.Now()
+	output, err := a.runCheck(call)
+	duration := time.Since(startTime).Milliseconds()
+	stopSpinner()
+
+	result := strings.TrimSpace(output)
+	if err != nil {
+		result = verifyFailure(command, output, err)
+	}
+	a.out.toolResult(call, result, err != nil)
+	a.recorder.ToolResult(call.Tool, call.Params, output, err != nil)
+
+	diagnostics := ""
+	if err != nil {
+		a.verifyRounds++
+		if a.verifyRounds > maxVerifyRounds {
+			a.out.warn(fmt.Sprintf("%s still fails after %d attempts to fix it", command, maxVerifyRounds))
+		} else {
+			diagnostics = "Tool result:\n" + result
+		}
+	}
+
+	// The record's content is what the model was sent, so a resumed
+	// session sees the same conversation
+	a.recordMessage(storage.ConversationMessage{
+		Role:      "tool",
+		Content:   diagnostics,
+		Timestamp: time.Now(),
+		ToolCall: &storage.ToolCallRecord{
+			Tool:     call.Tool,
+			Params:   call.Params,
+			Result:   result,
+			Duration: duration,
+			Success:  err == nil,
+		},
+	})
+	return diagnostics
+}
+
+// runCheck runs a verification, or serves its recorded result in a replay
+func (a *Assistant) runCheck(call *ToolCall) (string, error) {
+	if a.player != nil {
+		if result, isError, ok := a.player.ToolResult(call.Tool, call.Params); ok {
+			if isError {
+				return result, fmt.Errorf("check failed")
+			}
+			return result, nil
+		}
+		if !a.runUnrecorded {
+			return "", nil
+		}
+	}
+	command, _ := call.Params["command"].(string)
+	return tools.RunCheck(command, a.workingDir)
+}
+
+// verifyFailure describes a failed check for the model, listing the
+// diagnostics found in its output or, failing that, the output's tail
+func verifyFailure(command, output string, err error) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("verify: `%s` fails after your edits:\n", command))
+
+	diagnostics := tools.ParseDiagnostics(output)
+	for i, d := range diagnostics {
+		if i == maxDiagnostics {
+			sb.WriteString(fmt.Sprintf("... and %d more\n", len(diagnostics)-i))
+			break
+		}
+		sb.WriteString(d.String() + "\n")
+	}
+	if len(diagnostics) == 0 {
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if len(lines) > maxDiagnostics {
+			lines = lines[len(lines)-maxDiagnostics:]
+		}
+		if text := strings.Join(lines, "\n"); text != "" {
+			sb.WriteString(text + "\n")
+		} else {
+			sb.WriteString(err.Error() + "\n")
+		}
+	}
+	sb.WriteString("Fix these before you finish.")
+	return sb.String()
+}
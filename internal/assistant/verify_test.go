@@ -0,0 +1,81 @@
+package assistant
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyEdits(t *testing.T) {
+	server := scripted(t, func(n int) string {
+		switch n {
+		case 0:
+			return `{"tool": "write_file", "params": {"file_path": "main.go", "content": "package main // broken\n"}}`
+		case 2:
+			return `{"tool": "edit_file", "params": {"file_path": "main.go", "old_string": "broken", "new_string": "fixed"}}`
+		}
+		return "Done."
+	})
+	a, sink := newTestAssistant(t, server, Limits{})
+	if err := a.SetVerification(true, `grep -q fixed main.go || { echo "./main.go:1:15: still broken"; exit 1; }`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.runTurn("change main.go", completionSource{}); err != nil {
+		t.Fatalf("runTurn: %v", err)
+	}
+	if got := strings.Join(sink.tools, ","); got != "write_file,verify!,edit_file,verify" {
+		t.Errorf("tool output = %s", got)
+	}
+
+	// The failed check goes back to the model as diagnostics
+	if len(server.Requests()) != 4 {
+		t.Fatalf("got %d requests, want 4", len(server.Requests()))
+	}
+	if msg := server.LastMessage(2); !strings.Contains(msg, "fails after your edits:\nmain.go:1:15: still broken\n") {
+		t.Errorf("diagnostics message = %q", msg)
+	}
+
+	// A resumed session gets the diagnostics back, and no empty message for
+	// the check that passed
+	if err := a.LoadSession(a.GetSession().ID); err != nil {
+		t.Fatal(err)
+	}
+	diagnostics := 0
+	for _, msg := range a.conversation[1:] {
+		if msg.Content == "" {
+			t.Errorf("empty %s message restored", msg.Role)
+		}
+		if strings.Contains(msg.Content, "still broken") && msg.Role == "user" {
+			diagnostics++
+		}
+	}
+	if diagnostics != 1 {
+		t.Errorf("restored %d diagnostics messages, want 1", diagnostics)
+	}
+
+	// A turn without edits runs no check
+	sink.tools = nil
+	if err := a.runTurn("thanks", completionSource{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.tools) != 0 {
+		t.Errorf("check ran without edits: %v", sink.tools)
+	}
+}
+
+func TestDefaultVerifyCommand(t *testing.T) {
+	dir := t.TempDir()
+	if got := DefaultVerifyCommand(dir, nil); got != "" {
+		t.Errorf("empty project = %q", got)
+	}
+	os.WriteFile(filepath.Join(dir, "Makefile"), []byte("build:\n\tcc main.c\n"), 0644)
+	if got := DefaultVerifyCommand(dir, nil); got != "make build" {
+		t.Errorf("Makefile project = %q", got)
+	}
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/x\n"), 0644)
+	if got := DefaultVerifyCommand(dir, nil); got != "go build ./... && go vet ./..." {
+		t.Errorf("Go project = %q", got)
+	}
+}
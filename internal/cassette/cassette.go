@@ -0,0 +1,211 @@
+// Package cassette records the model requests, responses and tool results
+// of a session so the same user inputs can be replayed later, either from
+// the recording (deterministic) or against a live model, and compared.
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+// Version is the cassette file format version
+const Version = 1
+
+// Cassette is a recorded session
+type Cassette struct {
+	Version          int       `json:"version"`
+	RecordedAt       time.Time `json:"recorded_at"`
+	Host             string    `json:"host"`
+	Model            string    `json:"model"`
+	SystemPromptHash string    `json:"system_prompt_hash"` // Detects prompt changes between runs
+	Turns            []Turn    `json:"turns"`
+}
+
+// Turn is one user input and everything the model did in response
+type Turn struct {
+	Input        string        `json:"input"`
+	Interactions []Interaction `json:"interactions"`
+	ToolCalls    []ToolCall    `json:"tool_calls,omitempty"`
+	Answer       string        `json:"answer"`          // Final response text, empty if the turn was stopped
+	Error        string        `json:"error,omitempty"` // Why the turn failed, if it did
+}
+
+// Interaction is one request to the model and its response
+type Interaction struct {
+	Request  openai.ChatCompletionRequest `json:"request"`
+	Response string                       `json:"response,omitempty"`
+	Usage    *storage.TokenUsage          `json:"usage,omitempty"`
+	Error    string                       `json:"error,omitempty"`
+}
+
+// ToolCall is a tool the model called and the result it was given
+type ToolCall struct {
+	Tool    string                 `json:"tool"`
+	Params  map[string]interface{} `json:"params"`
+	Result  string                 `json:"result"`
+	IsError bool                   `json:"is_error,omitempty"`
+}
+
+// Key identifies the call by tool name and parameters
+func (c ToolCall) Key() string {
+	return CallKey(c.Tool, c.Params)
+}
+
+// CallKey identifies a tool call by name and parameters. JSON encoding
+// sorts map keys, so equal parameters give equal keys.
+func CallKey(tool string, params map[string]interface{}) string {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Sprintf("%s %v", tool, params)
+	}
+	return tool + " " + string(data)
+}
+
+// Load reads a cassette file
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if c.Version != Version {
+		return nil, fmt.Errorf("%s: unsupported cassette version %d", path, c.Version)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path
+func (c *Cassette) Save(path string) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Recorder builds a cassette as a session runs. It is safe to call from
+// the turn engine; a nil Recorder records nothing.
+type Recorder struct {
+	path string
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+// NewRecorder starts a recording that is saved to path after every turn.
+// path may be empty to record in memory only.
+func NewRecorder(path, host, model, systemPromptHash string) *Recorder {
+	return &Recorder{
+		path: path,
+		cassette: &Cassette{
+			Version:          Version,
+			RecordedAt:       time.Now(),
+			Host:             host,
+			Model:            model,
+			SystemPromptHash: systemPromptHash,
+		},
+	}
+}
+
+// Cassette returns the recording so far
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette
+}
+
+// BeginTurn starts recording a new user input
+func (r *Recorder) BeginTurn(input string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Turns = append(r.cassette.Turns, Turn{Input: input})
+}
+
+// current returns the turn being recorded; callers hold r.mu
+func (r *Recorder) current() *Turn {
+	if len(r.cassette.Turns) == 0 {
+		r.cassette.Turns = append(r.cassette.Turns, Turn{})
+	}
+	return &r.cassette.Turns[len(r.cassette.Turns)-1]
+}
+
+// Response records a model request and its response or error
+func (r *Recorder) Response(req openai.ChatCompletionRequest, response string, usage *storage.TokenUsage, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The request's message slice is reused by the engine; keep a copy
+	req.Messages = append([]openai.ChatCompletionMessage(nil), req.Messages...)
+	interaction := Interaction{Request: req, Response: response}
+	if usage != nil {
+		copied := *usage
+		interaction.Usage = &copied
+	}
+	if err != nil {
+		interaction.Error = err.Error()
+	}
+	turn := r.current()
+	turn.Interactions = append(turn.Interactions, interaction)
+}
+
+// ToolResult records a tool call and its result
+func (r *Recorder) ToolResult(tool string, params map[string]interface{}, result string, isError bool) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	turn := r.current()
+	turn.ToolCalls = append(turn.ToolCalls, ToolCall{Tool: tool, Params: params, Result: result, IsError: isError})
+}
+
+// Answer records the turn's final response text
+func (r *Recorder) Answer(text string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current().Answer = text
+}
+
+// Fail records that the turn ended with an error
+func (r *Recorder) Fail(err error) {
+	if r == nil || err == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current().Error = err.Error()
+}
+
+// Save writes the recording to its file, if it has one
+func (r *Recorder) Save() error {
+	if r == nil || r.path == "" {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Save(r.path)
+}
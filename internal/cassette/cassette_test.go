@@ -0,0 +1,119 @@
+package cassette
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+// record builds a two-turn cassette: a tool call then an answer, and a
+// plain answer
+func record(t *testing.T, path string) *Recorder {
+	t.Helper()
+	r := NewRecorder(path, "http://llm.test", "test-model", "hash")
+	req := openai.ChatCompletionRequest{Model: "test-model", Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "read it"}}}
+
+	r.BeginTurn("read main.go")
+	r.Response(req, `{"tool": "read_file", "params": {"file_path": "main.go"}}`, &storage.TokenUsage{TotalTokens: 50}, nil)
+	r.ToolResult("read_file", map[string]interface{}{"file_path": "main.go"}, "package main", false)
+	r.Response(req, "It is a Go program.", nil, nil)
+	r.Answer("It is a Go program.")
+
+	r.BeginTurn("thanks")
+	r.Response(req, "You're welcome.", nil, nil)
+	r.Answer("You're welcome.")
+	return r
+}
+
+func TestRecorderSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "session.json")
+	r := record(t, path)
+	if err := r.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Model != "test-model" || c.SystemPromptHash != "hash" || len(c.Turns) != 2 {
+		t.Fatalf("loaded %+v", c)
+	}
+	first := c.Turns[0]
+	if len(first.Interactions) != 2 || len(first.ToolCalls) != 1 || first.Answer != "It is a Go program." {
+		t.Errorf("first turn = %+v", first)
+	}
+	if first.Interactions[0].Usage == nil || first.Interactions[0].Usage.TotalTokens != 50 {
+		t.Errorf("usage not kept: %+v", first.Interactions[0].Usage)
+	}
+
+	// A nil recorder records nothing and never fails
+	var none *Recorder
+	none.BeginTurn("x")
+	none.Fail(errors.New("x"))
+	if err := none.Save(); err != nil {
+		t.Errorf("nil recorder Save: %v", err)
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	p := NewPlayer(record(t, "").Cassette())
+
+	p.BeginTurn()
+	if content, usage, err := p.Next(); err != nil || usage == nil || content == "" {
+		t.Fatalf("first response = %q, %v, %v", content, usage, err)
+	}
+	result, isError, ok := p.ToolResult("read_file", map[string]interface{}{"file_path": "main.go"})
+	if !ok || isError || result != "package main" {
+		t.Errorf("tool result = %q, %v, %v", result, isError, ok)
+	}
+	if _, _, ok := p.ToolResult("read_file", map[string]interface{}{"file_path": "other.go"}); ok {
+		t.Error("unrecorded call was served")
+	}
+	if content, _, _ := p.Next(); content != "It is a Go program." {
+		t.Errorf("second response = %q", content)
+	}
+	if _, _, err := p.Next(); !errors.Is(err, ErrExhausted) {
+		t.Errorf("past the end: %v", err)
+	}
+
+	// Calls made in another turn fall back to any recorded result
+	p.BeginTurn()
+	if _, _, ok := p.ToolResult("read_file", map[string]interface{}{"file_path": "main.go"}); !ok {
+		t.Error("call from an earlier turn not served")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	recorded := record(t, "").Cassette()
+
+	same := record(t, "").Cassette()
+	same.Turns[1].Answer = "You're\n  welcome."
+	if report := Compare(recorded, same); report.Differences() != 0 || report.PromptChanged {
+		t.Errorf("reflowed answer reported as different: %+v", report)
+	}
+
+	changed := record(t, "").Cassette()
+	changed.SystemPromptHash = "other"
+	changed.Turns[0].ToolCalls = nil
+	changed.Turns[1].Answer = "No problem."
+	report := Compare(recorded, changed)
+	if !report.PromptChanged || report.Differences() != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if !report.Turns[0].ToolsDiffer || report.Turns[0].AnswerDiffers {
+		t.Errorf("turn 1 = %+v", report.Turns[0])
+	}
+	if report.Turns[1].ToolsDiffer || !report.Turns[1].AnswerDiffers {
+		t.Errorf("turn 2 = %+v", report.Turns[1])
+	}
+
+	short := record(t, "").Cassette()
+	short.Turns = short.Turns[:1]
+	if report := Compare(recorded, short); report.Missing != 1 || report.Differences() != 1 {
+		t.Errorf("missing turn: %+v", report)
+	}
+}
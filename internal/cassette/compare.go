@@ -0,0 +1,87 @@
+package cassette
+
+import "strings"
+
+// TurnDiff compares one recorded turn with its replay
+type TurnDiff struct {
+	Turn           int // 1-based
+	Input          string
+	RecordedTools  []string // Call keys in order
+	ReplayedTools  []string
+	RecordedAnswer string
+	ReplayedAnswer string
+	RecordedError  string
+	ReplayedError  string
+	ToolsDiffer    bool
+	AnswerDiffers  bool
+}
+
+// Same reports whether the replay matched the recording
+func (d TurnDiff) Same() bool {
+	return !d.ToolsDiffer && !d.AnswerDiffers
+}
+
+// Report is the result of comparing a replay with its recording
+type Report struct {
+	Turns         []TurnDiff
+	PromptChanged bool // System prompt differs from the recording
+	Missing       int  // Recorded turns the replay did not reach
+}
+
+// Differences returns the number of turns that did not match
+func (r *Report) Differences() int {
+	n := r.Missing
+	for _, turn := range r.Turns {
+		if !turn.Same() {
+			n++
+		}
+	}
+	return n
+}
+
+// Compare matches each recorded turn with the replayed turn at the same
+// position, comparing the tool calls made and the final answers. A turn
+// that failed differently counts as a different answer.
+func Compare(recorded, replayed *Cassette) *Report {
+	report := &Report{
+		PromptChanged: recorded.SystemPromptHash != "" && replayed.SystemPromptHash != "" &&
+			recorded.SystemPromptHash != replayed.SystemPromptHash,
+	}
+
+	for i, rec := range recorded.Turns {
+		if i >= len(replayed.Turns) {
+			report.Missing = len(recorded.Turns) - i
+			break
+		}
+		rep := replayed.Turns[i]
+
+		diff := TurnDiff{
+			Turn:           i + 1,
+			Input:          rec.Input,
+			RecordedTools:  callKeys(rec.ToolCalls),
+			ReplayedTools:  callKeys(rep.ToolCalls),
+			RecordedAnswer: rec.Answer,
+			ReplayedAnswer: rep.Answer,
+			RecordedError:  rec.Error,
+			ReplayedError:  rep.Error,
+		}
+		diff.ToolsDiffer = strings.Join(diff.RecordedTools, "\n") != strings.Join(diff.ReplayedTools, "\n")
+		diff.AnswerDiffers = normalize(rec.Answer) != normalize(rep.Answer) || rec.Error != rep.Error
+		report.Turns = append(report.Turns, diff)
+	}
+
+	return report
+}
+
+func callKeys(calls []ToolCall) []string {
+	keys := make([]string, 0, len(calls))
+	for _, call := range calls {
+		keys = append(keys, call.Key())
+	}
+	return keys
+}
+
+// normalize collapses whitespace so reflowed answers compare equal
+func normalize(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
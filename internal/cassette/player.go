@@ -0,0 +1,81 @@
+package cassette
+
+import (
+	"errors"
+
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+// ErrExhausted is returned when a replay asks for more model responses
+// than the turn recorded
+var ErrExhausted = errors.New("cassette has no more recorded responses for this turn")
+
+// Player serves a cassette's recorded model responses and tool results
+// back to the turn engine, turn by turn
+type Player struct {
+	cassette *Cassette
+	turn     int          // Current turn index, -1 before the first
+	next     int          // Next interaction to serve in the turn
+	served   map[int]bool // Tool calls of the turn already served
+}
+
+// NewPlayer creates a player positioned before the first turn
+func NewPlayer(c *Cassette) *Player {
+	return &Player{cassette: c, turn: -1}
+}
+
+// BeginTurn advances to the next recorded turn
+func (p *Player) BeginTurn() {
+	p.turn++
+	p.next = 0
+	p.served = make(map[int]bool)
+}
+
+// Next returns the next recorded response of the current turn. A recorded
+// request failure is returned as an error with the same message.
+func (p *Player) Next() (string, *storage.TokenUsage, error) {
+	if p.turn < 0 || p.turn >= len(p.cassette.Turns) {
+		return "", nil, ErrExhausted
+	}
+	turn := p.cassette.Turns[p.turn]
+	if p.next >= len(turn.Interactions) {
+		return "", nil, ErrExhausted
+	}
+	interaction := turn.Interactions[p.next]
+	p.next++
+	if interaction.Error != "" {
+		return "", nil, errors.New(interaction.Error)
+	}
+	var usage *storage.TokenUsage
+	if interaction.Usage != nil {
+		copied := *interaction.Usage
+		usage = &copied
+	}
+	return interaction.Response, usage, nil
+}
+
+// ToolResult returns the recorded result of an identical tool call,
+// preferring unserved calls from the current turn and then any turn
+func (p *Player) ToolResult(tool string, params map[string]interface{}) (result string, isError bool, ok bool) {
+	key := CallKey(tool, params)
+
+	if p.turn >= 0 && p.turn < len(p.cassette.Turns) {
+		for i, call := range p.cassette.Turns[p.turn].ToolCalls {
+			if !p.served[i] && call.Key() == key {
+				p.served[i] = true
+				return call.Result, call.IsError, true
+			}
+		}
+	}
+
+	// A live model may make the call in a different turn or repeat it
+	for t := len(p.cassette.Turns) - 1; t >= 0; t-- {
+		calls := p.cassette.Turns[t].ToolCalls
+		for i := len(calls) - 1; i >= 0; i-- {
+			if calls[i].Key() == key {
+				return calls[i].Result, calls[i].IsError, true
+			}
+		}
+	}
+	return "", false, false
+}
@@ -4,61 +4,15 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/tara-vision/taracode/internal/ignore"
 )
 
 // DefaultExcludeDirs are directories to skip during exploration
-var DefaultExcludeDirs = map[string]bool{
-	".git":          true,
-	".taracode":     true,
-	"node_modules":  true,
-	"vendor":        true,
-	"__pycache__":   true,
-	".venv":         true,
-	"venv":          true,
-	"env":           true,
-	".idea":         true,
-	".vscode":       true,
-	"dist":          true,
-	"build":         true,
-	"target":        true,  // Rust
-	".next":         true,  // Next.js
-	"coverage":      true,
-	".cache":        true,
-	".pytest_cache": true,
-	".mypy_cache":   true,
-	".tox":          true,
-	".eggs":         true,
-	"*.egg-info":    true,
-	".bundle":       true,
-	".sass-cache":   true,
-	"bower_components": true,
-	".terraform":    true,
-	".serverless":   true,
-}
+var DefaultExcludeDirs = ignore.DefaultExcludeDirs
 
 // DefaultExcludePatterns are file patterns to skip
-var DefaultExcludePatterns = []string{
-	"*.lock",
-	"*.sum",
-	".DS_Store",
-	"*.log",
-	"*.min.js",
-	"*.min.css",
-	"*.map",
-	"*.pyc",
-	"*.pyo",
-	"*.class",
-	"*.o",
-	"*.obj",
-	"*.exe",
-	"*.dll",
-	"*.so",
-	"*.dylib",
-	"*.a",
-	"*.lib",
-	"*.bin",
-	"*.out",
-}
+var DefaultExcludePatterns = ignore.DefaultExcludePatterns
 
 // ExplorerOptions configures the directory exploration
 type ExplorerOptions struct {
@@ -78,16 +32,17 @@ func DefaultExplorerOptions() ExplorerOptions {
 	}
 }
 
-// ExploreProject builds a complete directory tree starting from rootPath
+// ExploreProject builds a complete directory tree starting from rootPath,
+// leaving out what the project's .gitignore and .taracodeignore files ignore
 func ExploreProject(rootPath string, opts ExplorerOptions) (*DirectoryTree, error) {
 	absRoot, err := filepath.Abs(rootPath)
 	if err != nil {
 		return nil, err
 	}
-	return exploreRecursive(absRoot, absRoot, 0, opts)
+	return exploreRecursive(absRoot, absRoot, 0, opts, ignore.New(absRoot))
 }
 
-func exploreRecursive(rootPath, currentPath string, depth int, opts ExplorerOptions) (*DirectoryTree, error) {
+func exploreRecursive(rootPath, currentPath string, depth int, opts ExplorerOptions, matcher *ignore.Matcher) (*DirectoryTree, error) {
 	info, err := os.Stat(currentPath)
 	if err != nil {
 		return nil, err
@@ -139,7 +94,13 @@ func exploreRecursive(rootPath, currentPath string, depth int, opts ExplorerOpti
 		}
 
 		childPath := filepath.Join(currentPath, name)
-		child, err := exploreRecursive(rootPath, childPath, depth+1, opts)
+
+		// Skip ignored and hidden paths
+		if matcher.Skip(childPath, entry.IsDir()) {
+			continue
+		}
+
+		child, err := exploreRecursive(rootPath, childPath, depth+1, opts, matcher)
 		if err == nil && child != nil {
 			node.Children = append(node.Children, child)
 		}
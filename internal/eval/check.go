@@ -0,0 +1,126 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// commandTimeout bounds setup and check commands
+const commandTimeout = 2 * time.Minute
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Check  string `json:"check"` // Human-readable description
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"` // Why it failed
+}
+
+// String describes the check
+func (c Check) String() string {
+	switch {
+	case c.FileExists != "":
+		return fmt.Sprintf("file %s exists", c.FileExists)
+	case c.FileMissing != "":
+		return fmt.Sprintf("file %s is missing", c.FileMissing)
+	case c.FileContains != nil:
+		return fmt.Sprintf("file %s contains %q", c.FileContains.Path, c.FileContains.Text)
+	case c.Command != "":
+		return fmt.Sprintf("command %q exits 0", c.Command)
+	case c.ToolCalled != "":
+		return fmt.Sprintf("tool %s called", c.ToolCalled)
+	case c.ToolNotCalled != "":
+		return fmt.Sprintf("tool %s not called", c.ToolNotCalled)
+	}
+	return "empty check"
+}
+
+// run evaluates the check against a finished case's workspace and the
+// names of the tools the agent called
+func (c Check) run(workspace string, toolsCalled []string) CheckResult {
+	result := CheckResult{Check: c.String(), Passed: true}
+	fail := func(format string, args ...interface{}) CheckResult {
+		result.Passed = false
+		result.Detail = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	switch {
+	case c.FileExists != "":
+		if _, err := os.Stat(filepath.Join(workspace, c.FileExists)); err != nil {
+			return fail("%v", err)
+		}
+	case c.FileMissing != "":
+		if _, err := os.Stat(filepath.Join(workspace, c.FileMissing)); err == nil {
+			return fail("file exists")
+		}
+	case c.FileContains != nil:
+		data, err := os.ReadFile(filepath.Join(workspace, c.FileContains.Path))
+		if err != nil {
+			return fail("%v", err)
+		}
+		if !strings.Contains(string(data), c.FileContains.Text) {
+			return fail("text not found")
+		}
+	case c.Command != "":
+		if output, err := runShell(workspace, c.Command); err != nil {
+			return fail("%v%s", err, tail(output))
+		}
+	case c.ToolCalled != "":
+		if !contains(toolsCalled, c.ToolCalled) {
+			return fail("called: %s", formatTools(toolsCalled))
+		}
+	case c.ToolNotCalled != "":
+		if contains(toolsCalled, c.ToolNotCalled) {
+			return fail("called: %s", formatTools(toolsCalled))
+		}
+	}
+	return result
+}
+
+// runShell runs a command in dir and returns its combined output
+func runShell(dir, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return string(output), fmt.Errorf("timed out after %s", commandTimeout)
+	}
+	return string(output), err
+}
+
+// tail returns the last lines of command output for a failure detail
+func tail(output string) string {
+	const maxLines = 5
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return ""
+	}
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	return "\n" + strings.Join(lines, "\n")
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func formatTools(tools []string) string {
+	if len(tools) == 0 {
+		return "no tools"
+	}
+	return strings.Join(tools, ", ")
+}
@@ -0,0 +1,139 @@
+package eval
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tara-vision/taracode/internal/assistant"
+	"github.com/tara-vision/taracode/internal/llmtest"
+	"github.com/tara-vision/taracode/internal/provider"
+)
+
+const testSuite = `
+name: notes
+timeout: 1m
+cases:
+  - name: write-note
+    fixture: fixture
+    files:
+      docs/README.md: "# Notes\n"
+    setup: ["test -f base.txt"]
+    prompt: save a note
+    checks:
+      - file_exists: docs/README.md
+      - file_contains: {path: notes.txt, text: remember}
+      - command: grep -q remember notes.txt
+      - tool_called: write_file
+      - tool_not_called: execute_command
+  - name: no-op
+    prompt: do nothing
+    checks:
+      - file_exists: notes.txt
+`
+
+// writeSuite writes the test suite and its fixture to a directory
+func writeSuite(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "fixture"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fixture", "base.txt"), []byte("base\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "suite.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	suite, err := Load(writeSuite(t, testSuite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suite.Name != "notes" || suite.Timeout != time.Minute || len(suite.Cases) != 2 || len(suite.Cases[0].Checks) != 5 {
+		t.Errorf("loaded %+v", suite)
+	}
+	if got := suite.Cases[0].Checks[1].String(); got != `file notes.txt contains "remember"` {
+		t.Errorf("check description = %q", got)
+	}
+
+	invalid := map[string]string{
+		"no cases":    "name: empty\n",
+		"no prompt":   "cases:\n  - name: a\n    checks: [{file_exists: x}]\n",
+		"two asserts": "cases:\n  - name: a\n    prompt: p\n    checks: [{file_exists: x, command: 'true'}]\n",
+		"no checks":   "cases:\n  - name: a\n    prompt: p\n",
+		"duplicate":   "cases:\n  - {name: a, prompt: p, checks: [{file_exists: x}]}\n  - {name: a, prompt: p, checks: [{file_exists: x}]}\n",
+	}
+	for name, content := range invalid {
+		if _, err := Load(writeSuite(t, content)); err == nil {
+			t.Errorf("%s: loaded without error", name)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	server := llmtest.New(t,
+		llmtest.Tool("write_file", map[string]any{"file_path": "notes.txt", "content": "remember this\n"}),
+		llmtest.Text("Wrote notes.txt."),
+		llmtest.Text("Nothing to do."),
+	)
+	t.Setenv("HOME", t.TempDir())
+	err := provider.SaveCapabilities(&provider.Capabilities{Host: server.URL, Model: llmtest.DefaultModel, ProbedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suite, err := Load(writeSuite(t, testSuite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var progress []string
+	runner := &Runner{
+		Options:  assistant.Options{Host: server.URL, Vendor: "openai"},
+		Models:   []string{llmtest.DefaultModel},
+		Progress: func(r *CaseResult) { progress = append(progress, r.Case) },
+	}
+	report := runner.Run(suite)
+
+	if len(report.Results) != 2 || strings.Join(progress, ",") != "write-note,no-op" {
+		t.Fatalf("results = %+v", report.Results)
+	}
+	pass, fail := report.Results[0], report.Results[1]
+	if !pass.Passed || pass.Error != "" {
+		t.Errorf("write-note failed: %+v", pass)
+	}
+	if pass.Iterations != 2 || strings.Join(pass.ToolCalls, ",") != "write_file" || pass.Usage.TotalTokens == 0 {
+		t.Errorf("write-note stats: %+v", pass)
+	}
+	if fail.Passed || len(fail.Checks) != 1 || fail.Checks[0].Passed {
+		t.Errorf("no-op passed: %+v", fail)
+	}
+
+	summaries := report.Summaries()
+	if len(summaries) != 1 || summaries[0].Passed != 1 || summaries[0].Total != 2 || summaries[0].PassRate() != 0.5 || !report.Failed() {
+		t.Errorf("summaries = %+v", summaries)
+	}
+}
+
+func TestRunUnavailableModel(t *testing.T) {
+	server := llmtest.New(t)
+	t.Setenv("HOME", t.TempDir())
+	provider.SaveCapabilities(&provider.Capabilities{Host: server.URL, Model: llmtest.DefaultModel, ProbedAt: time.Now()})
+
+	suite, err := Load(writeSuite(t, testSuite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite.Cases = suite.Cases[1:]
+	runner := &Runner{Options: assistant.Options{Host: server.URL, Vendor: "openai"}, Models: []string{"qwen3:30b"}}
+	report := runner.Run(suite)
+	if r := report.Results[0]; r.Passed || !strings.Contains(r.Error, "qwen3:30b not available") {
+		t.Errorf("result = %+v", r)
+	}
+}
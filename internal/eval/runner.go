@@ -0,0 +1,255 @@
+package eval
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/tara-vision/taracode/internal/assistant"
+	"github.com/tara-vision/taracode/internal/cassette"
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+// CaseResult is the outcome of one case run with one model
+type CaseResult struct {
+	Case       string             `json:"case"`
+	Model      string             `json:"model"`
+	Passed     bool               `json:"passed"`
+	Error      string             `json:"error,omitempty"` // Setup or agent failure
+	Checks     []CheckResult      `json:"checks"`
+	ToolCalls  []string           `json:"tool_calls"`
+	Iterations int                `json:"iterations"` // Model requests made
+	Usage      storage.TokenUsage `json:"usage"`
+	DurationMs int64              `json:"duration_ms"` // Agent time, excluding setup and checks
+}
+
+// Summary aggregates a model's results across the suite
+type Summary struct {
+	Model      string `json:"model"`
+	Passed     int    `json:"passed"`
+	Total      int    `json:"total"`
+	Tokens     int    `json:"tokens"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// PassRate returns the fraction of cases passed
+func (s Summary) PassRate() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Passed) / float64(s.Total)
+}
+
+// Report is the outcome of running a suite
+type Report struct {
+	Suite   string       `json:"suite"`
+	Results []CaseResult `json:"results"`
+}
+
+// Summaries returns per-model totals, in the order models were run
+func (r *Report) Summaries() []Summary {
+	var summaries []Summary
+	index := make(map[string]int)
+	for _, result := range r.Results {
+		i, ok := index[result.Model]
+		if !ok {
+			i = len(summaries)
+			index[result.Model] = i
+			summaries = append(summaries, Summary{Model: result.Model})
+		}
+		s := &summaries[i]
+		s.Total++
+		if result.Passed {
+			s.Passed++
+		}
+		s.Tokens += result.Usage.TotalTokens
+		s.DurationMs += result.DurationMs
+	}
+	return summaries
+}
+
+// Failed reports whether any case failed
+func (r *Report) Failed() bool {
+	for _, result := range r.Results {
+		if !result.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+// Runner runs suites against the LLM server configured in Options
+type Runner struct {
+	Options  assistant.Options
+	Models   []string                 // Overrides the suite's models when set
+	Progress func(result *CaseResult) // Called after each case, if set
+}
+
+// Run runs every case of the suite with every model
+func (r *Runner) Run(suite *Suite) *Report {
+	models := r.Models
+	if len(models) == 0 {
+		models = suite.Models
+	}
+	if len(models) == 0 {
+		models = []string{r.Options.Model} // Configured or auto-detected
+	}
+
+	report := &Report{Suite: suite.Name}
+	for _, model := range models {
+		for _, c := range suite.Cases {
+			result := r.runCase(suite, c, model)
+			if r.Progress != nil {
+				r.Progress(&result)
+			}
+			report.Results = append(report.Results, result)
+		}
+	}
+	return report
+}
+
+// runCase runs one case in a fresh workspace
+func (r *Runner) runCase(suite *Suite, c Case, model string) CaseResult {
+	result := CaseResult{Case: c.Name, Model: model}
+
+	workspace, err := os.MkdirTemp("", "taracode-eval-")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer os.RemoveAll(workspace)
+
+	// The recording lives outside the workspace so checks cannot see it
+	recording := workspace + ".json"
+	defer os.Remove(recording)
+
+	if err := prepare(suite, c, workspace); err != nil {
+		result.Error = fmt.Sprintf("setup: %v", err)
+		return result
+	}
+
+	opts := r.Options
+	opts.Model = model
+	opts.WorkingDir = workspace
+	opts.Quiet = true
+	opts.Silent = true
+	opts.Ephemeral = true
+	opts.Record = recording
+	if suite.Timeout > 0 {
+		opts.Limits.TurnTimeout = suite.Timeout
+	}
+
+	asst, err := assistant.New(opts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	// New falls back to another model when the requested one is missing,
+	// which would silently compare the wrong model
+	if info := asst.GetProviderInfo(); model != "" && info != nil && info.Model != model {
+		result.Error = fmt.Sprintf("model %s not available on the server", model)
+		return result
+	}
+	if model == "" {
+		result.Model = asst.GetProviderInfo().Model
+	}
+
+	start := time.Now()
+	if err := asst.ProcessMessage(c.Prompt); err != nil {
+		result.Error = err.Error()
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	if usage := asst.GetUsage(); usage != nil {
+		result.Usage = *usage
+	}
+
+	if recorded, err := cassette.Load(recording); err == nil {
+		for _, turn := range recorded.Turns {
+			result.Iterations += len(turn.Interactions)
+			for _, call := range turn.ToolCalls {
+				result.ToolCalls = append(result.ToolCalls, call.Tool)
+			}
+		}
+	}
+
+	// Checks run even after an agent error, to show how far it got
+	result.Passed = result.Error == ""
+	for _, check := range c.Checks {
+		checkResult := check.run(workspace, result.ToolCalls)
+		result.Checks = append(result.Checks, checkResult)
+		if !checkResult.Passed {
+			result.Passed = false
+		}
+	}
+	return result
+}
+
+// prepare builds a case's workspace: the fixture, inline files, then the
+// setup commands
+func prepare(suite *Suite, c Case, workspace string) error {
+	if c.Fixture != "" {
+		fixture := c.Fixture
+		if !filepath.IsAbs(fixture) {
+			fixture = filepath.Join(suite.dir, fixture)
+		}
+		if err := copyDir(fixture, workspace); err != nil {
+			return err
+		}
+	}
+
+	// Sorted so failures are reproducible
+	names := make([]string, 0, len(c.Files))
+	for name := range c.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(workspace, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(c.Files[name]), 0644); err != nil {
+			return err
+		}
+	}
+
+	for _, command := range c.Setup {
+		if output, err := runShell(workspace, command); err != nil {
+			return fmt.Errorf("%s: %v%s", command, err, tail(output))
+		}
+	}
+	return nil
+}
+
+// copyDir copies the files under src into dst, keeping permissions
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		if !info.Mode().IsRegular() {
+			return nil // Skip symlinks and special files
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
@@ -0,0 +1,120 @@
+// Package eval runs task suites against the agent and checks the results,
+// so models can be compared objectively on a team's own repositories.
+//
+// A suite is a YAML file of cases. Each case builds a workspace from a
+// fixture directory and inline files, gives the agent one prompt, and then
+// runs checks on the workspace and on the tools the agent called.
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a set of evaluation cases
+type Suite struct {
+	Name    string        `yaml:"name"`
+	Models  []string      `yaml:"models"`  // Models to compare; the configured model when empty
+	Timeout time.Duration `yaml:"timeout"` // Per case; the configured turn timeout when zero
+	Cases   []Case        `yaml:"cases"`
+
+	dir string // Directory of the suite file; fixture paths are relative to it
+}
+
+// Case is one task for the agent
+type Case struct {
+	Name    string            `yaml:"name"`
+	Fixture string            `yaml:"fixture"` // Directory copied into the workspace
+	Files   map[string]string `yaml:"files"`   // Extra files written into the workspace
+	Setup   []string          `yaml:"setup"`   // Shell commands run in the workspace before the prompt
+	Prompt  string            `yaml:"prompt"`
+	Checks  []Check           `yaml:"checks"`
+}
+
+// Check is one assertion about a case's outcome. Exactly one field is set.
+type Check struct {
+	FileExists    string    `yaml:"file_exists"`
+	FileMissing   string    `yaml:"file_missing"`
+	FileContains  *FileText `yaml:"file_contains"`
+	Command       string    `yaml:"command"` // Passes when the shell command exits 0
+	ToolCalled    string    `yaml:"tool_called"`
+	ToolNotCalled string    `yaml:"tool_not_called"`
+}
+
+// FileText names a file and text it must contain
+type FileText struct {
+	Path string `yaml:"path"`
+	Text string `yaml:"text"`
+}
+
+// Load reads and validates a suite file
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	suite.dir = filepath.Dir(path)
+	if suite.Name == "" {
+		suite.Name = filepath.Base(path)
+	}
+
+	if err := suite.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &suite, nil
+}
+
+func (s *Suite) validate() error {
+	if len(s.Cases) == 0 {
+		return fmt.Errorf("suite has no cases")
+	}
+	names := make(map[string]bool)
+	for i, c := range s.Cases {
+		if c.Name == "" {
+			return fmt.Errorf("case %d has no name", i+1)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("duplicate case name %q", c.Name)
+		}
+		names[c.Name] = true
+		if c.Prompt == "" {
+			return fmt.Errorf("case %q has no prompt", c.Name)
+		}
+		if len(c.Checks) == 0 {
+			return fmt.Errorf("case %q has no checks", c.Name)
+		}
+		for j, check := range c.Checks {
+			if n := check.assertions(); n != 1 {
+				return fmt.Errorf("case %q check %d sets %d assertions, want exactly 1", c.Name, j+1, n)
+			}
+		}
+	}
+	return nil
+}
+
+// assertions counts the fields set on the check
+func (c Check) assertions() int {
+	n := 0
+	for _, set := range []bool{
+		c.FileExists != "",
+		c.FileMissing != "",
+		c.FileContains != nil,
+		c.Command != "",
+		c.ToolCalled != "",
+		c.ToolNotCalled != "",
+	} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
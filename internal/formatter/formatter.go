@@ -0,0 +1,160 @@
+// Package formatter formats source files after they are edited: Go in
+// process with go/format, and other languages only with the external
+// formatters, reading the file on stdin, that a project configures per file
+// type in .taracode/formatters.json.
+package formatter
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tara-vision/taracode/internal/context"
+)
+
+// ConfigFile is the formatter configuration, relative to the project root
+const ConfigFile = ".taracode/formatters.json"
+
+// fileToken in a command is replaced by the path of the file being formatted
+const fileToken = "{file}"
+
+// timeout bounds a single external formatter run
+const timeout = 30 * time.Second
+
+// Config is a project's formatter configuration
+type Config struct {
+	Disabled   bool                `json:"disabled,omitempty"`   // Turns formatting after edits off
+	Formatters map[string][]string `json:"formatters,omitempty"` // Command by file type; an empty command turns the type off
+}
+
+// Formatter formats one file type
+type Formatter struct {
+	Name    string   // Shown in results, such as gofmt or black
+	Command []string // External command reading stdin and writing stdout; nil for gofmt
+}
+
+// LoadConfig reads a project's formatter configuration. A missing file
+// formats Go only.
+func LoadConfig(projectRoot string) (*Config, error) {
+	config := &Config{}
+	data, err := os.ReadFile(filepath.Join(projectRoot, ConfigFile))
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ConfigFile, err)
+	}
+	return config, nil
+}
+
+// For returns the formatter for a file, or nil when there is none. Go is
+// formatted with gofmt unless configured otherwise; other file types only
+// when the project configures a formatter for them, which is an error when
+// it is not installed.
+func (c *Config) For(path string) (*Formatter, error) {
+	if c.Disabled {
+		return nil, nil
+	}
+	fileType := context.DetectFileType(path)
+	command, configured := c.Formatters[fileType]
+	if !configured && fileType == "go" {
+		return &Formatter{Name: "gofmt"}, nil
+	}
+	if len(command) == 0 {
+		return nil, nil
+	}
+
+	if _, err := exec.LookPath(command[0]); err != nil {
+		return nil, fmt.Errorf("formatter %s for %s files is not installed (see %s)", command[0], fileType, ConfigFile)
+	}
+	return &Formatter{Name: filepath.Base(command[0]), Command: command}, nil
+}
+
+// Format returns src formatted. path names the file for formatters that
+// pick settings by location; the file itself is not read.
+func (f *Formatter) Format(path string, src []byte) ([]byte, error) {
+	if f.Command == nil {
+		return format.Source(src)
+	}
+
+	args := make([]string, len(f.Command)-1)
+	for i, arg := range f.Command[1:] {
+		args[i] = strings.ReplaceAll(arg, fileToken, path)
+	}
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, f.Command[0], args...)
+	cmd.Dir = filepath.Dir(path)
+	cmd.Stdin = bytes.NewReader(src)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("timed out after %v", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return nil, err
+	}
+	// An empty result for a non-empty file is a misbehaving formatter, not
+	// a request to empty the file
+	if stdout.Len() == 0 && len(bytes.TrimSpace(src)) > 0 {
+		return nil, fmt.Errorf("no output")
+	}
+	return stdout.Bytes(), nil
+}
+
+// File formats a file of the project in place. It returns the formatter's
+// name, or "" when the file has none, and whether the file changed.
+func File(projectRoot, path string) (name string, changed bool, err error) {
+	config, err := LoadConfig(projectRoot)
+	if err != nil {
+		return "", false, err
+	}
+	f, err := config.For(path)
+	if f == nil || err != nil {
+		return "", false, err
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return f.Name, false, err
+	}
+	formatted, err := f.Format(path, src)
+	if err != nil {
+		rel, relErr := filepath.Rel(projectRoot, path)
+		if relErr != nil || strings.HasPrefix(rel, "..") {
+			rel = path
+		}
+		if f.Command == nil {
+			// go/format reports line:column, which reads as a position
+			return f.Name, false, fmt.Errorf("%s rejected %s:%v", f.Name, rel, err)
+		}
+		return f.Name, false, fmt.Errorf("%s rejected %s: %v", f.Name, rel, err)
+	}
+	if bytes.Equal(formatted, src) {
+		return f.Name, false, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return f.Name, false, err
+	}
+	if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+		return f.Name, false, fmt.Errorf("failed to write formatted file: %w", err)
+	}
+	return f.Name, true, nil
+}
@@ -0,0 +1,80 @@
+package formatter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, dir, config string) {
+	t.Helper()
+	os.MkdirAll(filepath.Join(dir, ".taracode"), 0755)
+	if err := os.WriteFile(filepath.Join(dir, ConfigFile), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileGo(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+
+	os.WriteFile(path, []byte("package main\nfunc main(){\nprintln(1)\n}\n"), 0644)
+	name, changed, err := File(dir, path)
+	if err != nil || name != "gofmt" || !changed {
+		t.Fatalf("File = %q, %v, %v", name, changed, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "package main\n\nfunc main() {\n\tprintln(1)\n}\n" {
+		t.Errorf("formatted =\n%s", data)
+	}
+	if _, changed, _ := File(dir, path); changed {
+		t.Error("formatting a formatted file changed it")
+	}
+
+	os.WriteFile(path, []byte("package main\nfunc main() {\n"), 0644)
+	if _, _, err := File(dir, path); err == nil || !strings.Contains(err.Error(), "gofmt rejected main.go:2:15") {
+		t.Errorf("File on broken source = %v", err)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `{"formatters": {"text": ["tr", "a-z", "A-Z"], "go": [], "python": ["no-such-formatter"]}}`)
+
+	path := filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, []byte("hello\n"), 0644)
+	if name, changed, err := File(dir, path); err != nil || name != "tr" || !changed {
+		t.Fatalf("File = %q, %v, %v", name, changed, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "HELLO\n" {
+		t.Errorf("formatted = %q", data)
+	}
+
+	config, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, err := config.For("main.go"); f != nil || err != nil {
+		t.Errorf("For(main.go) with go turned off = %v, %v", f, err)
+	}
+	if _, err := config.For("app.py"); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("For(app.py) with a missing formatter = %v", err)
+	}
+	if f, err := config.For("README.md"); f != nil || err != nil {
+		t.Errorf("For(README.md) = %v, %v", f, err)
+	}
+
+	// Only Go is formatted without configuration, whatever is installed
+	config = &Config{}
+	for _, path := range []string{"app.js", "app.py", "main.rs", "style.css"} {
+		if f, err := config.For(path); f != nil || err != nil {
+			t.Errorf("For(%s) without configuration = %v, %v", path, f, err)
+		}
+	}
+
+	writeConfig(t, dir, `{"disabled": true}`)
+	config, _ = LoadConfig(dir)
+	if f, _ := config.For("main.go"); f != nil {
+		t.Error("formatter returned while disabled")
+	}
+}
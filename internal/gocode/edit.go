@@ -0,0 +1,81 @@
+package gocode
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+)
+
+// ReplaceDecl replaces the declaration d with content, the complete source
+// of the new declaration, and returns the formatted file. The content must
+// declare the same symbol. A doc comment in content replaces the old one;
+// without one the old doc comment is kept.
+func ReplaceDecl(d Decl, content string) ([]byte, error) {
+	src, err := os.ReadFile(d.File)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the new declaration on its own to check what it declares
+	const header = "package p\n\n"
+	content = strings.TrimSpace(content)
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", header+content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("new declaration does not parse: %w", err)
+	}
+	if len(file.Decls) != 1 {
+		return nil, fmt.Errorf("content must be exactly one declaration, got %d", len(file.Decls))
+	}
+	if !declares(declsOf(fset, file, []byte(header+content), ""), d) {
+		return nil, fmt.Errorf("new declaration does not declare %s %s", d.Kind, d.Name)
+	}
+
+	start := d.Start
+	if hasDoc(file.Decls[0]) {
+		start = d.DocStart
+	}
+	if d.Grouped {
+		// Inside a group the keyword is already given by the group
+		gen, ok := file.Decls[0].(*ast.GenDecl)
+		if !ok || gen.Lparen.IsValid() {
+			return nil, fmt.Errorf("%s is in a %s group: give a single %s declaration", d.Name, d.Kind, d.Kind)
+		}
+		keyword := fset.Position(gen.TokPos).Offset - len(header)
+		content = content[:keyword] + strings.TrimLeft(content[keyword+len(gen.Tok.String()):], " \t")
+	}
+
+	var updated []byte
+	updated = append(updated, src[:start]...)
+	updated = append(updated, content...)
+	updated = append(updated, src[d.End:]...)
+	formatted, err := format.Source(updated)
+	if err != nil {
+		return nil, fmt.Errorf("file does not parse after the replacement: %w", err)
+	}
+	return formatted, nil
+}
+
+// declares reports whether parsed declarations include d's symbol
+func declares(decls []Decl, d Decl) bool {
+	for _, nd := range decls {
+		if nd.Name == d.Name && nd.Kind == d.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDoc(decl ast.Decl) bool {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return decl.Doc != nil
+	case *ast.GenDecl:
+		return decl.Doc != nil
+	}
+	return false
+}
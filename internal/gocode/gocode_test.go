@@ -0,0 +1,185 @@
+package gocode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeModule creates a module in a temporary directory from a map of
+// relative paths to contents
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/shop\n\ngo 1.23\n"
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const orderSource = `package order
+
+import "fmt"
+
+// Order is a customer order
+type Order struct {
+	ID    int
+	Total int
+}
+
+const (
+	Pending = iota
+	Shipped
+)
+
+var defaultCurrency = "EUR"
+
+// Describe formats the order
+func (o *Order) Describe() string {
+	return fmt.Sprintf("#%d: %d %s", o.ID, o.Total, defaultCurrency)
+}
+
+func New(id int) *Order {
+	return &Order{ID: id}
+}
+`
+
+func TestSymbols(t *testing.T) {
+	dir := writeModule(t, map[string]string{"order/order.go": orderSource})
+
+	symbols, err := Symbols(filepath.Join(dir, "order"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range symbols {
+		got = append(got, s.Signature)
+	}
+	want := []string{
+		"type Order struct",
+		"const Pending = iota",
+		"const Shipped",
+		`var defaultCurrency = "EUR"`,
+		"func (o *Order) Describe() string",
+		"func New(id int) *Order",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("signatures =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if s := symbols[4]; s.Name != "Order.Describe" || s.Kind != "method" || s.StartLine != 19 || s.EndLine != 21 {
+		t.Errorf("method symbol = %+v", s)
+	}
+}
+
+func TestReplaceDecl(t *testing.T) {
+	dir := writeModule(t, map[string]string{"order/order.go": orderSource})
+	file := filepath.Join(dir, "order", "order.go")
+
+	// A method found by its bare name; the old doc comment is kept
+	decls, err := FindDecls(dir, "", "Describe")
+	if err != nil || len(decls) != 1 {
+		t.Fatalf("FindDecls(Describe) = %+v, %v", decls, err)
+	}
+	updated, err := ReplaceDecl(decls[0], "func (o *Order) Describe() string {\nreturn fmt.Sprint(o.ID)\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(updated), "// Describe formats the order\nfunc (o *Order) Describe() string {\n\treturn fmt.Sprint(o.ID)\n}") {
+		t.Errorf("method not replaced:\n%s", updated)
+	}
+	os.WriteFile(file, updated, 0644)
+
+	// A constant inside a group keeps the group
+	decls, _ = FindDecls(dir, file, "Shipped")
+	updated, err = ReplaceDecl(decls[0], "// Shipped orders have left the warehouse\nconst Shipped")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(updated), "\tPending = iota\n\t// Shipped orders have left the warehouse\n\tShipped\n)") {
+		t.Errorf("grouped constant not replaced:\n%s", updated)
+	}
+
+	decls, _ = FindDecls(dir, file, "New")
+	if _, err := ReplaceDecl(decls[0], "func Make(id int) *Order { return nil }"); err == nil {
+		t.Error("replacement declaring another name was accepted")
+	}
+	if _, err := ReplaceDecl(decls[0], "func New(id int) *Order {"); err == nil {
+		t.Error("replacement that does not parse was accepted")
+	}
+}
+
+func TestWorkspaceReferences(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"order/order.go": orderSource,
+		"order/order_test.go": `package order
+
+import "testing"
+
+func TestNew(t *testing.T) {
+	if New(1).Describe() == "" {
+		t.Fatal("empty")
+	}
+}
+`,
+		"main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/shop/order"
+)
+
+func main() {
+	o := order.New(7)
+	fmt.Println(o.Describe())
+}
+`,
+	})
+
+	ws, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := ws.Errors(); len(errs) > 0 {
+		t.Fatalf("type errors: %v", errs)
+	}
+
+	objs := ws.Lookup("Order.Describe")
+	if len(objs) != 1 {
+		t.Fatalf("Lookup(Order.Describe) = %v", objs)
+	}
+	if got := ws.Describe(objs[0]); got != "func (*Order).Describe() string" {
+		t.Errorf("Describe = %q", got)
+	}
+
+	// Declaration, test and main use the method
+	var refs []string
+	for _, loc := range ws.References(objs[0]) {
+		refs = append(refs, filepath.Base(loc.File)+":"+loc.Text)
+	}
+	want := "main.go:fmt.Println(o.Describe()),order.go:func (o *Order) Describe() string {,order_test.go:if New(1).Describe() == \"\" {"
+	if strings.Join(refs, ",") != want {
+		t.Errorf("references = %v", refs)
+	}
+
+	// The identifier used in main resolves to the same definition
+	obj, err := ws.ObjectAt(filepath.Join(dir, "main.go"), 10, 0, "New")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, err := ws.Definition(obj)
+	if err != nil || filepath.Base(loc.File) != "order.go" || loc.Line != 23 {
+		t.Errorf("Definition = %+v, %v", loc, err)
+	}
+	if source, ok := DeclAt(loc.File, loc.Line); !ok || !strings.HasPrefix(source, "func New(id int) *Order {") {
+		t.Errorf("DeclAt = %q", source)
+	}
+}
@@ -0,0 +1,405 @@
+package gocode
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// stdPackages maps package names to standard library import paths, from
+// go list, loaded once
+var stdPackages = sync.OnceValue(func() map[string][]string {
+	out, err := exec.Command("go", "list", "-f", "{{.Name}} {{.ImportPath}}", "std").Output()
+	if err != nil {
+		return nil
+	}
+	packages := make(map[string][]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		name, importPath, ok := strings.Cut(line, " ")
+		if !ok || strings.Contains(importPath, "internal") || strings.HasPrefix(importPath, "vendor/") {
+			continue
+		}
+		packages[name] = append(packages[name], importPath)
+	}
+	// Prefer the shortest path, e.g. math/rand over math/rand/v2
+	for _, paths := range packages {
+		sort.Slice(paths, func(i, j int) bool { return len(paths[i]) < len(paths[j]) })
+	}
+	return packages
+})
+
+// OrganizeImports removes unused imports from files of the workspace and
+// adds the ones they are missing, like goimports. Missing packages are
+// looked up in the imports of the rest of the package, the module, the
+// standard library and the module's dependencies, checking that they
+// export every name used.
+func OrganizeImports(ws *Workspace, files ...string) (*Change, error) {
+	change := newChange(ws.Root)
+	for _, name := range files {
+		pkg, file := ws.fileAST(name)
+		if file == nil {
+			return nil, fmt.Errorf("%s is not part of module %s", relativePath(ws.Root, name), ws.Module)
+		}
+		src, err := ws.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		unused := unusedImports(pkg, file)
+		var added []string
+		for _, ref := range missingPackages(pkg, file) {
+			if importPath := ws.findImport(pkg, ref.name, ref.members); importPath != "" {
+				added = append(added, importPath)
+			} else {
+				change.Notes = append(change.Notes, fmt.Sprintf("%s: no package %s exporting %s found", relativePath(ws.Root, name), ref.name, strings.Join(ref.members, ", ")))
+			}
+		}
+		if len(unused) == 0 && len(added) == 0 {
+			continue
+		}
+
+		updated, err := formatLike(src, rewriteImports(ws.Fset, file, src, unused, added))
+		if err != nil {
+			return nil, fmt.Errorf("%s does not parse after fixing imports: %w", relativePath(ws.Root, name), err)
+		}
+		change.set(ws, name, updated)
+	}
+	return change, nil
+}
+
+// fileAST returns a file's syntax tree and the type-checked package that
+// includes it
+func (w *Workspace) fileAST(name string) (*Package, *ast.File) {
+	for _, pkg := range w.Packages {
+		for _, f := range pkg.Files {
+			if pkg.Info != nil && w.Fset.Position(f.Pos()).Filename == name {
+				return pkg, f
+			}
+		}
+	}
+	return nil, nil
+}
+
+// unusedImports returns the imports of a file that nothing refers to.
+// Blank, dot and cgo imports are kept.
+func unusedImports(pkg *Package, file *ast.File) []*ast.ImportSpec {
+	used := make(map[types.Object]bool)
+	for _, obj := range pkg.Info.Uses {
+		if pn, ok := obj.(*types.PkgName); ok {
+			used[pn] = true
+		}
+	}
+
+	var unused []*ast.ImportSpec
+	for _, spec := range file.Imports {
+		if spec.Path.Value == `"C"` || (spec.Name != nil && (spec.Name.Name == "_" || spec.Name.Name == ".")) {
+			continue
+		}
+		obj := pkg.Info.Implicits[spec]
+		if spec.Name != nil {
+			obj = pkg.Info.Defs[spec.Name]
+		}
+		if obj != nil && !used[obj] {
+			unused = append(unused, spec)
+		}
+	}
+	return unused
+}
+
+// packageRef is an undefined name used as a package qualifier
+type packageRef struct {
+	name    string
+	members []string
+}
+
+// missingPackages returns the undefined names a file uses as qualifiers,
+// like fmt in fmt.Println without an import of fmt
+func missingPackages(pkg *Package, file *ast.File) []packageRef {
+	members := make(map[string]map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok || pkg.Info.Uses[ident] != nil || pkg.Info.Defs[ident] != nil {
+			return true
+		}
+		if members[ident.Name] == nil {
+			members[ident.Name] = make(map[string]bool)
+		}
+		members[ident.Name][sel.Sel.Name] = true
+		return true
+	})
+
+	var refs []packageRef
+	for name, set := range members {
+		ref := packageRef{name: name}
+		for member := range set {
+			ref.members = append(ref.members, member)
+		}
+		sort.Strings(ref.members)
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].name < refs[j].name })
+	return refs
+}
+
+// findImport returns the import path of a package called name that
+// exports every member, or "" when none does
+func (w *Workspace) findImport(pkg *Package, name string, members []string) string {
+	var candidates []string
+
+	// What the rest of the package already imports under that name
+	for _, other := range w.Packages {
+		if other.Dir != pkg.Dir {
+			continue
+		}
+		for _, f := range other.Files {
+			for _, spec := range f.Imports {
+				importPath, _ := strconv.Unquote(spec.Path.Value)
+				if (spec.Name != nil && spec.Name.Name == name) || (spec.Name == nil && guessName(importPath) == name) {
+					candidates = append(candidates, importPath)
+				}
+			}
+		}
+	}
+	for _, p := range w.Packages {
+		if p.Name == name && p.Dir != pkg.Dir && !strings.HasSuffix(p.Path, "_test") {
+			candidates = append(candidates, p.Path)
+		}
+	}
+	candidates = append(candidates, stdPackages()[name]...)
+	var deps []string
+	for importPath := range w.exports {
+		if guessName(importPath) == name && w.byPath[importPath] == nil {
+			deps = append(deps, importPath)
+		}
+	}
+	sort.Strings(deps)
+	candidates = append(candidates, deps...)
+
+	imp := workspaceImporter{w, pkg.Dir}
+	for _, importPath := range candidates {
+		if importPath == pkg.Path {
+			continue
+		}
+		imported, err := imp.Import(importPath)
+		if err != nil || imported.Name() != name {
+			continue
+		}
+		if exportsAll(imported, members) {
+			return importPath
+		}
+	}
+	return ""
+}
+
+func exportsAll(pkg *types.Package, members []string) bool {
+	for _, member := range members {
+		if obj := pkg.Scope().Lookup(member); obj == nil || !obj.Exported() {
+			return false
+		}
+	}
+	return true
+}
+
+// guessName guesses a package's name from its import path: the last
+// element, skipping a major version suffix and a go- prefix
+func guessName(importPath string) string {
+	name := path.Base(importPath)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+	name = strings.TrimPrefix(strings.TrimSuffix(name, ".go"), "go-")
+	return strings.ReplaceAll(name, "-", "")
+}
+
+// isStd reports whether an import path belongs to the standard library,
+// whose first element has no dot
+func isStd(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// rewriteImports removes and adds import specs in source, keeping the
+// layout of the imports that stay. New standard library imports join the
+// other standard library imports and the rest join the other imports,
+// gofmt sorting each block afterwards.
+func rewriteImports(fset *token.FileSet, file *ast.File, src []byte, unused []*ast.ImportSpec, added []string) []byte {
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	// lineRange extends a range to whole lines, newline included
+	lineRange := func(start, end int) (int, int) {
+		for start > 0 && src[start-1] != '\n' {
+			start--
+		}
+		for end < len(src) && src[end] != '\n' {
+			end++
+		}
+		return start, min(end+1, len(src))
+	}
+	removed := make(map[*ast.ImportSpec]bool)
+	for _, spec := range unused {
+		removed[spec] = true
+	}
+
+	// Removals, taking whole declarations when nothing in them is kept
+	var target *ast.GenDecl // Group that receives new imports
+	var single *ast.GenDecl // Ungrouped declaration that is kept
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		kept := 0
+		for _, spec := range gen.Specs {
+			if !removed[spec.(*ast.ImportSpec)] {
+				kept++
+			}
+		}
+		switch {
+		case kept == 0 && !(gen.Lparen.IsValid() && target == nil && len(added) > 0):
+			start := gen.Pos()
+			if gen.Doc != nil {
+				start = gen.Doc.Pos()
+			}
+			s, e := lineRange(offset(start), offset(gen.End()))
+			edits = append(edits, edit{s, e, ""})
+			continue
+		case gen.Lparen.IsValid() && target == nil:
+			target = gen
+		case !gen.Lparen.IsValid() && single == nil:
+			single = gen
+		}
+		for _, s := range gen.Specs {
+			spec := s.(*ast.ImportSpec)
+			if !removed[spec] {
+				continue
+			}
+			start, end := spec.Pos(), spec.End()
+			if spec.Doc != nil {
+				start = spec.Doc.Pos()
+			}
+			if spec.Comment != nil {
+				end = spec.Comment.End()
+			}
+			s, e := lineRange(offset(start), offset(end))
+			edits = append(edits, edit{s, e, ""})
+		}
+	}
+
+	if len(added) > 0 {
+		var std, other []string
+		for _, importPath := range added {
+			if isStd(importPath) {
+				std = append(std, strconv.Quote(importPath))
+			} else {
+				other = append(other, strconv.Quote(importPath))
+			}
+		}
+
+		switch {
+		case target != nil:
+			// Insert after the last kept import of the same kind, or at
+			// the start (standard library) or end (others) of the group
+			var lastStd, lastOther *ast.ImportSpec
+			for _, s := range target.Specs {
+				spec := s.(*ast.ImportSpec)
+				if removed[spec] {
+					continue
+				}
+				importPath, _ := strconv.Unquote(spec.Path.Value)
+				if isStd(importPath) {
+					lastStd = spec
+				} else {
+					lastOther = spec
+				}
+			}
+			lineEnd := func(spec *ast.ImportSpec) int {
+				end := spec.End()
+				if spec.Comment != nil {
+					end = spec.Comment.End()
+				}
+				_, e := lineRange(offset(end), offset(end))
+				return e
+			}
+			if len(std) > 0 {
+				at := offset(target.Lparen) + 1
+				text := "\n\t" + strings.Join(std, "\n\t")
+				if lastStd != nil {
+					at, text = lineEnd(lastStd), "\t"+strings.Join(std, "\n\t")+"\n"
+				} else if lastOther != nil {
+					text += "\n"
+				}
+				edits = append(edits, edit{at, at, text})
+			}
+			if len(other) > 0 {
+				at := offset(target.Rparen)
+				text := "\t" + strings.Join(other, "\n\t") + "\n"
+				if lastOther != nil {
+					at = lineEnd(lastOther)
+				} else if lastStd != nil || len(std) > 0 {
+					text = "\n" + text
+				}
+				edits = append(edits, edit{at, at, text})
+			}
+
+		default:
+			// Build a group from the kept ungrouped import, if any
+			var group []string
+			start, end := -1, -1
+			if single != nil {
+				spec := single.Specs[0].(*ast.ImportSpec)
+				start, end = offset(single.Pos()), offset(single.End())
+				text := string(src[offset(spec.Pos()):offset(spec.End())])
+				if importPath, _ := strconv.Unquote(spec.Path.Value); isStd(importPath) {
+					std = append([]string{text}, std...)
+				} else {
+					other = append([]string{text}, other...)
+				}
+			}
+			if len(std) > 0 {
+				group = append(group, "\t"+strings.Join(std, "\n\t"))
+			}
+			if len(other) > 0 {
+				group = append(group, "\t"+strings.Join(other, "\n\t"))
+			}
+			text := "import (\n" + strings.Join(group, "\n\n") + "\n)"
+			if len(std)+len(other) == 1 {
+				text = "import " + strings.TrimSpace(group[0])
+			}
+			if start < 0 {
+				// No imports left: add the group after the package clause
+				start = offset(file.Name.End())
+				end = start
+				text = "\n\n" + text
+			}
+			edits = append(edits, edit{start, end, text})
+		}
+	}
+
+	// Apply from the end; at the same offset removals go before insertions
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start > edits[j].start
+		}
+		return edits[i].end > edits[j].end
+	})
+	updated := append([]byte(nil), src...)
+	for _, e := range edits {
+		updated = append(updated[:e.start], append([]byte(e.text), updated[e.end:]...)...)
+	}
+	return updated
+}
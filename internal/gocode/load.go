@@ -0,0 +1,467 @@
+package gocode
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Workspace is a type-checked Go module
+type Workspace struct {
+	Root     string // Directory containing go.mod
+	Module   string // Module path
+	Fset     *token.FileSet
+	Packages []*Package // Every package, test variants included
+
+	deps    types.ImporterFrom  // Imports packages outside the module
+	exports map[string]string   // Export data files of dependencies by import path
+	overlay map[string][]byte   // Contents used instead of the files on disk
+	byPath  map[string]*Package // Importable packages by import path
+	lines   map[string][]string // Source lines by file, for locations
+}
+
+// Package is a type-checked package of the workspace. A directory with
+// tests yields up to three: the package itself, a test variant that adds
+// its _test.go files, and an external _test package.
+type Package struct {
+	Path   string // Import path; external test packages end in "_test"
+	Dir    string
+	Name   string
+	Files  []*ast.File
+	Types  *types.Package
+	Info   *types.Info
+	Errors []error // Type errors; checking continues past them
+	Test   bool    // Includes _test.go files
+
+	checking bool
+}
+
+// Location is a position in a source file
+type Location struct {
+	File   string
+	Line   int
+	Column int
+	Offset int    // Byte offset in the file
+	Text   string // The trimmed source line
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", l.File, l.Line, l.Column, l.Text)
+}
+
+// Load parses and type-checks every package of the module containing dir.
+// Type errors are collected per package rather than failing the load, so
+// code that does not compile can still be navigated.
+func Load(dir string) (*Workspace, error) {
+	return LoadOverlay(dir, nil)
+}
+
+// LoadOverlay loads the module containing dir with the files in overlay,
+// keyed by absolute path, in place of their contents on disk. Overlay
+// files need not exist on disk yet.
+func LoadOverlay(dir string, overlay map[string][]byte) (*Workspace, error) {
+	root, modulePath, err := FindModule(dir)
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := packageDirs(root)
+	if err != nil {
+		return nil, err
+	}
+	for name := range overlay {
+		if d := filepath.Dir(name); !slices.Contains(dirs, d) {
+			dirs = append(dirs, d)
+		}
+	}
+
+	fset := token.NewFileSet()
+	w := &Workspace{
+		Root:    root,
+		Module:  modulePath,
+		Fset:    fset,
+		exports: make(map[string]string),
+		overlay: overlay,
+		byPath:  make(map[string]*Package),
+		lines:   make(map[string][]string),
+	}
+	w.deps = dependencyImporter(fset, root, w.exports)
+	for _, d := range dirs {
+		if err := w.addDir(d); err != nil {
+			return nil, err
+		}
+	}
+
+	// Importable packages are checked on first import, the rest after
+	for _, pkg := range w.Packages {
+		if pkg.Types == nil {
+			w.check(pkg)
+		}
+	}
+	return w, nil
+}
+
+// ReadFile returns a file's contents, from the overlay when it has them
+func (w *Workspace) ReadFile(name string) ([]byte, error) {
+	if src, ok := w.overlay[name]; ok {
+		return src, nil
+	}
+	return os.ReadFile(name)
+}
+
+// addDir parses a package directory into its package, test variant and
+// external test package
+func (w *Workspace) addDir(dir string) error {
+	names, err := goFiles(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for name := range w.overlay {
+		if filepath.Dir(name) == dir && strings.HasSuffix(name, ".go") && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	path := importPath(w.Root, w.Module, dir)
+	var base, tests, xtests []*ast.File
+	for _, name := range names {
+		src, err := w.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		file, err := parser.ParseFile(w.Fset, name, src, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", relativePath(w.Root, name), err)
+		}
+		switch {
+		case !strings.HasSuffix(name, "_test.go"):
+			base = append(base, file)
+		case strings.HasSuffix(file.Name.Name, "_test"):
+			xtests = append(xtests, file)
+		default:
+			tests = append(tests, file)
+		}
+	}
+
+	add := func(path string, files []*ast.File, test bool) {
+		if len(files) > 0 {
+			w.Packages = append(w.Packages, &Package{Path: path, Dir: dir, Name: files[0].Name.Name, Files: files, Test: test})
+		}
+	}
+	add(path, base, false)
+	if len(base) > 0 {
+		w.byPath[path] = w.Packages[len(w.Packages)-1]
+	}
+	if len(tests) > 0 {
+		add(path, append(append([]*ast.File{}, base...), tests...), true)
+	}
+	add(path+"_test", xtests, true)
+	return nil
+}
+
+// check type-checks a package, importing module packages from the
+// workspace and everything else from source
+func (w *Workspace) check(pkg *Package) {
+	pkg.checking = true
+	defer func() { pkg.checking = false }()
+
+	pkg.Info = &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	conf := types.Config{
+		Importer:    workspaceImporter{w, pkg.Dir},
+		FakeImportC: true,
+		Error:       func(err error) { pkg.Errors = append(pkg.Errors, err) },
+	}
+	pkg.Types, _ = conf.Check(pkg.Path, w.Fset, pkg.Files, pkg.Info)
+}
+
+type workspaceImporter struct {
+	w   *Workspace
+	dir string
+}
+
+func (i workspaceImporter) Import(path string) (*types.Package, error) {
+	return i.ImportFrom(path, i.dir, 0)
+}
+
+func (i workspaceImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if pkg, ok := i.w.byPath[path]; ok {
+		if pkg.checking {
+			return nil, fmt.Errorf("import cycle through %s", path)
+		}
+		if pkg.Types == nil {
+			i.w.check(pkg)
+		}
+		return pkg.Types, nil
+	}
+	return i.w.deps.ImportFrom(path, dir, mode)
+}
+
+// dependencyImporter imports packages from outside the module using the
+// compiler's export data, which go list builds and caches for every
+// dependency and records in exports. Without a working go command it
+// falls back to checking dependencies from source, which is much slower.
+func dependencyImporter(fset *token.FileSet, root string, exports map[string]string) types.ImporterFrom {
+	cmd := exec.Command("go", "list", "-e", "-export", "-deps", "-test", "-f", "{{if .Export}}{{.ImportPath}}\t{{.Export}}{{end}}", "./...")
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		return importer.ForCompiler(fset, "source", nil).(types.ImporterFrom)
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		// Test variants of module packages, like "a [a.test]", are skipped
+		if path, file, ok := strings.Cut(line, "\t"); ok && !strings.Contains(path, " ") {
+			exports[path] = file
+		}
+	}
+	lookup := func(path string) (io.ReadCloser, error) {
+		file, ok := exports[path]
+		if !ok {
+			// A package the module does not import yet, e.g. one an edit adds
+			cmd := exec.Command("go", "list", "-export", "-f", "{{.Export}}", path)
+			cmd.Dir = root
+			out, err := cmd.Output()
+			if file = strings.TrimSpace(string(out)); err != nil || file == "" {
+				return nil, fmt.Errorf("no export data for %s (is it a dependency in go.mod?)", path)
+			}
+			exports[path] = file
+		}
+		return os.Open(file)
+	}
+	return importer.ForCompiler(fset, "gc", lookup).(types.ImporterFrom)
+}
+
+// Package returns the importable package with the given import path
+func (w *Workspace) Package(path string) *Package {
+	return w.byPath[path]
+}
+
+// typeError is a type error with its position relative to the module
+type typeError struct {
+	pos string
+	msg string
+}
+
+func (e typeError) String() string {
+	if e.pos == "" {
+		return e.msg
+	}
+	return e.pos + ": " + e.msg
+}
+
+// typeErrors returns the type errors of every package, without the
+// duplicates test variants report for shared files
+func (w *Workspace) typeErrors() []typeError {
+	seen := make(map[typeError]bool)
+	var errs []typeError
+	for _, pkg := range w.Packages {
+		for _, err := range pkg.Errors {
+			e := typeError{msg: err.Error()}
+			if terr, ok := err.(types.Error); ok {
+				pos := w.Fset.Position(terr.Pos)
+				e = typeError{pos: fmt.Sprintf("%s:%d:%d", relativePath(w.Root, pos.Filename), pos.Line, pos.Column), msg: terr.Msg}
+			}
+			if !seen[e] {
+				seen[e] = true
+				errs = append(errs, e)
+			}
+		}
+	}
+	return errs
+}
+
+// Errors returns the type errors of every package, sorted
+func (w *Workspace) Errors() []string {
+	var errs []string
+	for _, e := range w.typeErrors() {
+		errs = append(errs, e.String())
+	}
+	sort.Strings(errs)
+	return errs
+}
+
+// ObjectAt returns the object named by the identifier at a position. With
+// column 0 the first identifier called name on the line is used.
+func (w *Workspace) ObjectAt(file string, line, column int, name string) (types.Object, error) {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range w.Packages {
+		if pkg.Info == nil {
+			continue
+		}
+		for _, f := range pkg.Files {
+			if w.Fset.Position(f.Pos()).Filename != file {
+				continue
+			}
+			var found types.Object
+			ast.Inspect(f, func(n ast.Node) bool {
+				ident, ok := n.(*ast.Ident)
+				if !ok || found != nil {
+					return found == nil
+				}
+				pos := w.Fset.Position(ident.Pos())
+				if pos.Line != line || (column > 0 && (column < pos.Column || column >= pos.Column+len(ident.Name))) || (column == 0 && ident.Name != name) {
+					return true
+				}
+				if obj := pkg.Info.ObjectOf(ident); obj != nil {
+					found = obj
+				}
+				return true
+			})
+			if found != nil {
+				return found, nil
+			}
+			if column > 0 {
+				return nil, fmt.Errorf("no identifier at %s:%d:%d", relativePath(w.Root, file), line, column)
+			}
+			return nil, fmt.Errorf("no identifier %q on %s:%d", name, relativePath(w.Root, file), line)
+		}
+	}
+	if _, err := os.Stat(file); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s is not part of module %s", file, w.Module)
+}
+
+// Lookup finds module-level objects by name: "Name" for a package-level
+// object, "Type.Name" for a method or field. Methods named Name are used
+// when no package-level object matches.
+func (w *Workspace) Lookup(symbol string) []types.Object {
+	typeName, member, isMember := strings.Cut(symbol, ".")
+	var objs, methods []types.Object
+	seen := make(map[string]bool)
+	add := func(list *[]types.Object, obj types.Object) {
+		if key := w.objectKey(obj); !seen[key] {
+			seen[key] = true
+			*list = append(*list, obj)
+		}
+	}
+
+	for _, pkg := range w.Packages {
+		if pkg.Types == nil {
+			continue
+		}
+		scope := pkg.Types.Scope()
+		if !isMember {
+			if obj := scope.Lookup(symbol); obj != nil {
+				add(&objs, obj)
+			}
+		}
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || (isMember && name != typeName) {
+				continue
+			}
+			if !isMember {
+				member = symbol
+			}
+			obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(tn.Type()), true, pkg.Types, member)
+			if obj == nil || w.Fset.Position(obj.Pos()).Filename == "" {
+				continue
+			}
+			if isMember {
+				add(&objs, obj)
+			} else if _, isFunc := obj.(*types.Func); isFunc {
+				add(&methods, obj)
+			}
+		}
+	}
+	if len(objs) == 0 {
+		return methods
+	}
+	return objs
+}
+
+// objectKey identifies an object across package variants, which declare
+// separate objects for the same source
+func (w *Workspace) objectKey(obj types.Object) string {
+	pos := w.Fset.Position(obj.Pos())
+	if pos.Filename == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%s", pos.Filename, pos.Offset, obj.Name())
+}
+
+// Definition returns where an object is declared
+func (w *Workspace) Definition(obj types.Object) (Location, error) {
+	if !obj.Pos().IsValid() {
+		return Location{}, fmt.Errorf("%s is predeclared", obj.Name())
+	}
+	return w.location(obj.Pos()), nil
+}
+
+// References returns every use and the declaration of an object across
+// the module, test files included, sorted by file and position
+func (w *Workspace) References(obj types.Object) []Location {
+	key := w.objectKey(obj)
+	if key == "" {
+		return nil
+	}
+
+	seen := make(map[token.Pos]bool)
+	var locs []Location
+	for _, pkg := range w.Packages {
+		if pkg.Info == nil {
+			continue
+		}
+		for _, idents := range []map[*ast.Ident]types.Object{pkg.Info.Defs, pkg.Info.Uses} {
+			for ident, o := range idents {
+				if o == nil || seen[ident.Pos()] || w.objectKey(o) != key {
+					continue
+				}
+				seen[ident.Pos()] = true
+				locs = append(locs, w.location(ident.Pos()))
+			}
+		}
+	}
+	sortLocations(locs)
+	return locs
+}
+
+// location describes a position with its source line
+func (w *Workspace) location(pos token.Pos) Location {
+	p := w.Fset.Position(pos)
+	loc := Location{File: p.Filename, Line: p.Line, Column: p.Column, Offset: p.Offset}
+	lines, ok := w.lines[p.Filename]
+	if !ok {
+		if src, err := w.ReadFile(p.Filename); err == nil {
+			lines = strings.Split(string(src), "\n")
+		}
+		w.lines[p.Filename] = lines
+	}
+	if p.Line-1 < len(lines) {
+		loc.Text = strings.TrimSpace(lines[p.Line-1])
+	}
+	return loc
+}
+
+// Describe renders an object with its kind and type, e.g.
+// "func (*Server).Start(ctx context.Context) error"
+func (w *Workspace) Describe(obj types.Object) string {
+	qualifier := func(p *types.Package) string {
+		if p == obj.Pkg() {
+			return ""
+		}
+		return p.Name()
+	}
+	return types.ObjectString(obj, qualifier)
+}
@@ -0,0 +1,276 @@
+package gocode
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Change is the result of a refactoring: new contents for a set of files.
+// Nothing is written until Apply, which callers run after Verify.
+type Change struct {
+	Root   string
+	Before map[string][]byte // Original contents by absolute path; nil for new files
+	After  map[string][]byte
+	Notes  []string // Things the refactoring could not do
+}
+
+func newChange(root string) *Change {
+	return &Change{Root: root, Before: make(map[string][]byte), After: make(map[string][]byte)}
+}
+
+// set records new contents for a file, keeping its first original version
+func (c *Change) set(ws *Workspace, name string, content []byte) {
+	if _, ok := c.After[name]; !ok {
+		c.Before[name], _ = ws.ReadFile(name)
+	}
+	c.After[name] = content
+}
+
+// merge adds the edits of a change made on top of this one
+func (c *Change) merge(next *Change) {
+	for name, content := range next.After {
+		if _, ok := c.After[name]; !ok {
+			c.Before[name] = next.Before[name]
+		}
+		c.After[name] = content
+	}
+	c.Notes = append(c.Notes, next.Notes...)
+}
+
+// Files returns the changed files in order
+func (c *Change) Files() []string {
+	var files []string
+	for name, content := range c.After {
+		if !bytes.Equal(content, c.Before[name]) || c.Before[name] == nil {
+			files = append(files, name)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Diff returns a unified diff of every changed file
+func (c *Change) Diff() string {
+	var sb strings.Builder
+	for _, name := range c.Files() {
+		sb.WriteString(Diff(filepath.ToSlash(relativePath(c.Root, name)), c.Before[name], c.After[name]))
+	}
+	return sb.String()
+}
+
+// Verify type-checks the module with the change applied and fails with the
+// type errors it would introduce. Errors the code already had are ignored.
+func (c *Change) Verify(before *Workspace) error {
+	after, err := LoadOverlay(c.Root, c.After)
+	if err != nil {
+		return fmt.Errorf("change refused: %w", err)
+	}
+
+	existing := make(map[string]int)
+	for _, e := range before.typeErrors() {
+		existing[e.msg]++
+	}
+	var introduced []string
+	for _, e := range after.typeErrors() {
+		if existing[e.msg] > 0 {
+			existing[e.msg]--
+			continue
+		}
+		introduced = append(introduced, e.String())
+	}
+	if len(introduced) > 0 {
+		if len(introduced) > 10 {
+			introduced = append(introduced[:10], fmt.Sprintf("... and %d more", len(introduced)-10))
+		}
+		return fmt.Errorf("change refused, it would not type-check:\n%s", strings.Join(introduced, "\n"))
+	}
+	return nil
+}
+
+// Apply writes the changed files
+func (c *Change) Apply() error {
+	for _, name := range c.Files() {
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(name); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := os.WriteFile(name, c.After[name], mode); err != nil {
+			return fmt.Errorf("failed to write %s: %w", relativePath(c.Root, name), err)
+		}
+	}
+	return nil
+}
+
+// formatLike formats updated source when the original was gofmt-clean, so
+// edits do not reformat files that were not formatted to begin with
+func formatLike(original, updated []byte) ([]byte, error) {
+	if formatted, err := format.Source(original); err != nil || !bytes.Equal(formatted, original) {
+		return updated, nil
+	}
+	return format.Source(updated)
+}
+
+// Rename renames an object declared in the module and every reference to
+// it, test files included
+func Rename(ws *Workspace, obj types.Object, newName string) (*Change, error) {
+	oldName := obj.Name()
+	switch {
+	case !token.IsIdentifier(newName):
+		return nil, fmt.Errorf("%q is not a valid Go identifier", newName)
+	case newName == oldName:
+		return nil, fmt.Errorf("%s already has that name", oldName)
+	case obj.Pkg() == nil:
+		return nil, fmt.Errorf("%s is predeclared and cannot be renamed", oldName)
+	}
+	if _, ok := obj.(*types.PkgName); ok {
+		return nil, fmt.Errorf("%s is an import name: edit the import instead", oldName)
+	}
+	file := ws.Fset.Position(obj.Pos()).Filename
+	if !strings.HasPrefix(file, ws.Root+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s is declared outside the module and cannot be renamed", oldName)
+	}
+
+	// Catch clashes in the declaring scope or method set up front; the
+	// type check afterwards catches the rest
+	if scope := obj.Parent(); scope != nil {
+		if clash := scope.Lookup(newName); clash != nil {
+			return nil, fmt.Errorf("%s is already declared at %s", newName, ws.location(clash.Pos()))
+		}
+	}
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			if clash, _, _ := types.LookupFieldOrMethod(recv.Type(), true, obj.Pkg(), newName); clash != nil {
+				return nil, fmt.Errorf("%s already has a field or method %s", recv.Type(), newName)
+			}
+		}
+	}
+
+	byFile := make(map[string][]Location)
+	for _, ref := range ws.References(obj) {
+		byFile[ref.File] = append(byFile[ref.File], ref)
+	}
+	change := newChange(ws.Root)
+	for name, refs := range byFile {
+		src, err := ws.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		updated := append([]byte(nil), src...)
+		// Replace from the end so earlier offsets stay valid
+		for i := len(refs) - 1; i >= 0; i-- {
+			start, end := refs[i].Offset, refs[i].Offset+len(oldName)
+			if end > len(updated) || string(updated[start:end]) != oldName {
+				return nil, fmt.Errorf("unexpected source at %s", refs[i])
+			}
+			updated = append(updated[:start], append([]byte(newName), updated[end:]...)...)
+		}
+		if updated, err = formatLike(src, updated); err != nil {
+			return nil, err
+		}
+		change.set(ws, name, updated)
+	}
+	return change, nil
+}
+
+// MoveDecl moves a top-level declaration to another file of the same
+// package, creating the file when needed, and fixes the imports of both
+func MoveDecl(ws *Workspace, d Decl, dest string) (*Change, error) {
+	if filepath.Dir(dest) != filepath.Dir(d.File) {
+		return nil, fmt.Errorf("%s must be in the same package directory as %s", relativePath(ws.Root, dest), relativePath(ws.Root, d.File))
+	}
+	if dest == d.File {
+		return nil, fmt.Errorf("%s is already in %s", d.Name, relativePath(ws.Root, dest))
+	}
+	if !strings.HasSuffix(dest, ".go") {
+		return nil, fmt.Errorf("%s is not a Go file", relativePath(ws.Root, dest))
+	}
+
+	src, err := ws.ReadFile(d.File)
+	if err != nil {
+		return nil, err
+	}
+	text := string(src[d.DocStart:d.End])
+	if d.Grouped {
+		// A spec leaves its group as a declaration of its own
+		text = d.Kind + " " + text
+		if d.Kind == "const" && !hasValues(text) {
+			return nil, fmt.Errorf("%s takes its value from its const group (iota) and cannot be moved alone", d.Name)
+		}
+	}
+
+	// Cut the declaration along with the rest of its last line
+	end := d.End
+	if i := bytes.IndexByte(src[end:], '\n'); i >= 0 {
+		end += i + 1
+	}
+	start := d.DocStart
+	for start > 0 && src[start-1] != '\n' {
+		start--
+	}
+	source := append(append([]byte(nil), src[:start]...), src[end:]...)
+
+	target, err := ws.ReadFile(dest)
+	switch {
+	case err == nil:
+		target = append(bytes.TrimRight(target, "\n"), []byte("\n\n"+text+"\n")...)
+	case os.IsNotExist(err):
+		packageName, _ := packageClause(src)
+		target = []byte(fmt.Sprintf("package %s\n\n%s\n", packageName, text))
+	default:
+		return nil, err
+	}
+
+	change := newChange(ws.Root)
+	for name, content := range map[string][]byte{d.File: source, dest: target} {
+		formatted, err := format.Source(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s does not parse after the move: %w", relativePath(ws.Root, name), err)
+		}
+		change.set(ws, name, formatted)
+	}
+
+	// Imports follow the code: drop what the source no longer uses and
+	// add what the destination now needs
+	moved, err := LoadOverlay(ws.Root, change.After)
+	if err != nil {
+		return nil, err
+	}
+	imports, err := OrganizeImports(moved, d.File, dest)
+	if err != nil {
+		return nil, err
+	}
+	change.merge(imports)
+	return change, nil
+}
+
+// hasValues reports whether a standalone const declaration gives values
+func hasValues(text string) bool {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+text, 0)
+	if err != nil || len(file.Decls) != 1 {
+		return false
+	}
+	for _, spec := range file.Decls[0].(*ast.GenDecl).Specs {
+		if len(spec.(*ast.ValueSpec).Values) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// packageClause returns the package name declared by source
+func packageClause(src []byte) (string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly)
+	if err != nil {
+		return "", err
+	}
+	return file.Name.Name, nil
+}
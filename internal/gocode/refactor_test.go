@@ -0,0 +1,160 @@
+package gocode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const mainSource = `package main
+
+import (
+	"fmt"
+
+	"example.com/shop/order"
+)
+
+func main() {
+	o := order.New(7)
+	fmt.Println(o.Describe())
+}
+`
+
+func TestDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	after := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	want := `--- a/x.txt
++++ b/x.txt
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got := Diff("x.txt", []byte(before), []byte(after)); got != want {
+		t.Errorf("Diff =\n%s\nwant\n%s", got, want)
+	}
+	if got := Diff("x.txt", []byte(before), []byte(before)); got != "" {
+		t.Errorf("Diff of equal files = %q", got)
+	}
+}
+
+func TestRename(t *testing.T) {
+	dir := writeModule(t, map[string]string{"order/order.go": orderSource, "main.go": mainSource})
+	ws, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	change, err := Rename(ws, ws.Lookup("Order.Describe")[0], "Summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := change.Verify(ws); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	diff := change.Diff()
+	for _, want := range []string{"+\tfmt.Println(o.Summary())", "+func (o *Order) Summary() string {", "--- a/order/order.go"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff missing %q:\n%s", want, diff)
+		}
+	}
+	if err := change.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "main.go")); !strings.Contains(string(data), "o.Summary()") {
+		t.Errorf("main.go not updated:\n%s", data)
+	}
+
+	// Unexporting a name used by another package does not type-check
+	ws, _ = Load(dir)
+	change, err = Rename(ws, ws.Lookup("New")[0], "newOrder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := change.Verify(ws); err == nil || !strings.Contains(err.Error(), "main.go:10") {
+		t.Errorf("Verify = %v, want a type error in main.go", err)
+	}
+
+	if _, err := Rename(ws, ws.Lookup("New")[0], "Order"); err == nil || !strings.Contains(err.Error(), "already declared") {
+		t.Errorf("Rename to an existing name = %v", err)
+	}
+}
+
+func TestOrganizeImports(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"order/order.go": orderSource,
+		"main.go": `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	o := order.New(1)
+	fmt.Println(strings.ToUpper(o.Describe()))
+}
+`,
+	})
+	ws, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	change, err := OrganizeImports(ws, filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := change.Verify(ws); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	want := "import (\n\t\"fmt\"\n\t\"strings\"\n\n\t\"example.com/shop/order\"\n)\n"
+	if got := string(change.After[filepath.Join(dir, "main.go")]); !strings.Contains(got, want) {
+		t.Errorf("imports not organized:\n%s", got)
+	}
+}
+
+func TestMoveDecl(t *testing.T) {
+	dir := writeModule(t, map[string]string{"order/order.go": orderSource})
+	ws, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decls, _ := FindDecls(dir, "", "Order.Describe")
+
+	dest := filepath.Join(dir, "order", "format.go")
+	change, err := MoveDecl(ws, decls[0], dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := change.Verify(ws); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// The method and its fmt import move; the source keeps neither
+	moved := string(change.After[dest])
+	if !strings.HasPrefix(moved, "package order\n\nimport \"fmt\"\n\n// Describe formats the order\nfunc (o *Order) Describe() string {") {
+		t.Errorf("destination =\n%s", moved)
+	}
+	source := string(change.After[filepath.Join(dir, "order", "order.go")])
+	if strings.Contains(source, "Describe") || strings.Contains(source, `"fmt"`) {
+		t.Errorf("source still has the method or its import:\n%s", source)
+	}
+
+	decls, _ = FindDecls(dir, "", "Shipped")
+	if _, err := MoveDecl(ws, decls[0], dest); err == nil || !strings.Contains(err.Error(), "iota") {
+		t.Errorf("moving an iota constant = %v", err)
+	}
+	if _, err := MoveDecl(ws, decls[0], filepath.Join(dir, "other.go")); err == nil {
+		t.Error("move to another package was accepted")
+	}
+}
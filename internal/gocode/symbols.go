@@ -0,0 +1,316 @@
+package gocode
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Symbol is a top-level declaration: a function, method, type, variable or
+// constant
+type Symbol struct {
+	Name      string // "Name", or "Recv.Name" for methods
+	Kind      string // func, method, type, var or const
+	Signature string // Declaration header without body or doc comment
+	File      string
+	StartLine int // First line of the declaration, doc comment excluded
+	EndLine   int
+}
+
+// String renders the symbol as "signature  file:start-end"
+func (s Symbol) String() string {
+	return fmt.Sprintf("%s  %s:%d-%d", s.Signature, s.File, s.StartLine, s.EndLine)
+}
+
+// Decl is a declaration located in its file's source, for edits
+type Decl struct {
+	Symbol
+	Start    int  // Byte offset of the declaration
+	End      int  // Byte offset just past it
+	DocStart int  // Byte offset of its doc comment, Start when it has none
+	Grouped  bool // A spec inside a parenthesized type, var or const group
+}
+
+// parseFile parses a Go file with comments
+func parseFile(fset *token.FileSet, path string) (*ast.File, []byte, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, src, nil
+}
+
+// Symbols lists the top-level declarations of a Go file, or of every Go
+// file in a package directory, in source order
+func Symbols(path string) ([]Symbol, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = goFiles(path); err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no Go files in %s", path)
+		}
+	}
+
+	var symbols []Symbol
+	for _, name := range files {
+		decls, err := fileDecls(token.NewFileSet(), name)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range decls {
+			symbols = append(symbols, d.Symbol)
+		}
+	}
+	return symbols, nil
+}
+
+// FindDecls returns the declarations named symbol in a file, or in every
+// Go file of the module containing dir when file is empty. A plain name
+// matches functions, types, variables and constants; "Recv.Name" matches
+// a method. A plain name with no other match falls back to methods of
+// that name.
+func FindDecls(dir, file, symbol string) ([]Decl, error) {
+	var files []string
+	if file != "" {
+		files = []string{file}
+	} else {
+		root, _, err := FindModule(dir)
+		if err != nil {
+			return nil, err
+		}
+		dirs, err := packageDirs(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range dirs {
+			names, _ := goFiles(d)
+			files = append(files, names...)
+		}
+	}
+
+	var exact, methods []Decl
+	for _, name := range files {
+		decls, err := fileDecls(token.NewFileSet(), name)
+		if err != nil {
+			if file != "" {
+				return nil, err
+			}
+			continue // Unparsable files elsewhere in the module are skipped
+		}
+		for _, d := range decls {
+			switch {
+			case d.Name == symbol:
+				exact = append(exact, d)
+			case d.Kind == "method" && d.Name[strings.Index(d.Name, ".")+1:] == symbol:
+				methods = append(methods, d)
+			}
+		}
+	}
+	if len(exact) == 0 && !strings.Contains(symbol, ".") {
+		return methods, nil
+	}
+	return exact, nil
+}
+
+// DeclAt returns the source of the top-level declaration that starts on a
+// line of a file, doc comment included
+func DeclAt(file string, line int) (string, bool) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return "", false
+	}
+	decls, err := fileDecls(token.NewFileSet(), file)
+	if err != nil {
+		return "", false
+	}
+	for _, d := range decls {
+		if d.StartLine <= line && line <= d.EndLine && (d.StartLine == line || d.Kind == "type") {
+			return string(src[d.DocStart:d.End]), true
+		}
+	}
+	return "", false
+}
+
+// fileDecls lists the top-level declarations of a file
+func fileDecls(fset *token.FileSet, path string) ([]Decl, error) {
+	file, src, err := parseFile(fset, path)
+	if err != nil {
+		return nil, err
+	}
+	return declsOf(fset, file, src, path), nil
+}
+
+// declsOf lists the top-level declarations of a parsed file
+func declsOf(fset *token.FileSet, file *ast.File, src []byte, path string) []Decl {
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	line := func(pos token.Pos) int { return fset.Position(pos).Line }
+	newDecl := func(name, kind, signature string, node ast.Node, doc *ast.CommentGroup, grouped bool) Decl {
+		d := Decl{
+			Symbol: Symbol{
+				Name:      name,
+				Kind:      kind,
+				Signature: signature,
+				File:      path,
+				StartLine: line(node.Pos()),
+				EndLine:   line(node.End()),
+			},
+			Start:   offset(node.Pos()),
+			End:     offset(node.End()),
+			Grouped: grouped,
+		}
+		d.DocStart = d.Start
+		if doc != nil {
+			d.DocStart = offset(doc.Pos())
+		}
+		return d
+	}
+
+	var decls []Decl
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			name, kind := decl.Name.Name, "func"
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				name, kind = ReceiverName(decl.Recv.List[0].Type)+"."+name, "method"
+			}
+			decls = append(decls, newDecl(name, kind, funcSignature(fset, decl), decl, decl.Doc, false))
+
+		case *ast.GenDecl:
+			grouped := decl.Lparen.IsValid()
+			for _, spec := range decl.Specs {
+				// A lone spec is replaced together with its keyword
+				var node ast.Node = decl
+				doc := decl.Doc
+				if grouped {
+					node = spec
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if grouped {
+						doc = spec.Doc
+					}
+					decls = append(decls, newDecl(spec.Name.Name, "type", typeSignature(src, fset, spec), node, doc, grouped))
+				case *ast.ValueSpec:
+					if grouped {
+						doc = spec.Doc
+					}
+					kind := decl.Tok.String()
+					for _, ident := range spec.Names {
+						if ident.Name == "_" {
+							continue
+						}
+						decls = append(decls, newDecl(ident.Name, kind, valueSignature(src, fset, kind, ident, spec), node, doc, grouped))
+					}
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// ReceiverName returns the base type name of a method receiver, without
+// pointer or type parameters
+func ReceiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return "?"
+		}
+	}
+}
+
+// funcSignature prints a function declaration without its body or doc
+func funcSignature(fset *token.FileSet, decl *ast.FuncDecl) string {
+	header := *decl
+	header.Doc, header.Body = nil, nil
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, &header)
+	return buf.String()
+}
+
+// typeSignature renders "type Name struct", "type Name interface" or the
+// first line of any other type declaration
+func typeSignature(src []byte, fset *token.FileSet, spec *ast.TypeSpec) string {
+	header := strings.TrimSpace(sourceText(src, fset, spec.Name.Pos(), spec.Type.Pos()))
+	switch spec.Type.(type) {
+	case *ast.StructType:
+		return "type " + header + " struct"
+	case *ast.InterfaceType:
+		return "type " + header + " interface"
+	}
+	return "type " + header + " " + firstLine(sourceText(src, fset, spec.Type.Pos(), spec.Type.End()))
+}
+
+// valueSignature renders "var Name Type" or "const Name = value"
+func valueSignature(src []byte, fset *token.FileSet, kind string, ident *ast.Ident, spec *ast.ValueSpec) string {
+	signature := kind + " " + ident.Name
+	if spec.Type != nil {
+		signature += " " + sourceText(src, fset, spec.Type.Pos(), spec.Type.End())
+	}
+	for i, name := range spec.Names {
+		if name == ident && i < len(spec.Values) {
+			signature += " = " + firstLine(sourceText(src, fset, spec.Values[i].Pos(), spec.Values[i].End()))
+		}
+	}
+	return signature
+}
+
+func sourceText(src []byte, fset *token.FileSet, start, end token.Pos) string {
+	return string(src[fset.Position(start).Offset:fset.Position(end).Offset])
+}
+
+// firstLine shortens multi-line source to its first line
+func firstLine(s string) string {
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return strings.TrimSpace(s[:i]) + " ..."
+	}
+	return s
+}
+
+// sortLocations orders locations by file and position
+func sortLocations(locs []Location) {
+	sort.Slice(locs, func(i, j int) bool {
+		if locs[i].File != locs[j].File {
+			return locs[i].File < locs[j].File
+		}
+		if locs[i].Line != locs[j].Line {
+			return locs[i].Line < locs[j].Line
+		}
+		return locs[i].Column < locs[j].Column
+	})
+}
+
+// relativePath shortens path relative to dir when it lies inside it
+func relativePath(dir, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,289 @@
+// Package ignore decides which files of a project are left out of
+// listings, searches and indexes: dependency and build directories,
+// whatever .gitignore files ignore, and whatever the project's
+// .taracodeignore hides from the assistant's tools.
+package ignore
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileName is the project file, in gitignore syntax, listing paths hidden
+// from the assistant: they are never listed, searched or indexed, file
+// tools refuse them and git_status and git_diff leave them out. Output of
+// execute_command is not filtered.
+const FileName = ".taracodeignore"
+
+// preferencesFile holds the project's exclude_dirs preference (see
+// storage.Preferences)
+const preferencesFile = ".taracode/state/preferences.json"
+
+// DefaultExcludeDirs are directories skipped everywhere: version control,
+// dependencies, virtual environments, caches and build output
+var DefaultExcludeDirs = map[string]bool{
+	".git":             true,
+	".taracode":        true,
+	"node_modules":     true,
+	"vendor":           true,
+	"__pycache__":      true,
+	".venv":            true,
+	"venv":             true,
+	"env":              true,
+	".idea":            true,
+	".vscode":          true,
+	"dist":             true,
+	"build":            true,
+	"target":           true, // Rust
+	".next":            true, // Next.js
+	"coverage":         true,
+	".cache":           true,
+	".pytest_cache":    true,
+	".mypy_cache":      true,
+	".tox":             true,
+	".eggs":            true,
+	"*.egg-info":       true,
+	".bundle":          true,
+	".sass-cache":      true,
+	"bower_components": true,
+	".terraform":       true,
+	".serverless":      true,
+}
+
+// DefaultExcludePatterns are names of generated, compiled and lock files,
+// skipped by searches and the project explorer
+var DefaultExcludePatterns = []string{
+	"*.lock",
+	"*.sum",
+	".DS_Store",
+	"*.log",
+	"*.min.js",
+	"*.min.css",
+	"*.map",
+	"*.pyc",
+	"*.pyo",
+	"*.class",
+	"*.o",
+	"*.obj",
+	"*.exe",
+	"*.dll",
+	"*.so",
+	"*.dylib",
+	"*.a",
+	"*.lib",
+	"*.bin",
+	"*.out",
+}
+
+// Matcher applies a project's ignore rules. Paths are absolute or relative
+// to the project root; paths outside it are never ignored. A Matcher is
+// safe for concurrent use.
+type Matcher struct {
+	root    string // Project root
+	gitRoot string // Repository root .gitignore paths are relative to; root outside a repository
+	prefix  string // Project root relative to gitRoot, "" when they are the same
+	hidden  []rule // .taracodeignore, relative to root
+	dirs    []rule // exclude_dirs preference, relative to root
+	exclude []rule // .git/info/exclude, relative to gitRoot
+
+	mu         sync.Mutex
+	gitignores map[string][]rule // Rules of each directory's .gitignore, by path relative to gitRoot
+}
+
+// New returns the matcher of the project at root, reading its
+// .taracodeignore, the exclude_dirs preference and, inside a git
+// repository, .git/info/exclude. .gitignore files are read as directories
+// are matched.
+func New(root string) *Matcher {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	m := &Matcher{root: root, gitRoot: root, gitignores: make(map[string][]rule)}
+	m.hidden = readRules(filepath.Join(root, FileName), "")
+	for _, dir := range preferredExcludeDirs(root) {
+		if r, ok := parseRule(strings.TrimSuffix(dir, "/")+"/", ""); ok {
+			m.dirs = append(m.dirs, r)
+		}
+	}
+
+	for dir := root; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			m.gitRoot = dir
+			if rel, err := filepath.Rel(dir, root); err == nil && rel != "." {
+				m.prefix = filepath.ToSlash(rel)
+			}
+			m.exclude = readRules(filepath.Join(dir, ".git", "info", "exclude"), "")
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	return m
+}
+
+// preferredExcludeDirs reads exclude_dirs from the project's preferences
+func preferredExcludeDirs(root string) []string {
+	data, err := os.ReadFile(filepath.Join(root, preferencesFile))
+	if err != nil {
+		return nil
+	}
+	var prefs struct {
+		ExcludeDirs []string `json:"exclude_dirs"`
+	}
+	json.Unmarshal(data, &prefs)
+	return prefs.ExcludeDirs
+}
+
+// Root returns the project root
+func (m *Matcher) Root() string {
+	return m.root
+}
+
+// rel returns path relative to the project root, slash-separated, and
+// whether it is inside the project
+func (m *Matcher) rel(path string) (string, bool) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.root, path)
+	}
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// Hidden reports whether .taracodeignore hides path, or a directory
+// containing it, from the assistant
+func (m *Matcher) Hidden(path string) bool {
+	rel, ok := m.rel(path)
+	if !ok || len(m.hidden) == 0 {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		isDir := i < len(parts)-1
+		if !isDir {
+			if info, err := os.Stat(filepath.Join(m.root, filepath.FromSlash(rel))); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		if ignored, _ := match(m.hidden, strings.Join(parts[:i+1], "/"), isDir); ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// Ignored reports whether path, or a directory containing it, is
+// ignored
+func (m *Matcher) Ignored(path string, isDir bool) bool {
+	rel, ok := m.rel(path)
+	if !ok {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		if m.skip(strings.Join(parts[:i+1], "/"), isDir || i < len(parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// Skip reports whether a walk leaves out path, assuming it did not leave
+// out the directory containing it. Walks that never descend into skipped
+// directories use it instead of Ignored.
+func (m *Matcher) Skip(path string, isDir bool) bool {
+	rel, ok := m.rel(path)
+	return ok && m.skip(rel, isDir)
+}
+
+// skip decides for a path relative to the project root: default and
+// preferred excluded directories, hidden paths, then .gitignore rules from
+// the repository root down to the path's directory, where deeper files take
+// precedence
+func (m *Matcher) skip(rel string, isDir bool) bool {
+	name := rel[strings.LastIndex(rel, "/")+1:]
+	if isDir && excludedDir(name) {
+		return true
+	}
+	if ignored, _ := match(m.dirs, rel, isDir); ignored {
+		return true
+	}
+	if ignored, _ := match(m.hidden, rel, isDir); ignored {
+		return true
+	}
+
+	gitRel := rel
+	if m.prefix != "" {
+		gitRel = m.prefix + "/" + rel
+	}
+	ignored, _ := match(m.exclude, gitRel, isDir)
+	dirs := []string{""}
+	for i := range gitRel {
+		if gitRel[i] == '/' {
+			dirs = append(dirs, gitRel[:i])
+		}
+	}
+	for _, dir := range dirs {
+		if ig, matched := match(m.gitignore(dir), gitRel, isDir); matched {
+			ignored = ig
+		}
+	}
+	return ignored
+}
+
+// gitignore returns the rules of a directory's .gitignore, reading it the
+// first time
+func (m *Matcher) gitignore(dir string) []rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rules, ok := m.gitignores[dir]
+	if !ok {
+		rules = readRules(filepath.Join(m.gitRoot, filepath.FromSlash(dir), ".gitignore"), dir)
+		m.gitignores[dir] = rules
+	}
+	return rules
+}
+
+// excludedDir reports whether a directory name is excluded by default
+func excludedDir(name string) bool {
+	if DefaultExcludeDirs[name] {
+		return true
+	}
+	for pattern := range DefaultExcludeDirs {
+		if strings.ContainsAny(pattern, "*?[") {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Walk walks the tree at dir like filepath.WalkDir, leaving out ignored
+// files and directories. dir itself is walked even if it is ignored, so
+// an explicitly named directory can be listed. Errors reading a directory
+// skip it.
+func (m *Matcher) Walk(dir string, fn fs.WalkDirFunc) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if path != dir && m.Skip(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return fn(path, d, nil)
+	})
+}
@@ -0,0 +1,114 @@
+package ignore
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		pattern, base, path string
+		isDir               bool
+		want                bool
+	}{
+		{"*.log", "", "debug.log", false, true},
+		{"*.log", "", "a/b/debug.log", false, true},
+		{"/build", "", "build", true, true},
+		{"/build", "", "src/build", true, false},
+		{"build/", "", "src/build", true, true},
+		{"build/", "", "build", false, false},
+		{"docs/*.md", "", "docs/a.md", false, true},
+		{"docs/*.md", "", "docs/x/a.md", false, false},
+		{"**/fixtures", "", "a/b/fixtures", true, true},
+		{"logs/**", "", "logs/a/b.txt", false, true},
+		{"logs/**", "", "logs", true, false},
+		{"a/**/z", "", "a/z", false, true},
+		{"a/**/z", "", "a/b/c/z", false, true},
+		{"file[0-9].txt", "", "file7.txt", false, true},
+		{"file[!0-9].txt", "", "file7.txt", false, false},
+		{"*.tmp", "sub", "sub/x.tmp", false, true},
+		{"*.tmp", "sub", "other/x.tmp", false, false},
+		{"/only", "sub", "sub/only", false, true},
+		{"#comment", "", "#comment", false, false},
+		{`\#hash`, "", "#hash", false, true},
+		{`trailing\ `, "", "trailing ", false, true},
+	}
+	for _, tt := range tests {
+		var rules []rule
+		if r, ok := parseRule(tt.pattern, tt.base); ok {
+			rules = append(rules, r)
+		}
+		if got, _ := match(rules, tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q in %q: match(%q) = %v, want %v", tt.pattern, tt.base, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	repo := t.TempDir()
+	os.Mkdir(filepath.Join(repo, ".git"), 0755)
+	writeFiles(t, repo, map[string]string{
+		".gitignore":                           "*.log\n/app/tmp/\n",
+		".git/info/exclude":                    "notes.txt\n",
+		"app/.gitignore":                       "generated/*\n!generated/keep.go\n",
+		"app/.taracodeignore":                  "config/secrets.yaml\n",
+		"app/main.go":                          "",
+		"app/debug.log":                        "",
+		"app/notes.txt":                        "",
+		"app/tmp/cache":                        "",
+		"app/generated/a.go":                   "",
+		"app/generated/keep.go":                "",
+		"app/config/secrets.yaml":              "",
+		"app/config/app.yaml":                  "",
+		"app/node_modules/x.js":                "",
+		"app/lib/mod.egg-info/x":               "",
+		"app/skip/me.go":                       "",
+		"app/.taracode/state/preferences.json": `{"exclude_dirs": ["skip"]}`,
+	})
+
+	// The project is a subdirectory of the repository: the root .gitignore
+	// and info/exclude apply with paths from the repository root
+	m := New(filepath.Join(repo, "app"))
+	var walked []string
+	err := m.Walk(m.Root(), func(path string, d fs.DirEntry, err error) error {
+		if !d.IsDir() {
+			rel, _ := filepath.Rel(m.Root(), path)
+			walked = append(walked, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ".gitignore .taracodeignore config/app.yaml generated/keep.go main.go"
+	if got := strings.Join(walked, " "); got != want {
+		t.Errorf("walked %s, want %s", got, want)
+	}
+
+	if !m.Hidden("config/secrets.yaml") || !m.Hidden(filepath.Join(repo, "app", "config", "secrets.yaml")) || m.Hidden("config/app.yaml") {
+		t.Error("Hidden does not follow .taracodeignore")
+	}
+	if m.Hidden("skip/me.go") || !m.Ignored("skip/me.go", false) {
+		t.Error("exclude_dirs should skip without hiding")
+	}
+	if !m.Ignored("tmp/cache", false) || !m.Ignored("node_modules/x.js", false) || m.Ignored("generated/keep.go", false) {
+		t.Error("Ignored does not check parent directories")
+	}
+	if m.Ignored(filepath.Join(repo, "elsewhere.log"), false) {
+		t.Error("a path outside the project is ignored")
+	}
+}
@@ -0,0 +1,411 @@
+// Package llmtest provides an in-process OpenAI-compatible chat server that
+// replays scripted replies, for testing taracode end to end without a GPU.
+//
+// A Server answers /v1/models, /v1/chat/completions (streamed or not) and
+// /v1/embeddings and, when created with NewOllama, Ollama's /api/tags. Each
+// chat request consumes the next scripted Reply; every request is kept for
+// assertions. Embeddings are deterministic bags of words, so texts sharing
+// words are close.
+package llmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultModel is the model a Server lists unless SetModels is called
+const DefaultModel = "test-model"
+
+// Reply scripts one chat completion response
+type Reply struct {
+	Content   string        // Assistant message content
+	Reasoning string        // Sent separately as reasoning_content, vLLM style
+	Chunks    []string      // Stream these chunks instead of splitting Content
+	Usage     *openai.Usage // Reported usage; estimated from the text when nil
+	OmitUsage bool          // Report no usage, like servers that ignore stream_options
+	Status    int           // Fail with this HTTP status and an OpenAI error body
+	Raw       string        // Send this body verbatim, e.g. malformed JSON
+	Delay     time.Duration // Wait before responding
+}
+
+// Text returns a plain text reply
+func Text(content string) Reply {
+	return Reply{Content: content}
+}
+
+// Think returns a reply whose answer is preceded by an inline <think> block
+func Think(reasoning, answer string) Reply {
+	return Reply{Content: "<think>\n" + reasoning + "\n</think>\n\n" + answer}
+}
+
+// Tool returns a reply calling one tool in taracode's JSON tool format,
+// with "tool" first as models write it
+func Tool(name string, params map[string]any) Reply {
+	data, err := json.Marshal(params)
+	if err != nil {
+		panic(err)
+	}
+	return Reply{Content: fmt.Sprintf(`{"tool": %q, "params": %s}`, name, data)}
+}
+
+// Error returns a reply that fails with the given HTTP status
+func Error(status int, message string) Reply {
+	return Reply{Status: status, Content: message}
+}
+
+// Server is a scripted OpenAI-compatible chat server
+type Server struct {
+	*httptest.Server
+
+	t testing.TB
+
+	mu        sync.Mutex
+	models    []string
+	ownedBy   string
+	script    []Reply
+	requests  []openai.ChatCompletionRequest
+	embedded  []string // Inputs of embedding requests
+	responder func(n int, req openai.ChatCompletionRequest) Reply
+}
+
+// New starts an OpenAI-compatible server that answers chat requests with
+// replies in order. It is closed when the test finishes.
+func New(t testing.TB, replies ...Reply) *Server {
+	return start(t, false, replies)
+}
+
+// NewOllama starts a server that also serves Ollama's /api/tags, so
+// provider auto-detection identifies it as Ollama
+func NewOllama(t testing.TB, replies ...Reply) *Server {
+	return start(t, true, replies)
+}
+
+func start(t testing.TB, ollama bool, replies []Reply) *Server {
+	s := &Server{
+		t:       t,
+		models:  []string{DefaultModel},
+		ownedBy: "llmtest",
+		script:  replies,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", s.handleModels)
+	mux.HandleFunc("/v1/chat/completions", s.handleChat)
+	mux.HandleFunc("/v1/embeddings", s.handleEmbeddings)
+	if ollama {
+		mux.HandleFunc("/api/tags", s.handleTags)
+	}
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Enqueue appends replies to the script
+func (s *Server) Enqueue(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.script = append(s.script, replies...)
+}
+
+// SetModels changes the models the server lists
+func (s *Server) SetModels(models ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models = models
+}
+
+// SetOwnedBy changes the owned_by field of /v1/models, which provider
+// detection uses to recognize "vllm" and "llamacpp"
+func (s *Server) SetOwnedBy(owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ownedBy = owner
+}
+
+// Respond sets a function that answers requests once the script runs out.
+// n counts all chat requests so far, starting at 0.
+func (s *Server) Respond(fn func(n int, req openai.ChatCompletionRequest) Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responder = fn
+}
+
+// Requests returns the chat requests received so far
+func (s *Server) Requests() []openai.ChatCompletionRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]openai.ChatCompletionRequest(nil), s.requests...)
+}
+
+// LastMessage returns the content of the final message of the nth request
+func (s *Server) LastMessage(n int) string {
+	requests := s.Requests()
+	if n >= len(requests) || len(requests[n].Messages) == 0 {
+		s.t.Errorf("llmtest: no request %d (got %d requests)", n, len(requests))
+		return ""
+	}
+	msgs := requests[n].Messages
+	return msgs[len(msgs)-1].Content
+}
+
+// Embedded returns the texts embedded so far, in order
+func (s *Server) Embedded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.embedded...)
+}
+
+// Remaining returns how many scripted replies have not been used
+func (s *Server) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.script)
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	models, owner := s.models, s.ownedBy
+	s.mu.Unlock()
+
+	data := make([]map[string]any, 0, len(models))
+	for _, m := range models {
+		data = append(data, map[string]any{"id": m, "object": "model", "owned_by": owner})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": data})
+}
+
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	models := s.models
+	s.mu.Unlock()
+
+	tags := make([]map[string]any, 0, len(models))
+	for _, m := range models {
+		tags = append(tags, map[string]any{"name": m, "model": m})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"models": tags})
+}
+
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Input any    `json:"input"`
+		Model string `json:"model"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	var inputs []string
+	switch v := req.Input.(type) {
+	case string:
+		inputs = []string{v}
+	case []any:
+		for _, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				writeError(w, http.StatusBadRequest, "input must be strings")
+				return
+			}
+			inputs = append(inputs, text)
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "input is required")
+		return
+	}
+
+	s.mu.Lock()
+	s.embedded = append(s.embedded, inputs...)
+	s.mu.Unlock()
+
+	data := make([]map[string]any, len(inputs))
+	tokens := 0
+	for i, text := range inputs {
+		data[i] = map[string]any{"object": "embedding", "index": i, "embedding": embed(text)}
+		tokens += len(strings.Fields(text))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"model":  req.Model,
+		"data":   data,
+		"usage":  map[string]any{"prompt_tokens": tokens, "total_tokens": tokens},
+	})
+}
+
+// embed hashes the lowercased words of text into a normalized vector
+func embed(text string) []float32 {
+	const dimensions = 256
+	v := make([]float32, dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		v[h.Sum32()%dimensions]++
+	}
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm > 0 {
+		for i := range v {
+			v[i] /= float32(math.Sqrt(norm))
+		}
+	}
+	return v
+}
+
+// next records a request and returns its scripted reply
+func (s *Server) next(req openai.ChatCompletionRequest) (Reply, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.requests)
+	s.requests = append(s.requests, req)
+	if len(s.script) > 0 {
+		reply := s.script[0]
+		s.script = s.script[1:]
+		return reply, true
+	}
+	if s.responder != nil {
+		return s.responder(n, req), true
+	}
+	return Reply{}, false
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	reply, ok := s.next(req)
+	if !ok {
+		s.t.Errorf("llmtest: unexpected chat request %d with no scripted reply", len(s.Requests()))
+		writeError(w, http.StatusInternalServerError, "no scripted reply")
+		return
+	}
+
+	if reply.Delay > 0 {
+		select {
+		case <-time.After(reply.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	switch {
+	case reply.Status != 0:
+		writeError(w, reply.Status, reply.Content)
+	case reply.Raw != "":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, reply.Raw)
+	case req.Stream:
+		s.stream(w, req, reply)
+	default:
+		message := map[string]any{"role": "assistant", "content": reply.Content}
+		if reply.Reasoning != "" {
+			message["reasoning_content"] = reply.Reasoning
+		}
+		resp := map[string]any{
+			"id":      "chatcmpl-llmtest",
+			"object":  "chat.completion",
+			"model":   req.Model,
+			"choices": []map[string]any{{"index": 0, "message": message, "finish_reason": "stop"}},
+		}
+		if !reply.OmitUsage {
+			resp["usage"] = usageFor(req, reply)
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// stream writes the reply as server-sent events: reasoning deltas, content
+// deltas, then a usage chunk when the request asked for one
+func (s *Server) stream(w http.ResponseWriter, req openai.ChatCompletionRequest, reply Reply) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(chunk map[string]any) {
+		chunk["id"] = "chatcmpl-llmtest"
+		chunk["object"] = "chat.completion.chunk"
+		chunk["model"] = req.Model
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	delta := func(key, text string) map[string]any {
+		return map[string]any{"choices": []map[string]any{{"index": 0, "delta": map[string]any{key: text}}}}
+	}
+
+	for _, piece := range split(reply.Reasoning) {
+		send(delta("reasoning_content", piece))
+	}
+	chunks := reply.Chunks
+	if chunks == nil {
+		chunks = split(reply.Content)
+	}
+	for _, piece := range chunks {
+		send(delta("content", piece))
+	}
+	send(map[string]any{"choices": []map[string]any{{"index": 0, "delta": map[string]any{}, "finish_reason": "stop"}}})
+
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage && !reply.OmitUsage {
+		send(map[string]any{"choices": []map[string]any{}, "usage": usageFor(req, reply)})
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// split breaks text into small chunks on rune boundaries, the way servers
+// stream a few tokens at a time
+func split(text string) []string {
+	const runesPerChunk = 4
+	var chunks []string
+	runes := []rune(text)
+	for len(runes) > 0 {
+		n := min(runesPerChunk, len(runes))
+		chunks = append(chunks, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return chunks
+}
+
+// usageFor returns the reply's scripted usage or a rough count of the
+// request and reply text
+func usageFor(req openai.ChatCompletionRequest, reply Reply) openai.Usage {
+	if reply.Usage != nil {
+		return *reply.Usage
+	}
+	prompt := 0
+	for _, msg := range req.Messages {
+		prompt += len(strings.Fields(msg.Content)) + 4
+	}
+	completion := len(strings.Fields(reply.Reasoning + " " + reply.Content))
+	return openai.Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{"message": message, "type": "llmtest_error", "code": status},
+	})
+}
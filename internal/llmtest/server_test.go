@@ -0,0 +1,100 @@
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/tara-vision/taracode/internal/provider"
+)
+
+func client(s *Server) *openai.Client {
+	p := provider.NewVLLMProvider(s.URL, "")
+	return p.CreateClient()
+}
+
+func TestStreamedReplyWithReasoningAndUsage(t *testing.T) {
+	s := New(t, Reply{Content: "Hello, world.", Reasoning: "Say hello."})
+
+	stream, err := client(s).CreateChatCompletionStream(context.Background(), openai.ChatCompletionRequest{
+		Model:         DefaultModel,
+		Messages:      []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	var usage *openai.Usage
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chunk.Choices) > 0 {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+
+	// The provider transport folds reasoning_content into a <think> block
+	if got := content.String(); got != "<think>Say hello.</think>Hello, world." {
+		t.Errorf("content = %q", got)
+	}
+	if usage == nil || usage.CompletionTokens == 0 {
+		t.Errorf("usage = %+v", usage)
+	}
+	if got := s.LastMessage(0); got != "hi" {
+		t.Errorf("recorded message = %q", got)
+	}
+}
+
+func TestScriptThenResponder(t *testing.T) {
+	s := New(t, Text("first"), Error(503, "overloaded"), Reply{Raw: "{"})
+	s.Respond(func(n int, req openai.ChatCompletionRequest) Reply {
+		return Text("fallback")
+	})
+	c := client(s)
+	req := openai.ChatCompletionRequest{Model: DefaultModel, Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}}}
+
+	resp, err := c.CreateChatCompletion(context.Background(), req)
+	if err != nil || resp.Choices[0].Message.Content != "first" {
+		t.Fatalf("first reply = %+v, %v", resp, err)
+	}
+	var apiErr *openai.APIError
+	if _, err := c.CreateChatCompletion(context.Background(), req); !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != 503 {
+		t.Errorf("second reply error = %v", err)
+	}
+	if _, err := c.CreateChatCompletion(context.Background(), req); err == nil {
+		t.Error("malformed reply did not fail")
+	}
+	if resp, err := c.CreateChatCompletion(context.Background(), req); err != nil || resp.Choices[0].Message.Content != "fallback" {
+		t.Errorf("responder reply = %+v, %v", resp, err)
+	}
+	if s.Remaining() != 0 || len(s.Requests()) != 4 {
+		t.Errorf("remaining %d, requests %d", s.Remaining(), len(s.Requests()))
+	}
+}
+
+func TestDetection(t *testing.T) {
+	ctx := context.Background()
+	if got := provider.Detect(ctx, NewOllama(t).URL); got != provider.TypeOllama {
+		t.Errorf("NewOllama detected as %s", got)
+	}
+
+	s := New(t)
+	s.SetOwnedBy("llamacpp")
+	if got := provider.Detect(ctx, s.URL); got != provider.TypeLlamaCpp {
+		t.Errorf("owned_by llamacpp detected as %s", got)
+	}
+}
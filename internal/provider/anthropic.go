@@ -0,0 +1,578 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	anthropicAPIVersion       = "2023-06-01"
+	anthropicDefaultMaxTokens = 4096
+)
+
+// AnthropicProvider implements Provider for Anthropic Messages API endpoints.
+// The rest of the application speaks the OpenAI chat completions format, so
+// requests are translated to and from the Messages API by an HTTP transport
+// installed on the client returned from CreateClient.
+type AnthropicProvider struct {
+	*BaseProvider
+}
+
+// NewAnthropicProvider creates a new Anthropic provider
+func NewAnthropicProvider(host, apiKey string) *AnthropicProvider {
+	base := NewBaseProvider(TypeAnthropic, host, apiKey)
+	base.info.SupportsTools = true // Messages API supports native tool use
+	base.httpClient.Transport = &anthropicTransport{
+		base:   base.httpClient.Transport,
+		apiKey: apiKey,
+	}
+	return &AnthropicProvider{BaseProvider: base}
+}
+
+// Info returns provider metadata
+func (p *AnthropicProvider) Info() *Info {
+	return p.BaseProvider.Info()
+}
+
+// DetectModels queries available models from the /v1/models endpoint.
+// The response shape matches OpenAI's; only the auth headers differ, and
+// those are handled by the transport.
+func (p *AnthropicProvider) DetectModels(ctx context.Context) ([]string, error) {
+	return p.DetectModelsOpenAI(ctx)
+}
+
+// CreateClient returns an OpenAI-compatible client backed by the Messages API
+func (p *AnthropicProvider) CreateClient() *openai.Client {
+	return p.BaseProvider.CreateClient()
+}
+
+// SetModel sets the active model
+func (p *AnthropicProvider) SetModel(model string) {
+	p.BaseProvider.SetModel(model)
+}
+
+// anthropicTransport rewrites auth headers for every request and translates
+// chat completion calls into Messages API calls
+type anthropicTransport struct {
+	base   http.RoundTripper
+	apiKey string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *anthropicTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Del("Authorization")
+	if t.apiKey != "" {
+		req.Header.Set("x-api-key", t.apiKey)
+	}
+	req.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return t.base.RoundTrip(req)
+	}
+	return t.roundTripChat(req)
+}
+
+func (t *anthropicTransport) roundTripChat(req *http.Request) (*http.Response, error) {
+	var chatReq openai.ChatCompletionRequest
+	if req.Body != nil {
+		defer req.Body.Close()
+		if err := json.NewDecoder(req.Body).Decode(&chatReq); err != nil {
+			return nil, fmt.Errorf("decode chat request: %w", err)
+		}
+	}
+
+	body, err := json.Marshal(toAnthropicRequest(chatReq))
+	if err != nil {
+		return nil, fmt.Errorf("encode messages request: %w", err)
+	}
+
+	req.URL.Path = strings.TrimSuffix(req.URL.Path, "/chat/completions") + "/messages"
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = nil
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return translateAnthropicError(resp), nil
+	}
+
+	if chatReq.Stream {
+		includeUsage := chatReq.StreamOptions != nil && chatReq.StreamOptions.IncludeUsage
+		resp.Body = newAnthropicStreamReader(resp.Body, chatReq.Model, includeUsage)
+		resp.Header.Set("Content-Type", "text/event-stream")
+		resp.ContentLength = -1
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	var msgResp anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&msgResp); err != nil {
+		return nil, fmt.Errorf("decode messages response: %w", err)
+	}
+
+	out, err := json.Marshal(fromAnthropicResponse(msgResp))
+	if err != nil {
+		return nil, fmt.Errorf("encode chat response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(out))
+	resp.ContentLength = int64(len(out))
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// ============= Wire types =============
+
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   *float32           `json:"temperature,omitempty"`
+	TopP          *float32           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	Tools         []anthropicTool    `json:"tools,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string             `json:"role"`
+	Content []anthropicContent `json:"content"`
+}
+
+type anthropicContent struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Source    *anthropicImageSource `json:"source,omitempty"`
+	ID        string                `json:"id,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Input     json.RawMessage       `json:"input,omitempty"`
+	ToolUseID string                `json:"tool_use_id,omitempty"`
+	Content   string                `json:"content,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"` // base64 or url
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	ID         string             `json:"id"`
+	Model      string             `json:"model"`
+	Content    []anthropicContent `json:"content"`
+	StopReason string             `json:"stop_reason"`
+	Usage      anthropicUsage     `json:"usage"`
+}
+
+type anthropicError struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// ============= Request translation =============
+
+// toAnthropicRequest converts an OpenAI chat request to a Messages API request.
+// System messages become the top-level system prompt, tool results become
+// tool_result blocks, and consecutive messages with the same role are merged
+// because the Messages API requires strictly alternating turns.
+func toAnthropicRequest(req openai.ChatCompletionRequest) anthropicRequest {
+	out := anthropicRequest{
+		Model:         req.Model,
+		MaxTokens:     req.MaxTokens,
+		StopSequences: req.Stop,
+		Stream:        req.Stream,
+	}
+	if req.MaxCompletionTokens > 0 {
+		out.MaxTokens = req.MaxCompletionTokens
+	}
+	if out.MaxTokens <= 0 {
+		out.MaxTokens = anthropicDefaultMaxTokens
+	}
+	if req.Temperature != 0 {
+		temp := req.Temperature
+		out.Temperature = &temp
+	}
+	if req.TopP != 0 {
+		topP := req.TopP
+		out.TopP = &topP
+	}
+
+	for _, tool := range req.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = map[string]any{"type": "object"}
+		}
+		out.Tools = append(out.Tools, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+
+	var system []string
+	for _, msg := range req.Messages {
+		var role string
+		var blocks []anthropicContent
+
+		switch msg.Role {
+		case openai.ChatMessageRoleSystem:
+			system = append(system, messageText(msg))
+			continue
+		case openai.ChatMessageRoleTool, openai.ChatMessageRoleFunction:
+			role = "user"
+			blocks = []anthropicContent{{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   messageText(msg),
+			}}
+		case openai.ChatMessageRoleAssistant:
+			role = "assistant"
+			if text := messageText(msg); text != "" {
+				blocks = append(blocks, anthropicContent{Type: "text", Text: text})
+			}
+			for _, call := range msg.ToolCalls {
+				input := json.RawMessage(call.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicContent{
+					Type:  "tool_use",
+					ID:    call.ID,
+					Name:  call.Function.Name,
+					Input: input,
+				})
+			}
+		default:
+			role = "user"
+			blocks = userContentBlocks(msg)
+		}
+
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if n := len(out.Messages); n > 0 && out.Messages[n-1].Role == role {
+			out.Messages[n-1].Content = append(out.Messages[n-1].Content, blocks...)
+			continue
+		}
+		out.Messages = append(out.Messages, anthropicMessage{Role: role, Content: blocks})
+	}
+
+	out.System = strings.Join(system, "\n\n")
+	return out
+}
+
+// messageText returns the plain text of a message, joining text parts of
+// multi-part content
+func messageText(msg openai.ChatCompletionMessage) string {
+	if len(msg.MultiContent) == 0 {
+		return msg.Content
+	}
+	var parts []string
+	for _, part := range msg.MultiContent {
+		if part.Type == openai.ChatMessagePartTypeText {
+			parts = append(parts, part.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// userContentBlocks converts user message content, including image parts
+func userContentBlocks(msg openai.ChatCompletionMessage) []anthropicContent {
+	if len(msg.MultiContent) == 0 {
+		if msg.Content == "" {
+			return nil
+		}
+		return []anthropicContent{{Type: "text", Text: msg.Content}}
+	}
+
+	var blocks []anthropicContent
+	for _, part := range msg.MultiContent {
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			blocks = append(blocks, anthropicContent{Type: "text", Text: part.Text})
+		case openai.ChatMessagePartTypeImageURL:
+			if part.ImageURL == nil {
+				continue
+			}
+			blocks = append(blocks, anthropicContent{Type: "image", Source: imageSource(part.ImageURL.URL)})
+		}
+	}
+	return blocks
+}
+
+// imageSource converts an image URL (possibly a data: URL) to an image source
+func imageSource(url string) *anthropicImageSource {
+	// data:image/png;base64,XXXX
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		if meta, data, found := strings.Cut(rest, ","); found {
+			return &anthropicImageSource{
+				Type:      "base64",
+				MediaType: strings.TrimSuffix(meta, ";base64"),
+				Data:      data,
+			}
+		}
+	}
+	return &anthropicImageSource{Type: "url", URL: url}
+}
+
+// ============= Response translation =============
+
+// fromAnthropicResponse converts a Messages API response to a chat completion
+func fromAnthropicResponse(resp anthropicResponse) openai.ChatCompletionResponse {
+	msg := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	var text strings.Builder
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			msg.ToolCalls = append(msg.ToolCalls, openai.ToolCall{
+				ID:   block.ID,
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      block.Name,
+					Arguments: string(block.Input),
+				},
+			})
+		}
+	}
+	msg.Content = text.String()
+
+	return openai.ChatCompletionResponse{
+		ID:      resp.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   resp.Model,
+		Choices: []openai.ChatCompletionChoice{{
+			Index:        0,
+			Message:      msg,
+			FinishReason: finishReason(resp.StopReason),
+		}},
+		Usage: openai.Usage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		},
+	}
+}
+
+// finishReason maps a Messages API stop_reason to an OpenAI finish_reason
+func finishReason(stopReason string) openai.FinishReason {
+	switch stopReason {
+	case "max_tokens":
+		return openai.FinishReasonLength
+	case "tool_use":
+		return openai.FinishReasonToolCalls
+	case "":
+		return openai.FinishReasonNull
+	default:
+		return openai.FinishReasonStop
+	}
+}
+
+// translateAnthropicError rewrites an error body into the OpenAI error shape
+// so the client surfaces the server's message
+func translateAnthropicError(resp *http.Response) *http.Response {
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+
+	message := strings.TrimSpace(string(raw))
+	errType := "api_error"
+	var apiErr anthropicError
+	if json.Unmarshal(raw, &apiErr) == nil && apiErr.Error.Message != "" {
+		message = apiErr.Error.Message
+		errType = apiErr.Error.Type
+	}
+
+	out, _ := json.Marshal(map[string]any{
+		"error": map[string]string{"message": message, "type": errType},
+	})
+	resp.Body = io.NopCloser(bytes.NewReader(out))
+	resp.ContentLength = int64(len(out))
+	resp.Header.Del("Content-Length")
+	resp.Header.Set("Content-Type", "application/json")
+	return resp
+}
+
+// ============= Stream translation =============
+
+// anthropicStreamEvent covers the fields used from Messages API stream events
+type anthropicStreamEvent struct {
+	Type         string            `json:"type"`
+	Index        int               `json:"index"`
+	Message      anthropicResponse `json:"message"`
+	ContentBlock anthropicContent  `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// newAnthropicStreamReader translates a Messages API event stream into
+// OpenAI chat completion chunks as it is read
+func newAnthropicStreamReader(body io.ReadCloser, model string, includeUsage bool) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer body.Close()
+		pw.CloseWithError(translateAnthropicStream(body, pw, model, includeUsage))
+	}()
+	return pr
+}
+
+func translateAnthropicStream(r io.Reader, w io.Writer, model string, includeUsage bool) error {
+	var (
+		id        string
+		usage     anthropicUsage
+		toolIndex = map[int]int{} // content block index -> tool call index
+	)
+
+	emit := func(chunk any) error {
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		return err
+	}
+	chunk := func(delta openai.ChatCompletionStreamChoiceDelta, finish openai.FinishReason) openai.ChatCompletionStreamResponse {
+		return openai.ChatCompletionStreamResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: time.Now().Unix(),
+			Model:   model,
+			Choices: []openai.ChatCompletionStreamChoice{{Delta: delta, FinishReason: finish}},
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue // event: lines and blank separators
+		}
+
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			continue
+		}
+
+		var err error
+		switch event.Type {
+		case "message_start":
+			id = event.Message.ID
+			if event.Message.Model != "" {
+				model = event.Message.Model
+			}
+			usage.InputTokens = event.Message.Usage.InputTokens
+			err = emit(chunk(openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant}, ""))
+
+		case "content_block_start":
+			if event.ContentBlock.Type == "tool_use" {
+				idx := len(toolIndex)
+				toolIndex[event.Index] = idx
+				err = emit(chunk(openai.ChatCompletionStreamChoiceDelta{
+					ToolCalls: []openai.ToolCall{{
+						Index:    &idx,
+						ID:       event.ContentBlock.ID,
+						Type:     openai.ToolTypeFunction,
+						Function: openai.FunctionCall{Name: event.ContentBlock.Name},
+					}},
+				}, ""))
+			}
+
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				err = emit(chunk(openai.ChatCompletionStreamChoiceDelta{Content: event.Delta.Text}, ""))
+			case "input_json_delta":
+				idx := toolIndex[event.Index]
+				err = emit(chunk(openai.ChatCompletionStreamChoiceDelta{
+					ToolCalls: []openai.ToolCall{{
+						Index:    &idx,
+						Function: openai.FunctionCall{Arguments: event.Delta.PartialJSON},
+					}},
+				}, ""))
+			}
+
+		case "message_delta":
+			usage.OutputTokens = event.Usage.OutputTokens
+			if event.Delta.StopReason != "" {
+				err = emit(chunk(openai.ChatCompletionStreamChoiceDelta{}, finishReason(event.Delta.StopReason)))
+			}
+
+		case "message_stop":
+			if includeUsage {
+				err = emit(openai.ChatCompletionStreamResponse{
+					ID:      id,
+					Object:  "chat.completion.chunk",
+					Created: time.Now().Unix(),
+					Model:   model,
+					Choices: []openai.ChatCompletionStreamChoice{},
+					Usage: &openai.Usage{
+						PromptTokens:     usage.InputTokens,
+						CompletionTokens: usage.OutputTokens,
+						TotalTokens:      usage.InputTokens + usage.OutputTokens,
+					},
+				})
+			}
+			if err == nil {
+				_, err = io.WriteString(w, "data: [DONE]\n\n")
+			}
+			return err
+
+		case "error":
+			return emit(map[string]any{
+				"error": map[string]string{"message": event.Error.Message, "type": event.Error.Type},
+			})
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
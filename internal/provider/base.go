@@ -27,12 +27,19 @@ type BaseProvider struct {
 func NewBaseProvider(providerType Type, host, apiKey string) *BaseProvider {
 	host = strings.TrimSuffix(host, "/")
 
+	// Hosts configured with the API prefix already in the URL
+	// (e.g. https://openrouter.ai/api/v1) must not get it twice
+	apiPath := "/v1"
+	if strings.HasSuffix(host, "/v1") {
+		apiPath = ""
+	}
+
 	return &BaseProvider{
 		info: &Info{
 			Type:    providerType,
 			Name:    providerType.DisplayName(),
 			Host:    host,
-			APIPath: "/v1",
+			APIPath: apiPath,
 		},
 		httpClient: newHTTPClient(),
 		apiKey:     apiKey,
@@ -59,7 +66,7 @@ func (p *BaseProvider) CreateClient() *openai.Client {
 
 // DetectModelsOpenAI queries the /v1/models endpoint (OpenAI-compatible)
 func (p *BaseProvider) DetectModelsOpenAI(ctx context.Context) ([]string, error) {
-	url := p.info.Host + "/v1/models"
+	url := p.info.Host + p.info.APIPath + "/models"
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -105,14 +112,16 @@ func (p *BaseProvider) DetectModelsOpenAI(ctx context.Context) ([]string, error)
 func newHTTPClient() *http.Client {
 	return &http.Client{
 		Timeout: 0, // Disabled - use context timeout for streaming
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   defaultConnectTimeout,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			MaxIdleConns:        10,
-			IdleConnTimeout:     90 * time.Second,
-			TLSHandshakeTimeout: 10 * time.Second,
+		Transport: &chatTransport{
+			base: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout:   defaultConnectTimeout,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
 		},
 	}
 }
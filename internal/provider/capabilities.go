@@ -0,0 +1,424 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	probeRequestTimeout = 60 * time.Second // first request may include model load time
+	capabilityCacheTTL  = 7 * 24 * time.Hour
+	capabilityCacheFile = "capabilities.json"
+)
+
+// tinyPNG is a 1x1 transparent PNG used to probe image input support
+const tinyPNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg=="
+
+var thinkBlockRe = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// Capabilities describes what an endpoint actually supports for a model,
+// as measured by small test requests
+type Capabilities struct {
+	Host        string        `json:"host"`
+	Model       string        `json:"model"`
+	NativeTools bool          `json:"native_tools"` // returns structured tool_calls
+	StreamUsage bool          `json:"stream_usage"` // honors stream_options.include_usage
+	JSONMode    bool          `json:"json_mode"`    // honors response_format json_object
+	Vision      bool          `json:"vision"`       // accepts image_url content parts
+	Embeddings  bool          `json:"embeddings"`   // embeds with the model at /v1/embeddings
+	MaxContext  int           `json:"max_context,omitempty"`
+	ProbedAt    time.Time     `json:"probed_at"`
+	Checks      []ProbeResult `json:"checks,omitempty"`
+}
+
+// ProbeResult records the outcome of a single capability check
+type ProbeResult struct {
+	Name      string `json:"name"`
+	Supported bool   `json:"supported"`
+	Detail    string `json:"detail,omitempty"`
+	Duration  int64  `json:"duration_ms"`
+}
+
+// Apply copies probed capabilities onto provider metadata
+func (c *Capabilities) Apply(info *Info) {
+	if c == nil || info == nil {
+		return
+	}
+	info.SupportsTools = c.NativeTools
+	info.Capabilities = c
+}
+
+// Probe checks what the provider's endpoint supports for the active model
+// with a handful of tiny requests. Individual check failures are recorded
+// as unsupported rather than returned as errors.
+func Probe(ctx context.Context, p Provider) *Capabilities {
+	info := p.Info()
+	client := p.CreateClient()
+
+	caps := &Capabilities{
+		Host:     info.Host,
+		Model:    info.Model,
+		ProbedAt: time.Now(),
+	}
+
+	checks := []struct {
+		name string
+		fn   func(context.Context, *openai.Client, string) (bool, string)
+		set  *bool
+	}{
+		{"native tool calls", probeNativeTools, &caps.NativeTools},
+		{"stream usage", probeStreamUsage, &caps.StreamUsage},
+		{"JSON mode", probeJSONMode, &caps.JSONMode},
+		{"vision input", probeVision, &caps.Vision},
+		{"embeddings", probeEmbeddings, &caps.Embeddings},
+	}
+
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, probeRequestTimeout)
+		start := time.Now()
+		supported, detail := check.fn(checkCtx, client, info.Model)
+		cancel()
+
+		*check.set = supported
+		caps.Checks = append(caps.Checks, ProbeResult{
+			Name:      check.name,
+			Supported: supported,
+			Detail:    detail,
+			Duration:  time.Since(start).Milliseconds(),
+		})
+	}
+
+	start := time.Now()
+	maxCtx, detail := probeMaxContext(ctx, p)
+	caps.MaxContext = maxCtx
+	caps.Checks = append(caps.Checks, ProbeResult{
+		Name:      "max context",
+		Supported: maxCtx > 0,
+		Detail:    detail,
+		Duration:  time.Since(start).Milliseconds(),
+	})
+
+	return caps
+}
+
+func probeNativeTools(ctx context.Context, client *openai.Client, model string) (bool, string) {
+	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:     model,
+		MaxTokens: 512,
+		Messages: []openai.ChatCompletionMessage{{
+			Role:    openai.ChatMessageRoleUser,
+			Content: "What time is it in UTC? Use the get_time tool.",
+		}},
+		Tools: []openai.Tool{{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "get_time",
+				Description: "Returns the current time in a timezone",
+				Parameters: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"timezone": map[string]any{"type": "string"},
+					},
+					"required": []string{"timezone"},
+				},
+			},
+		}},
+	})
+	if err != nil {
+		return false, shortError(err)
+	}
+	if len(resp.Choices) == 0 {
+		return false, "no choices returned"
+	}
+	if calls := resp.Choices[0].Message.ToolCalls; len(calls) > 0 {
+		return true, fmt.Sprintf("model called %s", calls[0].Function.Name)
+	}
+	return false, "request accepted but no tool_calls returned"
+}
+
+func probeStreamUsage(ctx context.Context, client *openai.Client, model string) (bool, string) {
+	stream, err := client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
+		Model:         model,
+		MaxTokens:     16,
+		Messages:      []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "Say OK."}},
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		return false, shortError(err)
+	}
+	defer stream.Close()
+
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return false, "stream completed without a usage chunk"
+		}
+		if err != nil {
+			return false, shortError(err)
+		}
+		if chunk.Usage != nil && chunk.Usage.TotalTokens > 0 {
+			return true, fmt.Sprintf("%d tokens reported", chunk.Usage.TotalTokens)
+		}
+	}
+}
+
+func probeJSONMode(ctx context.Context, client *openai.Client, model string) (bool, string) {
+	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:     model,
+		MaxTokens: 256,
+		Messages: []openai.ChatCompletionMessage{{
+			Role:    openai.ChatMessageRoleUser,
+			Content: `Reply with the JSON object {"ok": true} and nothing else.`,
+		}},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
+	})
+	if err != nil {
+		return false, shortError(err)
+	}
+	if len(resp.Choices) == 0 {
+		return false, "no choices returned"
+	}
+	content := strings.TrimSpace(thinkBlockRe.ReplaceAllString(resp.Choices[0].Message.Content, ""))
+	if json.Valid([]byte(content)) {
+		return true, "response was valid JSON"
+	}
+	return false, "response was not valid JSON"
+}
+
+func probeVision(ctx context.Context, client *openai.Client, model string) (bool, string) {
+	_, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:     model,
+		MaxTokens: 16,
+		Messages: []openai.ChatCompletionMessage{{
+			Role: openai.ChatMessageRoleUser,
+			MultiContent: []openai.ChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "What color is this image? One word."},
+				{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{
+					URL: "data:image/png;base64," + tinyPNG,
+				}},
+			},
+		}},
+	})
+	if err != nil {
+		return false, shortError(err)
+	}
+	return true, "image input accepted"
+}
+
+func probeEmbeddings(ctx context.Context, client *openai.Client, model string) (bool, string) {
+	resp, err := client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: []string{"OK"},
+		Model: openai.EmbeddingModel(model),
+	})
+	if err != nil {
+		return false, shortError(err)
+	}
+	if len(resp.Data) == 0 || len(resp.Data[0].Embedding) == 0 {
+		return false, "no embedding returned"
+	}
+	return true, fmt.Sprintf("%d-dimension vectors", len(resp.Data[0].Embedding))
+}
+
+// metadataFetcher is implemented by providers that can query server
+// metadata endpoints with their own auth
+type metadataFetcher interface {
+	fetchJSON(ctx context.Context, method, path string, body, out any) error
+}
+
+// serverRoot returns the host without a trailing /v1, where server-specific
+// endpoints such as /props and /tokenize live
+func (p *BaseProvider) serverRoot() string {
+	return strings.TrimSuffix(p.info.Host, "/v1")
+}
+
+// fetchJSON performs an authenticated request against a path on the server root
+func (p *BaseProvider) fetchJSON(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.serverRoot()+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// probeMaxContext reads the context window from server-specific metadata:
+// vLLM and gateways report it in /v1/models, llama.cpp in /props and
+// Ollama in /api/show
+func probeMaxContext(ctx context.Context, p Provider) (int, string) {
+	fetcher, ok := p.(metadataFetcher)
+	if !ok {
+		return 0, "provider does not expose metadata"
+	}
+	info := p.Info()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var models struct {
+		Data []struct {
+			ID            string `json:"id"`
+			MaxModelLen   int    `json:"max_model_len"`
+			ContextLength int    `json:"context_length"`
+		} `json:"data"`
+	}
+	if err := fetcher.fetchJSON(ctx, "GET", "/v1/models", nil, &models); err == nil {
+		for _, m := range models.Data {
+			if m.ID != info.Model {
+				continue
+			}
+			if m.MaxModelLen > 0 {
+				return m.MaxModelLen, "max_model_len from /v1/models"
+			}
+			if m.ContextLength > 0 {
+				return m.ContextLength, "context_length from /v1/models"
+			}
+		}
+	}
+
+	switch info.Type {
+	case TypeLlamaCpp:
+		var props struct {
+			NCtx     int `json:"n_ctx"`
+			Settings struct {
+				NCtx int `json:"n_ctx"`
+			} `json:"default_generation_settings"`
+		}
+		if err := fetcher.fetchJSON(ctx, "GET", "/props", nil, &props); err == nil {
+			if props.Settings.NCtx > 0 {
+				return props.Settings.NCtx, "n_ctx from /props"
+			}
+			if props.NCtx > 0 {
+				return props.NCtx, "n_ctx from /props"
+			}
+		}
+
+	case TypeOllama:
+		var show struct {
+			ModelInfo map[string]any `json:"model_info"`
+		}
+		if err := fetcher.fetchJSON(ctx, "POST", "/api/show", map[string]string{"model": info.Model}, &show); err == nil {
+			for key, value := range show.ModelInfo {
+				if n, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") {
+					return int(n), key + " from /api/show"
+				}
+			}
+		}
+	}
+
+	return 0, "server does not report a context window"
+}
+
+// shortError trims an error message to a single readable line
+func shortError(err error) string {
+	msg := strings.TrimSpace(strings.SplitN(err.Error(), "\n", 2)[0])
+	if len(msg) > 120 {
+		msg = msg[:117] + "..."
+	}
+	return msg
+}
+
+// ============= Capability cache =============
+
+var cacheMu sync.Mutex
+
+// CapabilityCachePath returns the path of the per-user capability cache
+// (~/.taracode/capabilities.json)
+func CapabilityCachePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".taracode", capabilityCacheFile), nil
+}
+
+func capabilityKey(host, model string) string {
+	return strings.TrimSuffix(host, "/") + "|" + model
+}
+
+func readCapabilityCache(path string) map[string]*Capabilities {
+	cache := make(map[string]*Capabilities)
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+// LoadCachedCapabilities returns cached capabilities for host+model, or nil
+// if none are cached or the entry has expired
+func LoadCachedCapabilities(host, model string) *Capabilities {
+	path, err := CapabilityCachePath()
+	if err != nil {
+		return nil
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	caps, ok := readCapabilityCache(path)[capabilityKey(host, model)]
+	if !ok || time.Since(caps.ProbedAt) > capabilityCacheTTL {
+		return nil
+	}
+	return caps
+}
+
+// SaveCapabilities stores probed capabilities in the per-user cache
+func SaveCapabilities(caps *Capabilities) error {
+	path, err := CapabilityCachePath()
+	if err != nil {
+		return err
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	cache := readCapabilityCache(path)
+	cache[capabilityKey(caps.Host, caps.Model)] = caps
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal capabilities: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
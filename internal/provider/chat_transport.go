@@ -0,0 +1,223 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type extraBodyKey struct{}
+
+// WithExtraBody returns a context whose chat completion requests carry
+// additional top-level JSON fields that go-openai has no field for, such
+// as chat_template_kwargs
+func WithExtraBody(ctx context.Context, fields map[string]any) context.Context {
+	return context.WithValue(ctx, extraBodyKey{}, fields)
+}
+
+// chatTransport injects extra request fields and normalizes reasoning
+// output: servers that return reasoning in a separate reasoning_content
+// (vLLM, llama.cpp) or reasoning (Ollama) field have it folded back into
+// content as a <think> block, so callers see one format
+type chatTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *chatTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return t.base.RoundTrip(req)
+	}
+
+	if extra, ok := req.Context().Value(extraBodyKey{}).(map[string]any); ok && len(extra) > 0 && req.Body != nil {
+		body, err := mergeBody(req.Body, extra)
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = nil
+		req.ContentLength = int64(len(body))
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		resp.Body = newReasoningStreamReader(resp.Body)
+		return resp, nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	data = foldReasoning(data)
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// mergeBody adds fields to a JSON request body
+func mergeBody(body io.ReadCloser, fields map[string]any) ([]byte, error) {
+	defer body.Close()
+
+	var obj map[string]any
+	if err := json.NewDecoder(body).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("decode chat request: %w", err)
+	}
+	for key, value := range fields {
+		obj[key] = value
+	}
+	return json.Marshal(obj)
+}
+
+// reasoningText returns the separate reasoning field of a message or delta
+func reasoningText(msg map[string]any) string {
+	for _, key := range []string{"reasoning_content", "reasoning"} {
+		if text, ok := msg[key].(string); ok && text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// foldReasoning rewrites a chat completion so reasoning precedes the
+// content inside <think> tags. Bodies it cannot parse are returned as-is.
+func foldReasoning(data []byte) []byte {
+	var resp map[string]any
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return data
+	}
+	choices, _ := resp["choices"].([]any)
+
+	changed := false
+	for _, c := range choices {
+		choice, _ := c.(map[string]any)
+		msg, _ := choice["message"].(map[string]any)
+		if msg == nil {
+			continue
+		}
+		if reasoning := reasoningText(msg); reasoning != "" {
+			content, _ := msg["content"].(string)
+			msg["content"] = "<think>" + reasoning + "</think>" + content
+			changed = true
+		}
+	}
+	if !changed {
+		return data
+	}
+
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+// newReasoningStreamReader folds streamed reasoning deltas into content
+// deltas wrapped in <think> tags as the stream is read
+func newReasoningStreamReader(body io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer body.Close()
+		pw.CloseWithError(foldReasoningStream(body, pw))
+	}()
+	return pr
+}
+
+func foldReasoningStream(r io.Reader, w io.Writer) error {
+	inReasoning := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok || strings.TrimSpace(data) == "[DONE]" {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var chunk map[string]any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &chunk); err != nil {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+			continue
+		}
+
+		choices, _ := chunk["choices"].([]any)
+		changed := false
+		for _, c := range choices {
+			choice, _ := c.(map[string]any)
+			delta, _ := choice["delta"].(map[string]any)
+			if delta == nil {
+				continue
+			}
+			content, _ := delta["content"].(string)
+			reasoning := reasoningText(delta)
+
+			var folded strings.Builder
+			if reasoning != "" {
+				if !inReasoning {
+					folded.WriteString("<think>")
+					inReasoning = true
+				}
+				folded.WriteString(reasoning)
+			}
+			if content != "" && inReasoning {
+				folded.WriteString("</think>")
+				inReasoning = false
+			}
+			if folded.Len() == 0 {
+				continue
+			}
+			folded.WriteString(content)
+			delta["content"] = folded.String()
+			delete(delta, "reasoning_content")
+			delete(delta, "reasoning")
+			changed = true
+		}
+
+		// Close an open reasoning block when the choice finishes without content
+		if !changed && inReasoning {
+			for _, c := range choices {
+				choice, _ := c.(map[string]any)
+				if reason, _ := choice["finish_reason"].(string); reason != "" {
+					if delta, ok := choice["delta"].(map[string]any); ok {
+						delta["content"] = "</think>"
+						inReasoning = false
+						changed = true
+					}
+				}
+			}
+		}
+
+		if !changed {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+			continue
+		}
+		out, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n", out); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
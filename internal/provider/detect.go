@@ -2,47 +2,116 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
 
+// hostedAPIs maps well-known hosted API domains to their provider type
+var hostedAPIs = map[string]Type{
+	"api.anthropic.com": TypeAnthropic,
+	"api.openai.com":    TypeOpenAI,
+	"openrouter.ai":     TypeOpenAI,
+}
+
+// Detection is the outcome of vendor detection along with why it was chosen
+type Detection struct {
+	Type   Type
+	Reason string
+}
+
 // Detect identifies the provider type from host URL
 // It first checks URL patterns, then probes endpoints if needed
 func Detect(ctx context.Context, host string) Type {
+	return DetectWithReason(ctx, host).Type
+}
+
+// DetectWithReason identifies the provider type and explains the choice.
+// Servers are fingerprinted by the endpoints they actually serve; hostname
+// hints are only used when the server cannot be reached.
+func DetectWithReason(ctx context.Context, host string) Detection {
 	// Normalize host
 	host = strings.TrimSuffix(host, "/")
-	hostLower := strings.ToLower(host)
 
-	// 1. Check URL patterns first (fast path)
-	if strings.Contains(hostLower, "ollama") {
-		return TypeOllama
+	// 1. Hosted APIs are recognized by their exact domain
+	if u, err := url.Parse(host); err == nil {
+		hostname := strings.ToLower(u.Hostname())
+		if t, ok := hostedAPIs[hostname]; ok {
+			return Detection{Type: t, Reason: fmt.Sprintf("%s is a known %s endpoint", hostname, t.DisplayName())}
+		}
 	}
-	if strings.Contains(hostLower, "vllm") {
-		return TypeVLLM
+
+	client := newProbeClient()
+	reachable := false
+
+	// 2. Ollama has a unique /api/tags endpoint returning {"models": [...]}
+	if status, body, err := probeGet(ctx, client, host+"/api/tags"); err == nil {
+		reachable = true
+		if status == http.StatusOK && hasJSONKey(body, "models") {
+			return Detection{Type: TypeOllama, Reason: "GET /api/tags returned an Ollama model list"}
+		}
 	}
-	if strings.Contains(hostLower, "llama") && !strings.Contains(hostLower, "ollama") {
-		return TypeLlamaCpp
+
+	// 3. OpenAI-compatible servers identify themselves in /v1/models owned_by
+	base := host
+	if !strings.HasSuffix(base, "/v1") {
+		base += "/v1"
+	}
+	modelsListed := false
+	if status, body, err := probeGet(ctx, client, base+"/models"); err == nil {
+		reachable = true
+		modelsListed = status == http.StatusOK && hasJSONKey(body, "data")
+		if status == http.StatusOK {
+			switch owner := modelsOwner(body); owner {
+			case "vllm":
+				return Detection{Type: TypeVLLM, Reason: `GET /v1/models reports owned_by "vllm"`}
+			case "llamacpp":
+				return Detection{Type: TypeLlamaCpp, Reason: `GET /v1/models reports owned_by "llamacpp"`}
+			}
+		}
+		if status == http.StatusUnauthorized || status == http.StatusForbidden {
+			return Detection{Type: TypeVLLM, Reason: fmt.Sprintf("GET /v1/models requires auth (status %d); assuming OpenAI-compatible", status)}
+		}
 	}
 
-	// 2. Probe endpoints to detect (slower path)
-	// Ollama has a unique /api/tags endpoint
-	if probeEndpoint(ctx, host, "/api/tags") {
-		return TypeOllama
+	// 4. llama-server exposes /props with its generation settings
+	if status, body, err := probeGet(ctx, client, host+"/props"); err == nil {
+		reachable = true
+		if status == http.StatusOK && hasJSONKey(body, "default_generation_settings") {
+			return Detection{Type: TypeLlamaCpp, Reason: "GET /props returned llama.cpp server settings"}
+		}
 	}
 
-	// If /v1/models works, assume vLLM-compatible
-	if probeEndpoint(ctx, host, "/v1/models") {
-		return TypeVLLM
+	if modelsListed {
+		return Detection{Type: TypeVLLM, Reason: "GET /v1/models responded without a known owner; assuming vLLM-compatible"}
 	}
 
-	return TypeUnknown
+	if reachable {
+		return Detection{Type: TypeUnknown, Reason: "server responded but no known endpoints matched"}
+	}
+
+	// 5. Unreachable: fall back to hostname hints
+	hostLower := strings.ToLower(host)
+	switch {
+	case strings.Contains(hostLower, "ollama") || strings.Contains(hostLower, ":11434"):
+		return Detection{Type: TypeOllama, Reason: "server unreachable; hostname suggests Ollama"}
+	case strings.Contains(hostLower, "vllm"):
+		return Detection{Type: TypeVLLM, Reason: "server unreachable; hostname suggests vLLM"}
+	case strings.Contains(hostLower, "llama"):
+		return Detection{Type: TypeLlamaCpp, Reason: "server unreachable; hostname suggests llama.cpp"}
+	}
+
+	return Detection{Type: TypeUnknown, Reason: "server unreachable and hostname gives no hint"}
 }
 
-// probeEndpoint checks if an endpoint responds successfully
-func probeEndpoint(ctx context.Context, host, path string) bool {
-	client := &http.Client{
+// newProbeClient creates a short-timeout client for detection probes
+func newProbeClient() *http.Client {
+	return &http.Client{
 		Timeout: 5 * time.Second,
 		Transport: &http.Transport{
 			DialContext: (&net.Dialer{
@@ -50,22 +119,46 @@ func probeEndpoint(ctx context.Context, host, path string) bool {
 			}).DialContext,
 		},
 	}
+}
 
-	url := strings.TrimSuffix(host, "/") + path
+// probeGet issues a GET request and returns the status and (bounded) body
+func probeGet(ctx context.Context, client *http.Client, url string) (int, []byte, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return false
+		return 0, nil, err
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return false
+		return 0, nil, err
 	}
 	defer resp.Body.Close()
 
-	// Consider 2xx and some 4xx as "endpoint exists"
-	// 404 means endpoint doesn't exist, but 401/403 means it exists but needs auth
-	return resp.StatusCode >= 200 && resp.StatusCode < 500 && resp.StatusCode != 404
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return resp.StatusCode, body, nil
+}
+
+// hasJSONKey reports whether body is a JSON object containing key
+func hasJSONKey(body []byte, key string) bool {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return false
+	}
+	_, ok := obj[key]
+	return ok
+}
+
+// modelsOwner returns the owned_by value of the first model in a /v1/models response
+func modelsOwner(body []byte) string {
+	var resp struct {
+		Data []struct {
+			OwnedBy string `json:"owned_by"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || len(resp.Data) == 0 {
+		return ""
+	}
+	return strings.ToLower(resp.Data[0].OwnedBy)
 }
 
 // ParseVendorConfig parses a vendor string from config into a Type
@@ -80,6 +173,10 @@ func ParseVendorConfig(vendor string) Type {
 		return TypeOllama
 	case "llama.cpp", "llamacpp", "llama":
 		return TypeLlamaCpp
+	case "openai", "litellm", "openrouter":
+		return TypeOpenAI
+	case "anthropic", "claude":
+		return TypeAnthropic
 	case "", "auto":
 		return TypeUnknown // Will trigger auto-detection
 	default:
@@ -28,6 +28,10 @@ func New(ctx context.Context, host, vendor, apiKey string) (Provider, error) {
 		return NewLlamaCppProvider(host, apiKey), nil
 	case TypeVLLM:
 		return NewVLLMProvider(host, apiKey), nil
+	case TypeOpenAI:
+		return NewOpenAIProvider(host, apiKey), nil
+	case TypeAnthropic:
+		return NewAnthropicProvider(host, apiKey), nil
 	default:
 		// Default to vLLM for unknown (most compatible)
 		return NewVLLMProvider(host, apiKey), nil
@@ -47,6 +51,10 @@ func NewWithType(providerType Type, host, apiKey string) (Provider, error) {
 		return NewLlamaCppProvider(host, apiKey), nil
 	case TypeVLLM:
 		return NewVLLMProvider(host, apiKey), nil
+	case TypeOpenAI:
+		return NewOpenAIProvider(host, apiKey), nil
+	case TypeAnthropic:
+		return NewAnthropicProvider(host, apiKey), nil
 	default:
 		return NewVLLMProvider(host, apiKey), nil
 	}
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/sashabaranov/go-openai"
 )
@@ -14,7 +15,7 @@ type LlamaCppProvider struct {
 // NewLlamaCppProvider creates a new llama.cpp provider
 func NewLlamaCppProvider(host, apiKey string) *LlamaCppProvider {
 	base := NewBaseProvider(TypeLlamaCpp, host, apiKey)
-	// llama.cpp doesn't support tool calling
+	// Native tool calls need --jinja and a capable model; probing decides
 	base.info.SupportsTools = false
 	return &LlamaCppProvider{BaseProvider: base}
 }
@@ -39,3 +40,14 @@ func (p *LlamaCppProvider) CreateClient() *openai.Client {
 func (p *LlamaCppProvider) SetModel(model string) {
 	p.BaseProvider.SetModel(model)
 }
+
+// CountTokens counts tokens with llama-server's /tokenize endpoint
+func (p *LlamaCppProvider) CountTokens(ctx context.Context, text string) (int, error) {
+	var resp struct {
+		Tokens []json.RawMessage `json:"tokens"`
+	}
+	if err := p.fetchJSON(ctx, "POST", "/tokenize", map[string]any{"content": text}, &resp); err != nil {
+		return 0, err
+	}
+	return len(resp.Tokens), nil
+}
@@ -17,7 +17,7 @@ type OllamaProvider struct {
 // NewOllamaProvider creates a new Ollama provider
 func NewOllamaProvider(host, apiKey string) *OllamaProvider {
 	base := NewBaseProvider(TypeOllama, host, apiKey)
-	// Ollama has limited tool calling support (depends on model)
+	// Native tool calls depend on the model; probing decides
 	base.info.SupportsTools = false
 	return &OllamaProvider{BaseProvider: base}
 }
@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAIProvider implements Provider for hosted OpenAI-compatible gateways
+// (OpenAI itself, LiteLLM, OpenRouter and similar proxies)
+type OpenAIProvider struct {
+	*BaseProvider
+}
+
+// NewOpenAIProvider creates a new OpenAI-compatible provider
+func NewOpenAIProvider(host, apiKey string) *OpenAIProvider {
+	base := NewBaseProvider(TypeOpenAI, host, apiKey)
+	base.info.SupportsTools = true // Hosted gateways expose native tool calling
+	return &OpenAIProvider{BaseProvider: base}
+}
+
+// Info returns provider metadata
+func (p *OpenAIProvider) Info() *Info {
+	return p.BaseProvider.Info()
+}
+
+// DetectModels queries available models from the gateway's /models endpoint
+func (p *OpenAIProvider) DetectModels(ctx context.Context) ([]string, error) {
+	return p.DetectModelsOpenAI(ctx)
+}
+
+// CreateClient returns an OpenAI-compatible client
+func (p *OpenAIProvider) CreateClient() *openai.Client {
+	return p.BaseProvider.CreateClient()
+}
+
+// SetModel sets the active model
+func (p *OpenAIProvider) SetModel(model string) {
+	p.BaseProvider.SetModel(model)
+}
@@ -0,0 +1,496 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestParseVendorConfig(t *testing.T) {
+	cases := map[string]Type{
+		"vllm":       TypeVLLM,
+		"Ollama":     TypeOllama,
+		"llama.cpp":  TypeLlamaCpp,
+		"openai":     TypeOpenAI,
+		"litellm":    TypeOpenAI,
+		"openrouter": TypeOpenAI,
+		"anthropic":  TypeAnthropic,
+		"":           TypeUnknown,
+		"auto":       TypeUnknown,
+	}
+	for input, want := range cases {
+		if got := ParseVendorConfig(input); got != want {
+			t.Errorf("ParseVendorConfig(%q) = %s, want %s", input, got, want)
+		}
+	}
+}
+
+func TestOpenAIProviderModelsWithV1InHost(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		if r.URL.Path != "/api/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"data":[{"id":"gpt-4o-mini"},{"id":"qwen/qwen3-30b"}]}`)
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider(server.URL+"/api/v1", "sk-test")
+	models, err := p.DetectModels(context.Background())
+	if err != nil {
+		t.Fatalf("DetectModels failed: %v", err)
+	}
+	if len(models) != 2 || models[1] != "qwen/qwen3-30b" {
+		t.Errorf("Unexpected models: %v", models)
+	}
+	if gotAuth != "Bearer sk-test" {
+		t.Errorf("Expected bearer auth, got %q", gotAuth)
+	}
+}
+
+func TestAnthropicProviderModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "ak-test" || r.Header.Get("anthropic-version") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Bearer auth should not be sent to Anthropic")
+		}
+		fmt.Fprint(w, `{"data":[{"id":"claude-sonnet-4-5","display_name":"Claude Sonnet"}]}`)
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider(server.URL, "ak-test")
+	models, err := p.DetectModels(context.Background())
+	if err != nil {
+		t.Fatalf("DetectModels failed: %v", err)
+	}
+	if len(models) != 1 || models[0] != "claude-sonnet-4-5" {
+		t.Errorf("Unexpected models: %v", models)
+	}
+}
+
+func TestAnthropicChatCompletion(t *testing.T) {
+	var got anthropicRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{
+			"id": "msg_1",
+			"model": "claude-test",
+			"content": [
+				{"type": "text", "text": "Reading it now."},
+				{"type": "tool_use", "id": "toolu_1", "name": "read_file", "input": {"file_path": "main.go"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 12, "output_tokens": 7}
+		}`)
+	}))
+	defer server.Close()
+
+	client := NewAnthropicProvider(server.URL, "ak-test").CreateClient()
+	resp, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model: "claude-test",
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "You are helpful."},
+			{Role: openai.ChatMessageRoleUser, Content: "Hello"},
+			{Role: openai.ChatMessageRoleUser, Content: "Read main.go"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion failed: %v", err)
+	}
+
+	if got.System != "You are helpful." {
+		t.Errorf("System prompt not hoisted: %q", got.System)
+	}
+	if len(got.Messages) != 1 || len(got.Messages[0].Content) != 2 {
+		t.Errorf("Consecutive user messages should be merged: %+v", got.Messages)
+	}
+	if got.MaxTokens != anthropicDefaultMaxTokens {
+		t.Errorf("Expected default max_tokens, got %d", got.MaxTokens)
+	}
+
+	choice := resp.Choices[0]
+	if choice.Message.Content != "Reading it now." {
+		t.Errorf("Unexpected content: %q", choice.Message.Content)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Name != "read_file" {
+		t.Errorf("Tool use not translated: %+v", choice.Message.ToolCalls)
+	}
+	if choice.FinishReason != openai.FinishReasonToolCalls {
+		t.Errorf("Unexpected finish reason: %s", choice.FinishReason)
+	}
+	if resp.Usage.TotalTokens != 19 {
+		t.Errorf("Unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicChatCompletionStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"message_start","message":{"id":"msg_2","model":"claude-test","usage":{"input_tokens":20}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, e := range events {
+			var typed struct {
+				Type string `json:"type"`
+			}
+			json.Unmarshal([]byte(e), &typed)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, e)
+		}
+	}))
+	defer server.Close()
+
+	client := NewAnthropicProvider(server.URL, "").CreateClient()
+	stream, err := client.CreateChatCompletionStream(context.Background(), openai.ChatCompletionRequest{
+		Model:         "claude-test",
+		Messages:      []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "Hi"}},
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	var usage *openai.Usage
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if len(chunk.Choices) > 0 {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+
+	if content.String() != "Hello" {
+		t.Errorf("Unexpected streamed content: %q", content.String())
+	}
+	if usage == nil || usage.PromptTokens != 20 || usage.CompletionTokens != 5 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+}
+
+func TestAnthropicErrorTranslation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens too large"}}`)
+	}))
+	defer server.Close()
+
+	client := NewAnthropicProvider(server.URL, "").CreateClient()
+	_, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:    "claude-test",
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "Hi"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "max_tokens too large") {
+		t.Errorf("Expected translated error message, got: %v", err)
+	}
+}
+
+func TestToAnthropicRequestToolResults(t *testing.T) {
+	req := toAnthropicRequest(openai.ChatCompletionRequest{
+		Model: "claude-test",
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleUser, Content: "List files"},
+			{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{{
+				ID:       "toolu_1",
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: "list_files", Arguments: `{"directory":"."}`},
+			}}},
+			{Role: openai.ChatMessageRoleTool, ToolCallID: "toolu_1", Content: "main.go"},
+		},
+	})
+
+	if len(req.Messages) != 3 {
+		t.Fatalf("Expected 3 alternating messages, got %d", len(req.Messages))
+	}
+	if req.Messages[1].Content[0].Type != "tool_use" {
+		t.Errorf("Expected tool_use block, got %+v", req.Messages[1].Content)
+	}
+	result := req.Messages[2]
+	if result.Role != "user" || result.Content[0].Type != "tool_result" || result.Content[0].ToolUseID != "toolu_1" {
+		t.Errorf("Expected tool_result block, got %+v", result)
+	}
+}
+
+func TestDetectWithReason(t *testing.T) {
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    Type
+	}{
+		{"ollama", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/tags" {
+				fmt.Fprint(w, `{"models":[{"name":"qwen3:8b"}]}`)
+				return
+			}
+			http.NotFound(w, r)
+		}, TypeOllama},
+		{"vllm", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v1/models" {
+				fmt.Fprint(w, `{"data":[{"id":"qwen","owned_by":"vllm"}]}`)
+				return
+			}
+			http.NotFound(w, r)
+		}, TypeVLLM},
+		{"llama.cpp props", func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v1/models":
+				fmt.Fprint(w, `{"data":[{"id":"model.gguf","owned_by":"me"}]}`)
+			case "/props":
+				fmt.Fprint(w, `{"default_generation_settings":{"n_ctx":8192}}`)
+			default:
+				http.NotFound(w, r)
+			}
+		}, TypeLlamaCpp},
+		{"catch-all 200 is not ollama", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<html>hello</html>`)
+		}, TypeUnknown},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+
+			got := DetectWithReason(context.Background(), server.URL)
+			if got.Type != tc.want {
+				t.Errorf("DetectWithReason = %s (%s), want %s", got.Type, got.Reason, tc.want)
+			}
+			if got.Reason == "" {
+				t.Errorf("Expected a detection reason")
+			}
+		})
+	}
+}
+
+func TestProbeCapabilities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/models" {
+			fmt.Fprint(w, `{"data":[{"id":"test-model","max_model_len":32768}]}`)
+			return
+		}
+		if r.URL.Path == "/v1/embeddings" {
+			fmt.Fprint(w, `{"data":[{"object":"embedding","index":0,"embedding":[0.5,-0.25,1]}]}`)
+			return
+		}
+
+		var req openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		switch {
+		case req.Stream:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"OK\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":1,\"total_tokens\":6}}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		case len(req.Tools) > 0:
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","tool_calls":[{"id":"1","type":"function","function":{"name":"get_time","arguments":"{}"}}]}}]}`)
+		case req.ResponseFormat != nil:
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"<think>ok</think>{\"ok\": true}"}}]}`)
+		case len(req.Messages[0].MultiContent) > 0:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"image input not supported"}}`)
+		}
+	}))
+	defer server.Close()
+
+	p := NewVLLMProvider(server.URL, "")
+	p.SetModel("test-model")
+	caps := Probe(context.Background(), p)
+
+	if !caps.NativeTools || !caps.StreamUsage || !caps.JSONMode || !caps.Embeddings {
+		t.Errorf("Expected tools, stream usage, JSON mode and embeddings: %+v", caps)
+	}
+	if caps.Vision {
+		t.Errorf("Vision should be unsupported")
+	}
+	if caps.MaxContext != 32768 {
+		t.Errorf("Expected max context 32768, got %d", caps.MaxContext)
+	}
+	if len(caps.Checks) != 6 {
+		t.Errorf("Expected 6 checks, got %d", len(caps.Checks))
+	}
+}
+
+func TestCapabilityCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	caps := &Capabilities{Host: "http://gpu:8000/", Model: "m", StreamUsage: true, ProbedAt: time.Now()}
+	if err := SaveCapabilities(caps); err != nil {
+		t.Fatalf("SaveCapabilities failed: %v", err)
+	}
+
+	got := LoadCachedCapabilities("http://gpu:8000", "m")
+	if got == nil || !got.StreamUsage {
+		t.Fatalf("Expected cached capabilities, got %+v", got)
+	}
+	if LoadCachedCapabilities("http://gpu:8000", "other") != nil {
+		t.Errorf("Expected no entry for another model")
+	}
+
+	caps.Model = "stale"
+	caps.ProbedAt = time.Now().Add(-capabilityCacheTTL - time.Hour)
+	SaveCapabilities(caps)
+	if LoadCachedCapabilities("http://gpu:8000/", "stale") != nil {
+		t.Errorf("Expected expired entry to be ignored")
+	}
+}
+
+func TestChatTransportExtraBodyAndReasoning(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Done.","reasoning_content":"plan it"}}]}`)
+	}))
+	defer server.Close()
+
+	client := NewVLLMProvider(server.URL, "").CreateClient()
+	ctx := WithExtraBody(context.Background(), map[string]any{
+		"chat_template_kwargs": map[string]any{"enable_thinking": false},
+	})
+	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:    "qwen3",
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion failed: %v", err)
+	}
+
+	kwargs, _ := got["chat_template_kwargs"].(map[string]any)
+	if kwargs == nil || kwargs["enable_thinking"] != false {
+		t.Errorf("Extra body not injected: %v", got)
+	}
+	if got["model"] != "qwen3" {
+		t.Errorf("Original fields lost: %v", got)
+	}
+	if content := resp.Choices[0].Message.Content; content != "<think>plan it</think>Done." {
+		t.Errorf("Reasoning not folded into content: %q", content)
+	}
+}
+
+func TestChatTransportStreamReasoning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{
+			`{"reasoning_content":"plan"}`,
+			`{"reasoning_content":" it"}`,
+			`{"content":"Done"}`,
+			`{"content":"."}`,
+		} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":%s}]}\n\n", delta)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := NewLlamaCppProvider(server.URL, "").CreateClient()
+	stream, err := client.CreateChatCompletionStream(context.Background(), openai.ChatCompletionRequest{
+		Model:    "qwen3",
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	if content.String() != "<think>plan it</think>Done." {
+		t.Errorf("Unexpected streamed content: %q", content.String())
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tokenize" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		if _, ok := gotBody["content"]; ok {
+			fmt.Fprint(w, `{"tokens":[9707,11,1879]}`)
+			return
+		}
+		fmt.Fprint(w, `{"count":3,"max_model_len":32768,"tokens":[9707,11,1879]}`)
+	}))
+	defer server.Close()
+
+	vllm := NewVLLMProvider(server.URL+"/v1", "")
+	vllm.SetModel("qwen3")
+	if n, err := vllm.CountTokens(context.Background(), "Hello, world"); err != nil || n != 3 {
+		t.Errorf("vLLM CountTokens = (%d, %v), want 3", n, err)
+	}
+	if gotBody["model"] != "qwen3" || gotBody["prompt"] != "Hello, world" {
+		t.Errorf("Unexpected vLLM tokenize body: %v", gotBody)
+	}
+
+	llama := NewLlamaCppProvider(server.URL+"/v1", "")
+	if n, err := llama.CountTokens(context.Background(), "Hello, world"); err != nil || n != 3 {
+		t.Errorf("llama.cpp CountTokens = (%d, %v), want 3", n, err)
+	}
+}
+
+func TestTraceHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	// Any status counts as reachable; an IP literal needs no DNS lookup
+	timing, err := TraceHost(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing.Status != http.StatusNotFound || timing.RemoteAddr != server.Listener.Addr().String() {
+		t.Errorf("timing = %+v", timing)
+	}
+	if timing.DNS != 0 || timing.TLS != 0 || timing.FirstByte <= 0 {
+		t.Errorf("phases = DNS %v, TLS %v, first byte %v", timing.DNS, timing.TLS, timing.FirstByte)
+	}
+
+	server.Close()
+	if _, err := TraceHost(context.Background(), server.URL); err == nil {
+		t.Error("closed server reported reachable")
+	}
+}
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// ConnectionTiming breaks down how long each phase of reaching the host took
+type ConnectionTiming struct {
+	Addresses  []string      // Resolved IP addresses
+	RemoteAddr string        // Address actually connected to
+	DNS        time.Duration // Zero when the host is an IP literal
+	Connect    time.Duration
+	TLS        time.Duration // Zero for plain HTTP
+	TLSVersion string
+	FirstByte  time.Duration // From request start to first response byte
+	Status     int
+}
+
+// TraceHost issues a GET against host and records DNS, TCP, TLS and
+// time-to-first-byte timings. Any HTTP status counts as reachable.
+func TraceHost(ctx context.Context, host string) (*ConnectionTiming, error) {
+	timing := &ConnectionTiming{}
+	var dnsStart, connectStart, tlsStart time.Time
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			timing.DNS = time.Since(dnsStart)
+			for _, addr := range info.Addrs {
+				timing.Addresses = append(timing.Addresses, addr.String())
+			}
+		},
+		ConnectStart: func(network, addr string) { connectStart = time.Now() },
+		ConnectDone: func(network, addr string, err error) {
+			timing.Connect = time.Since(connectStart)
+			timing.RemoteAddr = addr
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			timing.TLS = time.Since(tlsStart)
+			timing.TLSVersion = tls.VersionName(state.Version)
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), "GET", host, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host URL: %w", err)
+	}
+
+	// A fresh transport guarantees a new connection so every phase is measured
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{DisableKeepAlives: true},
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return timing, err
+	}
+	defer resp.Body.Close()
+
+	timing.FirstByte = time.Since(start)
+	timing.Status = resp.StatusCode
+	return timing, nil
+}
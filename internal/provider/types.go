@@ -10,10 +10,12 @@ import (
 type Type string
 
 const (
-	TypeVLLM     Type = "vllm"
-	TypeOllama   Type = "ollama"
-	TypeLlamaCpp Type = "llama.cpp"
-	TypeUnknown  Type = "unknown"
+	TypeVLLM      Type = "vllm"
+	TypeOllama    Type = "ollama"
+	TypeLlamaCpp  Type = "llama.cpp"
+	TypeOpenAI    Type = "openai"
+	TypeAnthropic Type = "anthropic"
+	TypeUnknown   Type = "unknown"
 )
 
 // String returns the string representation of the provider type
@@ -30,6 +32,10 @@ func (t Type) DisplayName() string {
 		return "Ollama"
 	case TypeLlamaCpp:
 		return "llama.cpp"
+	case TypeOpenAI:
+		return "OpenAI-compatible"
+	case TypeAnthropic:
+		return "Anthropic"
 	default:
 		return "Unknown"
 	}
@@ -37,13 +43,15 @@ func (t Type) DisplayName() string {
 
 // Info holds provider metadata
 type Info struct {
-	Type          Type     // Provider type (vllm, ollama, llama.cpp)
+	Type          Type     // Provider type (vllm, ollama, llama.cpp, openai, anthropic)
 	Name          string   // Display name (e.g., "Ollama")
 	Host          string   // Base URL
 	Model         string   // Selected model
 	Models        []string // Available models
 	APIPath       string   // API path prefix (e.g., "/v1")
-	SupportsTools bool     // Whether provider supports tool calling
+	SupportsTools bool     // Whether the model returns native tool calls, as probed
+
+	Capabilities *Capabilities // Probed endpoint capabilities (nil until probed)
 }
 
 // Provider interface for LLM operations
@@ -60,3 +68,9 @@ type Provider interface {
 	// SetModel sets the active model
 	SetModel(model string)
 }
+
+// Tokenizer is implemented by providers whose server can count tokens
+// for the active model (vLLM and llama.cpp expose /tokenize)
+type Tokenizer interface {
+	CountTokens(ctx context.Context, text string) (int, error)
+}
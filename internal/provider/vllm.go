@@ -37,3 +37,19 @@ func (p *VLLMProvider) CreateClient() *openai.Client {
 func (p *VLLMProvider) SetModel(model string) {
 	p.BaseProvider.SetModel(model)
 }
+
+// CountTokens counts tokens with the server's /tokenize endpoint
+func (p *VLLMProvider) CountTokens(ctx context.Context, text string) (int, error) {
+	var resp struct {
+		Count  int   `json:"count"`
+		Tokens []int `json:"tokens"`
+	}
+	body := map[string]any{"model": p.info.Model, "prompt": text, "add_special_tokens": false}
+	if err := p.fetchJSON(ctx, "POST", "/tokenize", body, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Count > 0 {
+		return resp.Count, nil
+	}
+	return len(resp.Tokens), nil
+}
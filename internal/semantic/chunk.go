@@ -0,0 +1,140 @@
+package semantic
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tara-vision/taracode/internal/gocode"
+)
+
+const (
+	windowLines   = 40  // Lines per chunk of files chunked by lines
+	maxChunkLines = 120 // Longer Go declarations are split into windows
+)
+
+// chunk is a range of a file with the text embedded for it
+type chunk struct {
+	start, end int
+	name       string
+	text       string
+}
+
+// chunkFile splits a file into chunks: Go files by top-level declaration,
+// with their doc comments, and other files by fixed windows of lines. Go
+// files that do not parse are chunked by lines.
+func chunkFile(path string, src []byte) []chunk {
+	lines := strings.Split(strings.TrimRight(string(src), "\n"), "\n")
+	var chunks []chunk
+	if strings.HasSuffix(path, ".go") {
+		chunks = goChunks(path, src, lines)
+	}
+	if chunks == nil {
+		chunks = lineChunks(path, lines, 1, len(lines), "")
+	}
+	if len(chunks) > maxFileChunks {
+		chunks = chunks[:maxFileChunks]
+	}
+	return chunks
+}
+
+// goChunks makes a chunk of each top-level declaration other than imports,
+// or nil when the file does not parse
+func goChunks(path string, src []byte, lines []string) []chunk {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	var chunks []chunk
+	prevEnd := 0
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			continue
+		}
+		start, end := fset.Position(decl.Pos()).Line, fset.Position(decl.End()).Line
+		// Take in the doc comment and any comment lines just above
+		for start-1 > prevEnd && strings.HasPrefix(strings.TrimSpace(lines[start-2]), "//") {
+			start--
+		}
+		prevEnd = end
+		chunks = append(chunks, lineChunks(path, lines, start, end, declName(decl))...)
+	}
+	if chunks == nil {
+		// Only a package clause and imports
+		return []chunk{}
+	}
+	return chunks
+}
+
+// declName names a declaration as go_symbols does, joining the names of a
+// group
+func declName(decl ast.Decl) string {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Recv != nil && len(decl.Recv.List) > 0 {
+			return gocode.ReceiverName(decl.Recv.List[0].Type) + "." + decl.Name.Name
+		}
+		return decl.Name.Name
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, spec.Name.Name)
+			case *ast.ValueSpec:
+				for _, ident := range spec.Names {
+					if ident.Name != "_" {
+						names = append(names, ident.Name)
+					}
+				}
+			}
+		}
+		if len(names) > 4 {
+			names = append(names[:4], "...")
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// lineChunks splits lines start to end (1-based, inclusive) into windows,
+// dropping windows with nothing but blank lines. Declarations longer than
+// maxChunkLines are split too, keeping their name.
+func lineChunks(path string, lines []string, start, end int, name string) []chunk {
+	size := windowLines
+	if name != "" {
+		size = maxChunkLines
+	}
+	var chunks []chunk
+	for from := start; from <= end; from += size {
+		to := min(from+size-1, end)
+		body := strings.Join(lines[from-1:to], "\n")
+		if strings.TrimSpace(body) == "" {
+			continue
+		}
+		chunks = append(chunks, chunk{start: from, end: to, name: name, text: embedText(path, name, body)})
+	}
+	return chunks
+}
+
+// embedText is what is embedded for a chunk: its file and name, which
+// carry meaning of their own, then its source
+func embedText(path, name, body string) string {
+	header := path
+	if name != "" {
+		header += " " + name
+	}
+	text := header + "\n" + body
+	if len(text) > maxEmbedChars {
+		cut := maxEmbedChars
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+	return text
+}
@@ -0,0 +1,51 @@
+package semantic
+
+import (
+	"context"
+	"fmt"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Embedder turns texts into vectors
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Model() string
+}
+
+// clientEmbedder embeds through an OpenAI-compatible /v1/embeddings endpoint
+type clientEmbedder struct {
+	client *openai.Client
+	model  string
+}
+
+// NewEmbedder returns an Embedder using the server behind client
+func NewEmbedder(client *openai.Client, model string) Embedder {
+	return &clientEmbedder{client: client, model: model}
+}
+
+func (e *clientEmbedder) Model() string {
+	return e.model
+}
+
+func (e *clientEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: texts,
+		Model: openai.EmbeddingModel(e.model),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed (set embedding_model in config to an embedding model the server serves): %w", err)
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding request returned %d vectors for %d texts", len(resp.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding response has out-of-range index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,362 @@
+// Package semantic keeps an embedding index of a project's source files
+// for searching code by meaning rather than by text
+package semantic
+
+import (
+	"bytes"
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tara-vision/taracode/internal/ignore"
+)
+
+// IndexFile is where the index is kept, relative to the project root
+const IndexFile = ".taracode/context/semantic-index.json"
+
+const (
+	maxFileSize   = 256 * 1024 // Larger files are generated or data, not code to search
+	maxFileChunks = 200        // Chunks kept per file
+	batchSize     = 32         // Chunks per embedding request
+	maxEmbedChars = 6000       // Text of a chunk sent for embedding
+)
+
+// Vector is an embedding, stored as base64 little-endian float32s to keep
+// the index file compact
+type Vector []float32
+
+func (v Vector) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(buf))
+}
+
+func (v *Vector) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	buf, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(buf)%4 != 0 {
+		return fmt.Errorf("vector has %d bytes, not a multiple of 4", len(buf))
+	}
+	*v = make(Vector, len(buf)/4)
+	for i := range *v {
+		(*v)[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return nil
+}
+
+// Chunk is an indexed range of lines of a file
+type Chunk struct {
+	StartLine int    `json:"start"`
+	EndLine   int    `json:"end"`
+	Name      string `json:"name,omitempty"` // Declaration name for Go chunks
+	Vector    Vector `json:"vector"`
+}
+
+// FileEntry is an indexed file. ModTime and Size tell whether it may have
+// changed; Hash tells whether it did.
+type FileEntry struct {
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+	Hash    string    `json:"hash"`
+	Chunks  []Chunk   `json:"chunks"`
+}
+
+// Index maps project files, by slash-separated relative path, to their
+// embedded chunks
+type Index struct {
+	Model string                `json:"model"`
+	Files map[string]*FileEntry `json:"files"`
+
+	root string
+}
+
+// Load reads the index of the project at root. A missing or unreadable
+// index is empty: it is a cache, rebuilt by Update.
+func Load(root string) *Index {
+	ix := &Index{Files: make(map[string]*FileEntry), root: root}
+	data, err := os.ReadFile(filepath.Join(root, IndexFile))
+	if err != nil {
+		return ix
+	}
+	if err := json.Unmarshal(data, ix); err != nil || ix.Files == nil {
+		return &Index{Files: make(map[string]*FileEntry), root: root}
+	}
+	return ix
+}
+
+// Save writes the index to the project
+func (ix *Index) Save() error {
+	path := filepath.Join(ix.root, IndexFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Chunks returns the number of indexed chunks
+func (ix *Index) Chunks() int {
+	n := 0
+	for _, entry := range ix.Files {
+		n += len(entry.Chunks)
+	}
+	return n
+}
+
+// UpdateStats says what an update did
+type UpdateStats struct {
+	Indexed int // Files embedded, new or changed
+	Removed int // Files no longer in the project
+	Chunks  int // Chunks embedded
+}
+
+// pendingFile is a new or changed file waiting for its embeddings
+type pendingFile struct {
+	path   string
+	entry  *FileEntry
+	texts  []string
+	vector int // Chunks embedded so far
+}
+
+// Update brings the index up to date with the project: files whose size
+// and modification time are unchanged are skipped, and files whose content
+// hash is unchanged are not embedded again. The index is saved, including
+// the files finished before an embedding error. progress, when set,
+// receives the embedding progress.
+func (ix *Index) Update(ctx gocontext.Context, embedder Embedder, progress func(status string)) (UpdateStats, error) {
+	var stats UpdateStats
+	changed := false
+	if ix.Model != embedder.Model() {
+		// Vectors of different models cannot be compared
+		ix.Model = embedder.Model()
+		ix.Files = make(map[string]*FileEntry)
+		changed = true
+	}
+
+	seen := make(map[string]bool)
+	var pending []*pendingFile
+	total := 0
+	err := walkSource(ix.root, func(path string, info fs.FileInfo) error {
+		seen[path] = true
+		old := ix.Files[path]
+		if old != nil && old.Size == info.Size() && old.ModTime.Equal(info.ModTime()) {
+			return nil
+		}
+		changed = true
+		src, err := os.ReadFile(filepath.Join(ix.root, filepath.FromSlash(path)))
+		if err != nil || isBinary(src) {
+			delete(ix.Files, path)
+			return nil
+		}
+		sum := sha256.Sum256(src)
+		entry := &FileEntry{ModTime: info.ModTime(), Size: info.Size(), Hash: hex.EncodeToString(sum[:])}
+		if old != nil && old.Hash == entry.Hash {
+			entry.Chunks = old.Chunks
+			ix.Files[path] = entry
+			return nil
+		}
+
+		p := &pendingFile{path: path, entry: entry}
+		for _, c := range chunkFile(path, src) {
+			entry.Chunks = append(entry.Chunks, Chunk{StartLine: c.start, EndLine: c.end, Name: c.name})
+			p.texts = append(p.texts, c.text)
+		}
+		if len(p.texts) == 0 {
+			ix.Files[path] = entry
+			return nil
+		}
+		pending = append(pending, p)
+		total += len(p.texts)
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	for path := range ix.Files {
+		if !seen[path] {
+			delete(ix.Files, path)
+			stats.Removed++
+		}
+	}
+
+	// Embed in batches that may span files; a file joins the index once
+	// all of its chunks are embedded
+	var batch []string
+	var owners []*pendingFile
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		vectors, err := embedder.Embed(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for i, v := range vectors {
+			p := owners[i]
+			p.entry.Chunks[p.vector].Vector = v
+			p.vector++
+			if p.vector == len(p.texts) {
+				ix.Files[p.path] = p.entry
+				stats.Indexed++
+			}
+		}
+		stats.Chunks += len(batch)
+		batch, owners = batch[:0], owners[:0]
+		if progress != nil {
+			progress(fmt.Sprintf("indexing %d/%d chunks", stats.Chunks, total))
+		}
+		return nil
+	}
+	for _, p := range pending {
+		delete(ix.Files, p.path)
+		for _, text := range p.texts {
+			batch = append(batch, text)
+			owners = append(owners, p)
+			if len(batch) == batchSize {
+				if err := flush(); err != nil {
+					ix.Save()
+					return stats, err
+				}
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		ix.Save()
+		return stats, err
+	}
+
+	if changed || stats.Removed > 0 {
+		if err := ix.Save(); err != nil {
+			return stats, fmt.Errorf("failed to save index: %w", err)
+		}
+	}
+	return stats, nil
+}
+
+// Result is a chunk matching a query
+type Result struct {
+	File      string
+	StartLine int
+	EndLine   int
+	Name      string
+	Score     float64 // Cosine similarity to the query
+}
+
+// Search returns the limit chunks closest to query, best first, or every
+// chunk when limit is 0
+func (ix *Index) Search(ctx gocontext.Context, embedder Embedder, query string, limit int) ([]Result, error) {
+	vectors, err := embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	q := vectors[0]
+
+	var results []Result
+	for path, entry := range ix.Files {
+		for _, c := range entry.Chunks {
+			results = append(results, Result{
+				File:      path,
+				StartLine: c.StartLine,
+				EndLine:   c.EndLine,
+				Name:      c.Name,
+				Score:     cosine(q, c.Vector),
+			})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].File != results[j].File {
+			return results[i].File < results[j].File
+		}
+		return results[i].StartLine < results[j].StartLine
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// walkSource calls fn for each source file under root, by relative path,
+// skipping hidden files, whatever the project's ignore rules leave out,
+// excluded file patterns and large files
+func walkSource(root string, fn func(path string, info fs.FileInfo) error) error {
+	return ignore.New(root).Walk(root, func(path string, d fs.DirEntry, err error) error {
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(name, ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(name, ".") {
+			return nil
+		}
+		for _, pattern := range ignore.DefaultExcludePatterns {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				return nil
+			}
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() == 0 || info.Size() > maxFileSize {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		return fn(filepath.ToSlash(rel), info)
+	})
+}
+
+// isBinary reports whether content looks like a binary file
+func isBinary(src []byte) bool {
+	if len(src) > 8000 {
+		src = src[:8000]
+	}
+	return bytes.IndexByte(src, 0) >= 0
+}
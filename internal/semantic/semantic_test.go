@@ -0,0 +1,164 @@
+package semantic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tara-vision/taracode/internal/llmtest"
+	"github.com/tara-vision/taracode/internal/provider"
+)
+
+const sessionGo = `package auth
+
+import "time"
+
+// Session is a signed-in user
+type Session struct {
+	User    string
+	Expires time.Time
+}
+
+// Expired reports whether the session has run out
+// and must be renewed
+func (s *Session) Expired(now time.Time) bool {
+	return now.After(s.Expires)
+}
+
+var (
+	// Lifetime is how long a session lasts
+	Lifetime = 24 * time.Hour
+	maxUsers = 10
+)
+`
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testEmbedder(t *testing.T) (*llmtest.Server, Embedder) {
+	server := llmtest.New(t)
+	return server, NewEmbedder(provider.NewVLLMProvider(server.URL, "").CreateClient(), "test-embed")
+}
+
+func TestChunkFile(t *testing.T) {
+	chunks := chunkFile("auth/session.go", []byte(sessionGo))
+	var got []string
+	for _, c := range chunks {
+		got = append(got, fmt.Sprintf("%s %d-%d", c.name, c.start, c.end))
+	}
+	want := []string{"Session 5-9", "Session.Expired 11-15", "Lifetime, maxUsers 17-21"}
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("Go chunks = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(chunks[1].text, "auth/session.go Session.Expired\n// Expired reports") {
+		t.Errorf("chunk text = %q", chunks[1].text)
+	}
+
+	// Other files, and Go that does not parse, are chunked by lines
+	text := strings.Repeat("line\n", 90)
+	chunks = chunkFile("notes.md", []byte(text))
+	if len(chunks) != 3 || chunks[1].start != 41 || chunks[1].end != 80 || chunks[2].end != 90 {
+		t.Errorf("line chunks = %+v", chunks)
+	}
+	if chunks = chunkFile("broken.go", []byte("package x\nfunc {\n")); len(chunks) != 1 || chunks[0].end != 2 {
+		t.Errorf("broken Go chunks = %+v", chunks)
+	}
+}
+
+func TestUpdateIsIncremental(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "auth/session.go", sessionGo)
+	writeFile(t, dir, "README.md", "# Auth\n\nSessions expire after a day.\n")
+	writeFile(t, dir, "node_modules/x/index.js", "module.exports = 1\n")
+	writeFile(t, dir, "logo.png", "\x89PNG\r\n\x1a\n\x00\x00")
+	server, embedder := testEmbedder(t)
+	ctx := context.Background()
+
+	ix := Load(dir)
+	stats, err := ix.Update(ctx, embedder, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Indexed != 2 || stats.Chunks != 4 || len(ix.Files) != 2 {
+		t.Fatalf("first update = %+v, files %v", stats, ix.Files)
+	}
+	embedded := len(server.Embedded())
+
+	// Reloaded from disk, nothing is embedded again, even for a file that
+	// was touched without being changed
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "README.md"), later, later)
+	ix = Load(dir)
+	if ix.Chunks() != 4 {
+		t.Fatalf("reloaded index has %d chunks", ix.Chunks())
+	}
+	if stats, err = ix.Update(ctx, embedder, nil); err != nil || stats.Chunks != 0 {
+		t.Fatalf("second update = %+v, %v", stats, err)
+	}
+	if !ix.Files["README.md"].ModTime.Equal(later) {
+		t.Error("touched file's modification time was not updated")
+	}
+
+	// A changed file is embedded again and a deleted one removed
+	writeFile(t, dir, "auth/session.go", strings.Replace(sessionGo, "24 * time.Hour", "12 * time.Hour", 1))
+	os.Remove(filepath.Join(dir, "README.md"))
+	var progress []string
+	stats, err = ix.Update(ctx, embedder, func(status string) { progress = append(progress, status) })
+	if err != nil || stats.Indexed != 1 || stats.Removed != 1 || stats.Chunks != 3 {
+		t.Fatalf("third update = %+v, %v", stats, err)
+	}
+	if got := server.Embedded()[embedded:]; len(got) != 3 || !strings.HasPrefix(got[0], "auth/session.go") {
+		t.Errorf("embedded again = %q", got)
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != "indexing 3/3 chunks" {
+		t.Errorf("progress = %q", progress)
+	}
+
+	// Another model's vectors are not reused
+	other := NewEmbedder(provider.NewVLLMProvider(server.URL, "").CreateClient(), "other-embed")
+	if stats, err = Load(dir).Update(ctx, other, nil); err != nil || stats.Chunks != 3 {
+		t.Errorf("update with another model = %+v, %v", stats, err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "auth/session.go", sessionGo)
+	writeFile(t, dir, "server/routes.go", `package server
+
+// Routes registers the HTTP handlers
+func Routes(mux Mux) {
+	mux.Handle("/users", listUsers)
+}
+`)
+	_, embedder := testEmbedder(t)
+	ctx := context.Background()
+
+	ix := Load(dir)
+	if _, err := ix.Update(ctx, embedder, nil); err != nil {
+		t.Fatal(err)
+	}
+	results, err := ix.Search(ctx, embedder, "when has the session expired", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Name != "Session.Expired" || results[0].File != "auth/session.go" || results[0].StartLine != 11 {
+		t.Fatalf("results = %+v", results)
+	}
+	if results[0].Score <= results[1].Score {
+		t.Errorf("results not ranked: %+v", results)
+	}
+	if results, _ = ix.Search(ctx, embedder, "register HTTP handlers", 1); len(results) != 1 || results[0].Name != "Routes" {
+		t.Errorf("results = %+v", results)
+	}
+}
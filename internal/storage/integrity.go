@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IntegrityIssue describes a problem found in the .taracode directory
+type IntegrityIssue struct {
+	Path    string // Path relative to the project root
+	Problem string // What is wrong
+	Fix     string // How to fix it
+}
+
+// CheckIntegrity inspects the .taracode directory under projectRoot. It
+// leaves the project's files alone, but tests that each subdirectory is
+// writable by creating and removing a temporary probe file in it. A missing
+// directory is not an issue; it is created on first run.
+func CheckIntegrity(projectRoot string) []IntegrityIssue {
+	rootDir := filepath.Join(projectRoot, ".taracode")
+	if _, err := os.Stat(rootDir); os.IsNotExist(err) {
+		return nil
+	}
+
+	var issues []IntegrityIssue
+	rel := func(path string) string {
+		if r, err := filepath.Rel(projectRoot, path); err == nil {
+			return r
+		}
+		return path
+	}
+
+	// Directory layout and writability
+	for _, dir := range []string{"context", "history", "plans", "state"} {
+		path := filepath.Join(rootDir, dir)
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			issues = append(issues, IntegrityIssue{
+				Path:    rel(path),
+				Problem: "directory is missing",
+				Fix:     "run taracode once to recreate it",
+			})
+			continue
+		}
+		probe, err := os.CreateTemp(path, ".doctor-*")
+		if err != nil {
+			issues = append(issues, IntegrityIssue{
+				Path:    rel(path),
+				Problem: "directory is not writable",
+				Fix:     fmt.Sprintf("check permissions: chmod u+w %s", rel(path)),
+			})
+			continue
+		}
+		probe.Close()
+		os.Remove(probe.Name())
+	}
+
+	// JSON documents must parse into their expected types
+	documents := map[string]interface{}{
+		filepath.Join("history", "sessions.json"):  &SessionIndex{},
+		filepath.Join("state", "current.json"):     &CurrentState{},
+		filepath.Join("state", "preferences.json"): &Preferences{},
+		filepath.Join("plans", "active.json"):      &Plan{},
+		"memory.json":                              &memoryFile{},
+	}
+	var index *SessionIndex
+	for name, target := range documents {
+		path := filepath.Join(rootDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err := json.Unmarshal(data, target); err != nil {
+			fix := fmt.Sprintf("fix or delete %s (it is rebuilt with defaults)", rel(path))
+			if _, ok := target.(*SessionIndex); ok {
+				fix = fmt.Sprintf("fix %s, or delete it and run taracode once to rebuild it from the session files", rel(path))
+			}
+			issues = append(issues, IntegrityIssue{
+				Path:    rel(path),
+				Problem: fmt.Sprintf("invalid JSON: %v", err),
+				Fix:     fix,
+			})
+			continue
+		}
+		if idx, ok := target.(*SessionIndex); ok {
+			index = idx
+		}
+	}
+
+	// Session files and the session index must agree. Without a readable
+	// index there is nothing to compare them with.
+	files, _ := filepath.Glob(filepath.Join(rootDir, "history", "session_*.json"))
+	indexPath := filepath.Join(rootDir, "history", "sessions.json")
+	if _, err := os.Stat(indexPath); os.IsNotExist(err) && len(files) > 0 {
+		issues = append(issues, IntegrityIssue{
+			Path:    rel(indexPath),
+			Problem: fmt.Sprintf("session index is missing (%d session files)", len(files)),
+			Fix:     "run taracode once to rebuild it from the session files",
+		})
+	}
+	if index != nil {
+		indexed := make(map[string]bool)
+		for _, meta := range index.Sessions {
+			indexed[meta.ID] = true
+			path := filepath.Join(rootDir, "history", fmt.Sprintf("session_%s.json", meta.ID))
+			data, err := os.ReadFile(path)
+			if err != nil {
+				issues = append(issues, IntegrityIssue{
+					Path:    rel(path),
+					Problem: "listed in sessions.json but the file is missing",
+					Fix:     "remove the entry from .taracode/history/sessions.json",
+				})
+				continue
+			}
+			var session Session
+			if err := json.Unmarshal(data, &session); err != nil {
+				issues = append(issues, IntegrityIssue{
+					Path:    rel(path),
+					Problem: fmt.Sprintf("invalid JSON: %v", err),
+					Fix:     fmt.Sprintf("delete %s and its sessions.json entry", rel(path)),
+				})
+			}
+		}
+		if index.ActiveSessionID != "" && !indexed[index.ActiveSessionID] {
+			issues = append(issues, IntegrityIssue{
+				Path:    rel(indexPath),
+				Problem: "active session is not in the session list",
+				Fix:     "start a new session with /session new",
+			})
+		}
+
+		for _, path := range files {
+			id := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "session_"), ".json")
+			if !indexed[id] {
+				issues = append(issues, IntegrityIssue{
+					Path:    rel(path),
+					Problem: "session file is not listed in sessions.json",
+					Fix:     fmt.Sprintf("delete %s and run taracode once to rebuild it, which lists every session file", rel(indexPath)),
+				})
+			}
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// problems maps the paths CheckIntegrity reports to their problems
+func problems(issues []IntegrityIssue) map[string]string {
+	found := make(map[string]string)
+	for _, issue := range issues {
+		found[filepath.ToSlash(issue.Path)] = issue.Problem
+	}
+	return found
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	if issues := CheckIntegrity(dir); issues != nil {
+		t.Fatalf("missing .taracode reported: %+v", issues)
+	}
+
+	m, err := NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, _ := m.CreateSession("kept")
+	gone, _ := m.CreateSession("gone")
+	if issues := CheckIntegrity(dir); len(issues) != 0 {
+		t.Fatalf("fresh .taracode reported: %+v", issues)
+	}
+
+	history := filepath.Join(dir, ".taracode", "history")
+	os.Remove(filepath.Join(history, "session_"+gone.ID+".json"))
+	os.WriteFile(filepath.Join(history, "session_stray.json"), []byte(`{"id": "stray"}`), 0644)
+	os.WriteFile(filepath.Join(dir, ".taracode", "state", "preferences.json"), []byte("{not json"), 0644)
+
+	found := problems(CheckIntegrity(dir))
+	if len(found) != 3 {
+		t.Errorf("got %d issues, want 3: %v", len(found), found)
+	}
+	if p := found[".taracode/history/session_"+gone.ID+".json"]; !strings.Contains(p, "file is missing") {
+		t.Errorf("listed but missing session = %q", p)
+	}
+	if p := found[".taracode/history/session_stray.json"]; !strings.Contains(p, "not listed") {
+		t.Errorf("unlisted session = %q", p)
+	}
+	if p := found[".taracode/state/preferences.json"]; !strings.HasPrefix(p, "invalid JSON") {
+		t.Errorf("invalid preferences = %q", p)
+	}
+	if _, ok := found[".taracode/history/session_"+kept.ID+".json"]; ok {
+		t.Error("intact session reported")
+	}
+}
+
+func TestCheckIntegrityBrokenIndex(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := m.CreateSession("first")
+	second, _ := m.CreateSession("second")
+	indexPath := filepath.Join(dir, ".taracode", "history", "sessions.json")
+
+	// A broken index is reported once, and the sessions it no longer lists
+	// are not offered up for deletion
+	for name, content := range map[string]string{"corrupt": "{not json", "missing": ""} {
+		if content == "" {
+			os.Remove(indexPath)
+		} else {
+			os.WriteFile(indexPath, []byte(content), 0644)
+		}
+		issues := CheckIntegrity(dir)
+		if len(issues) != 1 || filepath.ToSlash(issues[0].Path) != ".taracode/history/sessions.json" {
+			t.Fatalf("%s index: issues = %+v", name, issues)
+		}
+		if !strings.Contains(issues[0].Fix, "rebuild") {
+			t.Errorf("%s index: fix = %q", name, issues[0].Fix)
+		}
+	}
+
+	// The next run rebuilds it from the session files
+	m, err = NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, _ := m.ListSessions()
+	if len(sessions) != 2 || sessions[0].ID != first.ID || sessions[1].ID != second.ID {
+		t.Errorf("rebuilt index = %+v", sessions)
+	}
+	if issues := CheckIntegrity(dir); len(issues) != 0 {
+		t.Errorf("issues after rebuild: %+v", issues)
+	}
+}
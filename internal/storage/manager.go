@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -68,11 +69,14 @@ func (m *Manager) ensureDirectories() error {
 }
 
 func (m *Manager) loadAll() error {
-	// Load session index
+	// Load session index, rebuilding it from the session files when it is
+	// missing or corrupt
 	m.sessionIndex = &SessionIndex{}
 	indexPath := filepath.Join(m.rootDir, "history", "sessions.json")
-	if data, err := os.ReadFile(indexPath); err == nil {
-		json.Unmarshal(data, m.sessionIndex)
+	if data, err := os.ReadFile(indexPath); err != nil || json.Unmarshal(data, m.sessionIndex) != nil {
+		if err := m.rebuildSessionIndex(); err != nil {
+			return err
+		}
 	}
 
 	// Load current state
@@ -184,6 +188,14 @@ func (m *Manager) AddMessage(sessionID string, msg ConversationMessage) error {
 	session.Messages = append(session.Messages, msg)
 	session.UpdatedAt = time.Now()
 
+	// Roll usage up before history trimming can drop the message
+	if msg.Usage != nil {
+		if session.TotalUsage == nil {
+			session.TotalUsage = &TokenUsage{}
+		}
+		session.TotalUsage.Add(msg.Usage)
+	}
+
 	// Trim if exceeding max history
 	if m.preferences.MaxHistoryLength > 0 && len(session.Messages) > m.preferences.MaxHistoryLength {
 		session.Messages = session.Messages[len(session.Messages)-m.preferences.MaxHistoryLength:]
@@ -239,6 +251,38 @@ func (m *Manager) saveSession(session *Session) error {
 	return os.WriteFile(sessionPath, data, 0644)
 }
 
+// rebuildSessionIndex lists the session files in the index, oldest first,
+// with no active session
+func (m *Manager) rebuildSessionIndex() error {
+	m.sessionIndex = &SessionIndex{}
+	files, _ := filepath.Glob(filepath.Join(m.rootDir, "history", "session_*.json"))
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var session Session
+		if err := json.Unmarshal(data, &session); err != nil || session.ID == "" {
+			continue
+		}
+		m.sessionIndex.Sessions = append(m.sessionIndex.Sessions, SessionMetadata{
+			ID:           session.ID,
+			Name:         session.Name,
+			CreatedAt:    session.CreatedAt,
+			UpdatedAt:    session.UpdatedAt,
+			MessageCount: len(session.Messages),
+			Summary:      session.Summary,
+		})
+	}
+	if len(m.sessionIndex.Sessions) == 0 {
+		return nil
+	}
+	sort.Slice(m.sessionIndex.Sessions, func(i, j int) bool {
+		return m.sessionIndex.Sessions[i].CreatedAt.Before(m.sessionIndex.Sessions[j].CreatedAt)
+	})
+	return m.saveSessionIndex()
+}
+
 func (m *Manager) saveSessionIndex() error {
 	indexPath := filepath.Join(m.rootDir, "history", "sessions.json")
 	data, err := json.MarshalIndent(m.sessionIndex, "", "  ")
@@ -252,26 +296,28 @@ func (m *Manager) saveSessionIndex() error {
 
 // CreatePlan creates a new task plan
 func (m *Manager) CreatePlan(title string, taskContents []string) (*Plan, error) {
+	tasks := make([]Task, len(taskContents))
+	for i, content := range taskContents {
+		tasks[i] = NewTask(content, "")
+	}
+	return m.CreatePlanWithTasks(title, "", tasks)
+}
+
+// CreatePlanWithTasks creates a new active plan from tasks that may have
+// notes and subtasks. It replaces any active plan; archive that first.
+func (m *Manager) CreatePlanWithTasks(title, description string, tasks []Task) (*Plan, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	now := time.Now()
 	plan := &Plan{
-		ID:        uuid.New().String(),
-		Title:     title,
-		CreatedAt: now,
-		UpdatedAt: now,
-		Status:    PlanStatusActive,
-		Tasks:     make([]Task, len(taskContents)),
-	}
-
-	for i, content := range taskContents {
-		plan.Tasks[i] = Task{
-			ID:        uuid.New().String(),
-			Content:   content,
-			Status:    TaskStatusPending,
-			CreatedAt: now,
-		}
+		ID:          uuid.New().String(),
+		Title:       title,
+		Description: description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Status:      PlanStatusActive,
+		Tasks:       tasks,
 	}
 
 	if err := m.savePlan(plan); err != nil {
@@ -280,6 +326,7 @@ func (m *Manager) CreatePlan(title string, taskContents []string) (*Plan, error)
 
 	// Update current state
 	m.currentState.ActivePlanID = plan.ID
+	m.currentState.ActiveTaskID = ""
 	if len(plan.Tasks) > 0 {
 		m.currentState.ActiveTaskID = plan.Tasks[0].ID
 	}
@@ -311,7 +358,7 @@ func (m *Manager) GetActivePlan() (*Plan, error) {
 	return &plan, nil
 }
 
-// UpdateTaskStatus updates the status of a task in a plan
+// UpdateTaskStatus updates the status of a task or subtask in a plan
 func (m *Manager) UpdateTaskStatus(planID, taskID string, status TaskStatus) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -322,22 +369,67 @@ func (m *Manager) UpdateTaskStatus(planID, taskID string, status TaskStatus) err
 	}
 
 	now := time.Now()
-	for i := range plan.Tasks {
-		if plan.Tasks[i].ID == taskID {
-			plan.Tasks[i].Status = status
-			if status == TaskStatusCompleted {
-				plan.Tasks[i].CompletedAt = &now
-			}
-			break
-		}
+	if task := findTask(plan.Tasks, taskID); task != nil {
+		setStatus(task, status)
 	}
 
 	plan.UpdatedAt = now
 	return m.savePlan(plan)
 }
 
+// UpdateActivePlan applies fn to the active plan and saves it if fn
+// succeeds. It returns the updated plan.
+func (m *Manager) UpdateActivePlan(fn func(plan *Plan) error) (*Plan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	plan, err := m.getActivePlanUnsafe()
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil || m.currentState.ActivePlanID == "" {
+		return nil, fmt.Errorf("no active plan")
+	}
+
+	if err := fn(plan); err != nil {
+		return nil, err
+	}
+	plan.UpdatedAt = time.Now()
+	if err := m.savePlan(plan); err != nil {
+		return nil, err
+	}
+
+	// Track the task being worked on
+	var current func(tasks []Task) string
+	current = func(tasks []Task) string {
+		for _, t := range tasks {
+			if id := current(t.SubTasks); id != "" {
+				return id
+			}
+			if t.Status == TaskStatusInProgress {
+				return t.ID
+			}
+		}
+		return ""
+	}
+	m.currentState.ActiveTaskID = current(plan.Tasks)
+	m.saveCurrentState()
+
+	return plan, nil
+}
+
 // ArchivePlan moves the active plan to archive
 func (m *Manager) ArchivePlan(planID string) error {
+	return m.closePlan(planID, PlanStatusArchived)
+}
+
+// CompletePlan marks the active plan completed and moves it to archive
+func (m *Manager) CompletePlan(planID string) error {
+	return m.closePlan(planID, PlanStatusCompleted)
+}
+
+// closePlan moves the active plan to archive with a final status
+func (m *Manager) closePlan(planID string, status PlanStatus) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -346,7 +438,7 @@ func (m *Manager) ArchivePlan(planID string) error {
 		return fmt.Errorf("plan not found")
 	}
 
-	plan.Status = PlanStatusArchived
+	plan.Status = status
 	plan.UpdatedAt = time.Now()
 
 	// Move to archive
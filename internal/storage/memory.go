@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryScope says where a memory applies
+type MemoryScope string
+
+const (
+	MemoryProject MemoryScope = "project" // Stored in .taracode/memory.json
+	MemoryUser    MemoryScope = "user"    // Stored in ~/.taracode/memory.json, shared by all projects
+)
+
+// Memory is a durable fact the assistant has been told to remember
+type Memory struct {
+	ID        string      `json:"id"`
+	Content   string      `json:"content"`
+	Tags      []string    `json:"tags,omitempty"` // Limit user memories to matching projects, e.g. "go"
+	Scope     MemoryScope `json:"-"`              // Set from the file it was loaded from
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// memoryFile is the on-disk format of a memory store
+type memoryFile struct {
+	Memories []Memory `json:"memories"`
+}
+
+// memoryLocks serializes access to each memory file within the process
+var memoryLocks sync.Map
+
+// MemoryStore reads and writes the memories of one scope. The file is
+// re-read on every call so the REPL and tools always agree.
+type MemoryStore struct {
+	path  string
+	scope MemoryScope
+}
+
+// ProjectMemory returns the memory store of a project
+func ProjectMemory(projectRoot string) *MemoryStore {
+	return &MemoryStore{path: filepath.Join(projectRoot, ".taracode", "memory.json"), scope: MemoryProject}
+}
+
+// UserMemory returns the memory store shared by all of the user's projects
+func UserMemory() (*MemoryStore, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return &MemoryStore{path: filepath.Join(home, ".taracode", "memory.json"), scope: MemoryUser}, nil
+}
+
+// Scope returns the store's scope
+func (s *MemoryStore) Scope() MemoryScope {
+	return s.scope
+}
+
+func (s *MemoryStore) lock() func() {
+	mu, _ := memoryLocks.LoadOrStore(s.path, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+func (s *MemoryStore) load() ([]Memory, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file memoryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	for i := range file.Memories {
+		file.Memories[i].Scope = s.scope
+	}
+	return file.Memories, nil
+}
+
+func (s *MemoryStore) save(memories []Memory) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(memoryFile{Memories: memories}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal memories: %w", err)
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// List returns all memories, oldest first
+func (s *MemoryStore) List() ([]Memory, error) {
+	defer s.lock()()
+	return s.load()
+}
+
+// Add stores a new memory. Remembering the same content again updates the
+// existing memory's tags instead of adding a duplicate.
+func (s *MemoryStore) Add(content string, tags []string) (*Memory, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, fmt.Errorf("memory content is empty")
+	}
+
+	defer s.lock()()
+	memories, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range memories {
+		if strings.EqualFold(memories[i].Content, content) {
+			memories[i].Tags = tags
+			memories[i].UpdatedAt = now
+			return &memories[i], s.save(memories)
+		}
+	}
+
+	memory := Memory{
+		ID:        uuid.New().String()[:8],
+		Content:   content,
+		Tags:      tags,
+		Scope:     s.scope,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	memories = append(memories, memory)
+	return &memory, s.save(memories)
+}
+
+// Update replaces the content of the memory with the given ID
+func (s *MemoryStore) Update(id, content string) (*Memory, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, fmt.Errorf("memory content is empty")
+	}
+
+	defer s.lock()()
+	memories, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range memories {
+		if memories[i].ID == id {
+			memories[i].Content = content
+			memories[i].UpdatedAt = time.Now()
+			return &memories[i], s.save(memories)
+		}
+	}
+	return nil, nil
+}
+
+// Remove deletes the memory with the given ID, returning it, or nil if the
+// store has no such memory
+func (s *MemoryStore) Remove(id string) (*Memory, error) {
+	defer s.lock()()
+	memories, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range memories {
+		if memories[i].ID == id {
+			removed := memories[i]
+			memories = append(memories[:i], memories[i+1:]...)
+			return &removed, s.save(memories)
+		}
+	}
+	return nil, nil
+}
+
+// Matches reports whether the memory mentions every word of query in its
+// content or tags. An empty query matches everything.
+func (m Memory) Matches(query string) bool {
+	text := strings.ToLower(m.Content + " " + strings.Join(m.Tags, " "))
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// RelevantMemories picks the memories to show the model for a project:
+// all project memories, plus user memories that are untagged or tagged with
+// one of the project's keywords (such as its language). Project memories
+// come first, and within a scope the most recently updated; at most limit
+// memories are returned.
+func RelevantMemories(project, user []Memory, keywords []string, limit int) []Memory {
+	keywordSet := make(map[string]bool)
+	for _, k := range keywords {
+		keywordSet[strings.ToLower(k)] = true
+	}
+	relevant := func(m Memory) bool {
+		if m.Scope == MemoryProject || len(m.Tags) == 0 {
+			return true
+		}
+		for _, tag := range m.Tags {
+			if keywordSet[strings.ToLower(tag)] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var selected []Memory
+	for _, m := range append(append([]Memory(nil), project...), user...) {
+		if relevant(m) {
+			selected = append(selected, m)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].Scope != selected[j].Scope {
+			return selected[i].Scope == MemoryProject
+		}
+		return selected[i].UpdatedAt.After(selected[j].UpdatedAt)
+	})
+	if len(selected) > limit {
+		selected = selected[:limit]
+	}
+	return selected
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := ProjectMemory(t.TempDir())
+
+	first, err := store.Add("Run tests with make test", nil)
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := store.Add("  run tests with MAKE TEST ", []string{"ci"}); err != nil {
+		t.Fatalf("Add duplicate failed: %v", err)
+	}
+	if _, err := store.Add("Use tabs", nil); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := store.Add("   ", nil); err == nil {
+		t.Error("empty memory was saved")
+	}
+
+	memories, err := store.List()
+	if err != nil || len(memories) != 2 {
+		t.Fatalf("List = %+v, %v", memories, err)
+	}
+	if memories[0].ID != first.ID || len(memories[0].Tags) != 1 || memories[0].Scope != MemoryProject {
+		t.Errorf("duplicate not merged: %+v", memories[0])
+	}
+	if !memories[0].Matches("TESTS ci") || memories[0].Matches("lint") {
+		t.Error("Matches gave the wrong answer")
+	}
+
+	if m, err := store.Update(first.ID, "Run tests with go test ./..."); err != nil || m == nil || m.Content != "Run tests with go test ./..." {
+		t.Errorf("Update = %+v, %v", m, err)
+	}
+	if m, err := store.Remove(first.ID); err != nil || m == nil {
+		t.Errorf("Remove = %+v, %v", m, err)
+	}
+	if m, err := store.Remove(first.ID); err != nil || m != nil {
+		t.Errorf("second Remove = %+v, %v", m, err)
+	}
+	if memories, _ := store.List(); len(memories) != 1 || memories[0].Content != "Use tabs" {
+		t.Errorf("after Remove: %+v", memories)
+	}
+}
+
+func TestRelevantMemories(t *testing.T) {
+	now := time.Now()
+	project := []Memory{
+		{ID: "p1", Scope: MemoryProject, UpdatedAt: now.Add(-time.Hour)},
+		{ID: "p2", Scope: MemoryProject, Tags: []string{"rust"}, UpdatedAt: now},
+	}
+	user := []Memory{
+		{ID: "u1", Scope: MemoryUser, UpdatedAt: now},
+		{ID: "u2", Scope: MemoryUser, Tags: []string{"Go"}, UpdatedAt: now.Add(time.Hour)},
+		{ID: "u3", Scope: MemoryUser, Tags: []string{"python"}, UpdatedAt: now},
+	}
+
+	var ids []string
+	for _, m := range RelevantMemories(project, user, []string{"go"}, 10) {
+		ids = append(ids, m.ID)
+	}
+	if got := strings.Join(ids, ","); got != "p2,p1,u2,u1" {
+		t.Errorf("relevant = %s, want p2,p1,u2,u1", got)
+	}
+	if got := RelevantMemories(project, user, nil, 3); len(got) != 3 {
+		t.Errorf("limit not applied: %d memories", len(got))
+	}
+}
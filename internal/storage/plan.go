@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NewTask creates a pending task with optional notes and subtasks
+func NewTask(content, notes string, subTasks ...Task) Task {
+	return Task{
+		ID:        uuid.New().String(),
+		Content:   content,
+		Status:    TaskStatusPending,
+		CreatedAt: time.Now(),
+		Notes:     notes,
+		SubTasks:  subTasks,
+	}
+}
+
+// ParseTaskStatus validates a task status name
+func ParseTaskStatus(s string) (TaskStatus, error) {
+	switch status := TaskStatus(strings.ToLower(strings.TrimSpace(s))); status {
+	case TaskStatusPending, TaskStatusInProgress, TaskStatusCompleted, TaskStatusSkipped:
+		return status, nil
+	case "done":
+		return TaskStatusCompleted, nil
+	}
+	return "", fmt.Errorf("unknown task status %q (use pending, in_progress, completed or skipped)", s)
+}
+
+// TaskAt finds a task by its outline number: "2" is the second task and
+// "2.1" its first subtask. It returns the task and its parent, nil for a
+// top-level task.
+func (p *Plan) TaskAt(ref string) (task, parent *Task, err error) {
+	tasks := &p.Tasks
+	for i, part := range strings.Split(strings.TrimSpace(ref), ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 1 || n > len(*tasks) {
+			return nil, nil, fmt.Errorf("no task %s in plan (tasks are numbered like 2 or 2.1)", ref)
+		}
+		if i > 0 {
+			parent = task
+		}
+		task = &(*tasks)[n-1]
+		tasks = &task.SubTasks
+	}
+	return task, parent, nil
+}
+
+// SetTaskStatus changes the status of the task at ref. Completing the last
+// open subtask completes its parent.
+func (p *Plan) SetTaskStatus(ref string, status TaskStatus) (*Task, error) {
+	task, _, err := p.TaskAt(ref)
+	if err != nil {
+		return nil, err
+	}
+	setStatus(task, status)
+
+	// Walk up the outline completing parents whose subtasks are all done
+	parts := strings.Split(strings.TrimSpace(ref), ".")
+	for depth := len(parts) - 1; depth > 0; depth-- {
+		parent, _, _ := p.TaskAt(strings.Join(parts[:depth], "."))
+		if parent.Status == TaskStatusCompleted || !allDone(parent.SubTasks) {
+			break
+		}
+		setStatus(parent, TaskStatusCompleted)
+	}
+	p.UpdatedAt = time.Now()
+	return task, nil
+}
+
+// RemoveTask deletes the task at ref along with its subtasks
+func (p *Plan) RemoveTask(ref string) (Task, error) {
+	task, parent, err := p.TaskAt(ref)
+	if err != nil {
+		return Task{}, err
+	}
+	tasks := &p.Tasks
+	if parent != nil {
+		tasks = &parent.SubTasks
+	}
+	removed := *task
+	parts := strings.Split(strings.TrimSpace(ref), ".")
+	i, _ := strconv.Atoi(parts[len(parts)-1])
+	*tasks = append((*tasks)[:i-1], (*tasks)[i:]...)
+	p.UpdatedAt = time.Now()
+	return removed, nil
+}
+
+func setStatus(task *Task, status TaskStatus) {
+	task.Status = status
+	task.CompletedAt = nil
+	if status == TaskStatusCompleted {
+		now := time.Now()
+		task.CompletedAt = &now
+	}
+}
+
+func allDone(tasks []Task) bool {
+	for _, t := range tasks {
+		if t.Status != TaskStatusCompleted && t.Status != TaskStatusSkipped {
+			return false
+		}
+	}
+	return true
+}
+
+// findTask returns the task with the given ID anywhere in the outline
+func findTask(tasks []Task, id string) *Task {
+	for i := range tasks {
+		if tasks[i].ID == id {
+			return &tasks[i]
+		}
+		if t := findTask(tasks[i].SubTasks, id); t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// Progress counts completed or skipped tasks and all tasks, subtasks included
+func (p *Plan) Progress() (done, total int) {
+	var count func(tasks []Task)
+	count = func(tasks []Task) {
+		for _, t := range tasks {
+			total++
+			if t.Status == TaskStatusCompleted || t.Status == TaskStatusSkipped {
+				done++
+			}
+			count(t.SubTasks)
+		}
+	}
+	count(p.Tasks)
+	return done, total
+}
+
+// StatusMarker returns the checkbox shown for a task status
+func StatusMarker(status TaskStatus) string {
+	switch status {
+	case TaskStatusCompleted:
+		return "[x]"
+	case TaskStatusInProgress:
+		return "[>]"
+	case TaskStatusSkipped:
+		return "[-]"
+	}
+	return "[ ]"
+}
+
+// Outline renders the tasks as a numbered checklist, subtasks indented and
+// numbered like 2.1, with notes under their task
+func (p *Plan) Outline() string {
+	var sb strings.Builder
+	var write func(tasks []Task, prefix string, depth int)
+	write = func(tasks []Task, prefix string, depth int) {
+		indent := strings.Repeat("   ", depth)
+		for i, t := range tasks {
+			number := fmt.Sprintf("%s%d", prefix, i+1)
+			sb.WriteString(fmt.Sprintf("%s%s. %s %s\n", indent, number, StatusMarker(t.Status), t.Content))
+			if t.Notes != "" {
+				sb.WriteString(fmt.Sprintf("%s   Note: %s\n", indent, t.Notes))
+			}
+			write(t.SubTasks, number+".", depth+1)
+		}
+	}
+	write(p.Tasks, "", 0)
+	return sb.String()
+}
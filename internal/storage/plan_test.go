@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanOutline(t *testing.T) {
+	plan := &Plan{Tasks: []Task{
+		NewTask("Add middleware", ""),
+		NewTask("Write tests", "cover expiry", NewTask("Unit", ""), NewTask("Integration", "")),
+	}}
+
+	if task, parent, err := plan.TaskAt("2.1"); err != nil || task.Content != "Unit" || parent.Content != "Write tests" {
+		t.Fatalf("TaskAt(2.1) = %+v, %+v, %v", task, parent, err)
+	}
+	for _, ref := range []string{"0", "3", "1.1", "x", ""} {
+		if _, _, err := plan.TaskAt(ref); err == nil {
+			t.Errorf("TaskAt(%q) found a task", ref)
+		}
+	}
+
+	// Finishing the last subtask completes the parent
+	if _, err := plan.SetTaskStatus("2.1", TaskStatusCompleted); err != nil {
+		t.Fatal(err)
+	}
+	if plan.Tasks[1].Status != TaskStatusPending {
+		t.Error("parent completed with a subtask open")
+	}
+	if _, err := plan.SetTaskStatus("2.2", TaskStatusSkipped); err != nil {
+		t.Fatal(err)
+	}
+	if plan.Tasks[1].Status != TaskStatusCompleted || plan.Tasks[1].CompletedAt == nil {
+		t.Errorf("parent not completed: %+v", plan.Tasks[1])
+	}
+	if done, total := plan.Progress(); done != 3 || total != 4 {
+		t.Errorf("Progress = %d/%d, want 3/4", done, total)
+	}
+
+	want := "1. [ ] Add middleware\n2. [x] Write tests\n   Note: cover expiry\n   2.1. [x] Unit\n   2.2. [-] Integration\n"
+	if got := plan.Outline(); got != want {
+		t.Errorf("Outline =\n%s\nwant\n%s", got, want)
+	}
+
+	if removed, err := plan.RemoveTask("2.1"); err != nil || removed.Content != "Unit" {
+		t.Errorf("RemoveTask(2.1) = %+v, %v", removed, err)
+	}
+	if task, _, _ := plan.TaskAt("2.1"); task == nil || task.Content != "Integration" {
+		t.Errorf("subtasks not renumbered after removal: %+v", task)
+	}
+
+	if status, err := ParseTaskStatus("Done"); err != nil || status != TaskStatusCompleted {
+		t.Errorf("ParseTaskStatus(Done) = %q, %v", status, err)
+	}
+	if _, err := ParseTaskStatus("finished"); err == nil {
+		t.Error("ParseTaskStatus accepted an unknown status")
+	}
+}
+
+func TestManagerActivePlan(t *testing.T) {
+	mgr, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.UpdateActivePlan(func(*Plan) error { return nil }); err == nil {
+		t.Error("UpdateActivePlan succeeded without a plan")
+	}
+
+	plan, err := mgr.CreatePlanWithTasks("Refactor", "", []Task{NewTask("Split", "", NewTask("Move types", ""))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.UpdateActivePlan(func(p *Plan) error {
+		_, err := p.SetTaskStatus("1.1", TaskStatusInProgress)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if id := mgr.GetCurrentState().ActiveTaskID; id != plan.Tasks[0].SubTasks[0].ID {
+		t.Errorf("ActiveTaskID = %q, want the in-progress subtask", id)
+	}
+
+	// Nested tasks can still be updated by ID
+	if err := mgr.UpdateTaskStatus(plan.ID, plan.Tasks[0].SubTasks[0].ID, TaskStatusCompleted); err != nil {
+		t.Fatal(err)
+	}
+	active, _ := mgr.GetActivePlan()
+	if !strings.Contains(active.Outline(), "1.1. [x] Move types") {
+		t.Errorf("nested status not saved:\n%s", active.Outline())
+	}
+
+	if err := mgr.CompletePlan(plan.ID); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := mgr.GetActivePlan(); active != nil {
+		t.Errorf("completed plan still active: %+v", active)
+	}
+}
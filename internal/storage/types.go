@@ -16,11 +16,15 @@ type Session struct {
 
 // ConversationMessage represents a single message in conversation
 type ConversationMessage struct {
-	Role      string          `json:"role"` // user, assistant, system, tool
-	Content   string          `json:"content"`
-	Timestamp time.Time       `json:"timestamp"`
-	ToolCall  *ToolCallRecord `json:"tool_call,omitempty"`
-	Usage     *TokenUsage     `json:"usage,omitempty"`
+	Role      string            `json:"role"` // user, assistant, system, tool
+	Content   string            `json:"content"`
+	Timestamp time.Time         `json:"timestamp"`
+	ToolCall  *ToolCallRecord   `json:"tool_call,omitempty"`
+	Usage     *TokenUsage       `json:"usage,omitempty"`
+	Model     string            `json:"model,omitempty"`     // Model that produced an assistant message
+	Params    *GenerationParams `json:"params,omitempty"`    // Sampling parameters used for an assistant message
+	Reasoning string            `json:"reasoning,omitempty"` // Model reasoning, kept out of Content so it is not sent back
+	Iteration int               `json:"iteration,omitempty"` // Tool-loop iteration (1-based) of an assistant message
 }
 
 // ToolCallRecord captures tool execution details
@@ -32,11 +36,24 @@ type ToolCallRecord struct {
 	Success  bool                   `json:"success"`
 }
 
+// GenerationParams holds sampling parameters for a chat completion.
+// Nil fields are left to the server default.
+type GenerationParams struct {
+	Temperature     *float32 `json:"temperature,omitempty" mapstructure:"temperature"`
+	TopP            *float32 `json:"top_p,omitempty" mapstructure:"top_p"`
+	MaxTokens       *int     `json:"max_tokens,omitempty" mapstructure:"max_tokens"`
+	Stop            []string `json:"stop,omitempty" mapstructure:"stop"`
+	Seed            *int     `json:"seed,omitempty" mapstructure:"seed"`
+	PresencePenalty *float32 `json:"presence_penalty,omitempty" mapstructure:"presence_penalty"`
+}
+
 // TokenUsage tracks token consumption for an LLM call
 type TokenUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens     int   `json:"prompt_tokens"`
+	CompletionTokens int   `json:"completion_tokens"`
+	TotalTokens      int   `json:"total_tokens"`
+	DurationMs       int64 `json:"duration_ms,omitempty"` // Wall time of the request, for throughput
+	Estimated        bool  `json:"estimated,omitempty"`   // Counted locally because the server reported no usage
 }
 
 // SessionIndex tracks all sessions
@@ -111,6 +128,19 @@ type Preferences struct {
 	PreferredModel    string   `json:"preferred_model,omitempty"`
 	ExcludeDirs       []string `json:"exclude_dirs,omitempty"`
 	CustomPromptRules []string `json:"custom_prompt_rules,omitempty"`
+
+	// Generation overrides set with /set, applied on top of config
+	Generation *GenerationParams `json:"generation,omitempty"`
+
+	// Reasoning mode set with /think (nil = model default) and how
+	// reasoning is displayed: hide, collapsed or full
+	Thinking         *bool  `json:"thinking,omitempty"`
+	ReasoningDisplay string `json:"reasoning_display,omitempty"`
+
+	// Build check run after a turn's edits, set with /verify; the command
+	// is the project default when empty
+	Verify        bool   `json:"verify,omitempty"`
+	VerifyCommand string `json:"verify_command,omitempty"`
 }
 
 // DefaultPreferences returns sensible default preferences
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// Add accumulates another usage record into u
+func (u *TokenUsage) Add(other *TokenUsage) {
+	if other == nil {
+		return
+	}
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.DurationMs += other.DurationMs
+	u.Estimated = u.Estimated || other.Estimated
+}
+
+// TokensPerSecond returns completion throughput, or 0 if unknown
+func (u *TokenUsage) TokensPerSecond() float64 {
+	if u == nil || u.DurationMs <= 0 {
+		return 0
+	}
+	return float64(u.CompletionTokens) / (float64(u.DurationMs) / 1000)
+}
+
+// IterationUsage is the usage of one model response within a turn
+type IterationUsage struct {
+	Iteration int
+	Usage     TokenUsage
+	Tools     []string // Tools called by this response
+}
+
+// TurnUsage is the usage of one user message and the tool loop it started
+type TurnUsage struct {
+	Turn       int
+	Prompt     string // The user message that started the turn
+	Iterations []IterationUsage
+	Total      TokenUsage
+}
+
+// UsageByTurn groups the session's recorded usage by user turn and by
+// tool-loop iteration within each turn. Messages trimmed from history are
+// not included; Session.TotalUsage covers the whole session.
+func (s *Session) UsageByTurn() []TurnUsage {
+	var turns []TurnUsage
+	var current *TurnUsage
+
+	for _, msg := range s.Messages {
+		if msg.Role == "user" {
+			turns = append(turns, TurnUsage{Turn: len(turns) + 1, Prompt: msg.Content})
+			current = &turns[len(turns)-1]
+			continue
+		}
+		if msg.Role != "assistant" {
+			continue
+		}
+		if current == nil {
+			// History trimmed past the start of this turn
+			turns = append(turns, TurnUsage{Turn: 1})
+			current = &turns[len(turns)-1]
+		}
+
+		// Responses that call several tools are recorded once per tool;
+		// only the first record of a response carries its usage
+		if msg.Usage != nil || len(current.Iterations) == 0 || msg.Iteration != current.Iterations[len(current.Iterations)-1].Iteration {
+			iteration := IterationUsage{Iteration: msg.Iteration}
+			if iteration.Iteration == 0 {
+				iteration.Iteration = len(current.Iterations) + 1
+			}
+			if msg.Usage != nil {
+				iteration.Usage = *msg.Usage
+				current.Total.Add(msg.Usage)
+			}
+			current.Iterations = append(current.Iterations, iteration)
+		}
+		if msg.ToolCall != nil {
+			last := &current.Iterations[len(current.Iterations)-1]
+			last.Tools = append(last.Tools, msg.ToolCall.Tool)
+		}
+	}
+
+	return turns
+}
+
+// UsageReport aggregates usage across sessions
+type UsageReport struct {
+	Since    time.Time
+	Sessions int
+	Turns    int
+	Total    TokenUsage
+	ByModel  map[string]*TokenUsage
+	ByDay    map[string]*TokenUsage // Keyed by YYYY-MM-DD
+}
+
+// Days returns the report's days in chronological order
+func (r *UsageReport) Days() []string {
+	days := make([]string, 0, len(r.ByDay))
+	for day := range r.ByDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	return days
+}
+
+// Models returns the report's models ordered by total tokens, highest first
+func (r *UsageReport) Models() []string {
+	models := make([]string, 0, len(r.ByModel))
+	for model := range r.ByModel {
+		models = append(models, model)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return r.ByModel[models[i]].TotalTokens > r.ByModel[models[j]].TotalTokens
+	})
+	return models
+}
+
+// UsageSince aggregates recorded usage from every session in history for
+// messages at or after since
+func (m *Manager) UsageSince(since time.Time) (*UsageReport, error) {
+	report := &UsageReport{
+		Since:   since,
+		ByModel: make(map[string]*TokenUsage),
+		ByDay:   make(map[string]*TokenUsage),
+	}
+
+	sessions, err := m.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, meta := range sessions {
+		if meta.UpdatedAt.Before(since) {
+			continue
+		}
+		session, err := m.GetSession(meta.ID)
+		if err != nil {
+			continue
+		}
+
+		counted := false
+		for _, msg := range session.Messages {
+			if msg.Timestamp.Before(since) {
+				continue
+			}
+			if msg.Role == "user" {
+				report.Turns++
+				continue
+			}
+			if msg.Usage == nil {
+				continue
+			}
+			counted = true
+
+			report.Total.Add(msg.Usage)
+
+			model := msg.Model
+			if model == "" {
+				model = "(unknown)"
+			}
+			if report.ByModel[model] == nil {
+				report.ByModel[model] = &TokenUsage{}
+			}
+			report.ByModel[model].Add(msg.Usage)
+
+			day := msg.Timestamp.Format("2006-01-02")
+			if report.ByDay[day] == nil {
+				report.ByDay[day] = &TokenUsage{}
+			}
+			report.ByDay[day].Add(msg.Usage)
+		}
+		if counted {
+			report.Sessions++
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsageRollupAndBreakdown(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	session, _ := m.CreateSession("")
+	now := time.Now()
+
+	add := func(msg ConversationMessage) {
+		msg.Timestamp = now
+		if err := m.AddMessage(session.ID, msg); err != nil {
+			t.Fatalf("AddMessage failed: %v", err)
+		}
+	}
+	usage := func(prompt, completion int) *TokenUsage {
+		return &TokenUsage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion, DurationMs: 1000}
+	}
+
+	add(ConversationMessage{Role: "user", Content: "list and read"})
+	add(ConversationMessage{Role: "assistant", Model: "qwen3", Iteration: 1, Usage: usage(100, 20), ToolCall: &ToolCallRecord{Tool: "list_files"}})
+	add(ConversationMessage{Role: "assistant", Model: "qwen3", Iteration: 1, ToolCall: &ToolCallRecord{Tool: "read_file"}})
+	add(ConversationMessage{Role: "assistant", Model: "qwen3", Iteration: 2, Usage: usage(200, 40)})
+	add(ConversationMessage{Role: "user", Content: "thanks"})
+	add(ConversationMessage{Role: "assistant", Model: "qwen3", Iteration: 1, Usage: usage(250, 10)})
+
+	session, _ = m.GetSession(session.ID)
+	if session.TotalUsage == nil || session.TotalUsage.TotalTokens != 620 {
+		t.Fatalf("Expected TotalUsage of 620 tokens, got %+v", session.TotalUsage)
+	}
+
+	turns := session.UsageByTurn()
+	if len(turns) != 2 {
+		t.Fatalf("Expected 2 turns, got %d", len(turns))
+	}
+	if len(turns[0].Iterations) != 2 || len(turns[0].Iterations[0].Tools) != 2 {
+		t.Errorf("Expected 2 iterations with 2 tools in the first, got %+v", turns[0].Iterations)
+	}
+	if turns[0].Total.TotalTokens != 360 || turns[1].Total.TotalTokens != 260 {
+		t.Errorf("Unexpected turn totals: %d, %d", turns[0].Total.TotalTokens, turns[1].Total.TotalTokens)
+	}
+	if tps := turns[0].Iterations[0].Usage.TokensPerSecond(); tps != 20 {
+		t.Errorf("Expected 20 tokens/sec, got %v", tps)
+	}
+
+	report, err := m.UsageSince(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("UsageSince failed: %v", err)
+	}
+	if report.Sessions != 1 || report.Turns != 2 || report.ByModel["qwen3"].TotalTokens != 620 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if report, _ := m.UsageSince(now.Add(time.Hour)); report.Total.TotalTokens != 0 {
+		t.Errorf("Expected no usage after now, got %d", report.Total.TotalTokens)
+	}
+}
@@ -0,0 +1,117 @@
+package testrun
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// "test path::name ... ok" as each test finishes
+	cargoTest = regexp.MustCompile(`^test (\S+) \.\.\. (ok|FAILED|ignored)`)
+	// Start of a failing test's captured output
+	cargoOutput = regexp.MustCompile(`^---- (\S+) stdout ----$`)
+	// "thread 'name' panicked at src/lib.rs:10:5:" (older: "..., src/lib.rs:10:5")
+	cargoPanic = regexp.MustCompile(`panicked at (?:'.*', )?([^\s:]+):(\d+):\d+:?$`)
+	// Compiler error position
+	cargoError = regexp.MustCompile(`^\s*--> ([^\s:]+):(\d+):\d+$`)
+)
+
+// cargoCommand runs cargo test, filtered by test name and package
+func cargoCommand(opts Options) []string {
+	args := []string{"cargo", "test", "--color", "never"}
+	if opts.Package != "" {
+		args = append(args, "-p", opts.Package)
+	}
+	if opts.Test != "" {
+		args = append(args, opts.Test)
+	}
+	return args
+}
+
+// cargoParser reads cargo test's default output
+type cargoParser struct {
+	failures map[string]*Failure
+	order    []string
+	current  *Failure // Failure whose captured output is being read
+	compile  []string // Compiler errors when the tests did not build
+	inError  bool
+}
+
+func (p *cargoParser) line(text string, r *Result) {
+	if p.failures == nil {
+		p.failures = make(map[string]*Failure)
+	}
+
+	if m := cargoTest.FindStringSubmatch(text); m != nil {
+		switch m[2] {
+		case "ok":
+			r.Passed++
+		case "FAILED":
+			r.Failed++
+			p.failure(m[1])
+		case "ignored":
+			r.Skipped++
+		}
+		return
+	}
+	if m := cargoOutput.FindStringSubmatch(text); m != nil {
+		p.current = p.failure(m[1])
+		return
+	}
+	if strings.HasPrefix(text, "failures:") || strings.HasPrefix(text, "test result:") {
+		p.current = nil
+		return
+	}
+
+	if p.current != nil {
+		if m := cargoPanic.FindStringSubmatch(text); m != nil {
+			p.current.File = m[1]
+			p.current.Line, _ = strconv.Atoi(m[2])
+		} else if strings.TrimSpace(text) != "" && !strings.HasPrefix(text, "note: run with `RUST_BACKTRACE") && strings.Count(p.current.Message, "\n") < 4 {
+			if p.current.Message != "" {
+				p.current.Message += "\n"
+			}
+			p.current.Message += text
+		}
+		p.current.Log = append(p.current.Log, text)
+		return
+	}
+
+	// Compiler errors stop the tests from building
+	if strings.HasPrefix(text, "error") {
+		p.inError = !strings.HasPrefix(text, "error: could not compile") && !strings.HasPrefix(text, "error: aborting")
+		if p.inError {
+			p.compile = append(p.compile, text)
+		}
+		return
+	}
+	if p.inError {
+		if strings.TrimSpace(text) == "" {
+			p.inError = false
+			return
+		}
+		if m := cargoError.FindStringSubmatch(text); m != nil {
+			p.compile[len(p.compile)-1] = m[1] + ":" + m[2] + ": " + p.compile[len(p.compile)-1]
+		}
+	}
+}
+
+func (p *cargoParser) failure(name string) *Failure {
+	f, ok := p.failures[name]
+	if !ok {
+		f = &Failure{Name: name}
+		p.failures[name] = f
+		p.order = append(p.order, name)
+	}
+	return f
+}
+
+func (p *cargoParser) finish(r *Result) {
+	for _, name := range p.order {
+		f := p.failures[name]
+		f.Log = trimLog(f.Log)
+		r.Failures = append(r.Failures, *f)
+	}
+	r.Errors = append(r.Errors, p.compile...)
+}
@@ -0,0 +1,282 @@
+package testrun
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tara-vision/taracode/internal/gocode"
+)
+
+// goEvent is a line of go test -json output
+type goEvent struct {
+	Action     string
+	Package    string
+	ImportPath string // build-output and build-fail events (Go 1.24+)
+	Test       string
+	Output     string
+}
+
+var (
+	// Position of a t.Error or t.Fatal message
+	goReport = regexp.MustCompile(`^\s+([^\s:]+\.go):(\d+): (.*)$`)
+	// Test file frame of a panic's stack trace
+	goFrame = regexp.MustCompile(`^\s+(\S+_test\.go):(\d+)`)
+)
+
+// newGoParser reads output of tests run in dir
+func newGoParser(dir string) *goParser {
+	p := &goParser{dir: dir}
+	p.root, p.module, _ = gocode.FindModule(dir)
+	return p
+}
+
+// goCommand runs the selected packages with JSON output
+func goCommand(opts Options) []string {
+	args := []string{"go", "test", "-json"}
+	if opts.Test != "" {
+		args = append(args, "-run", goRunPattern(opts.Test))
+	}
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "./..."
+	}
+	return append(args, pkg)
+}
+
+// goRunPattern anchors each level of a plain test name, such as
+// TestOrder/empty, so it selects that test rather than every match
+func goRunPattern(name string) string {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		if regexp.QuoteMeta(part) == part {
+			parts[i] = "^" + part + "$"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// goTest is a test seen in the output
+type goTest struct {
+	pkg, name string
+	status    string // pass, fail or skip; empty while running
+	output    []string
+}
+
+// goParser reads go test -json output
+type goParser struct {
+	dir     string // Where the tests run
+	root    string // Module root and path, for turning packages into directories
+	module  string
+	tests   []*goTest
+	byKey   map[string]*goTest
+	pkgLogs map[string][]string // Package-level output
+	failed  []string            // Packages that failed
+	build   map[string][]string // Build output by package
+	other   []string            // Lines that were not JSON, such as old-style build errors
+}
+
+func (p *goParser) line(text string, r *Result) {
+	var ev goEvent
+	if !decodeJSON(text, &ev) {
+		if strings.TrimSpace(text) != "" {
+			p.other = append(p.other, text)
+		}
+		return
+	}
+	if p.byKey == nil {
+		p.byKey = make(map[string]*goTest)
+		p.pkgLogs = make(map[string][]string)
+		p.build = make(map[string][]string)
+	}
+	output := strings.TrimRight(ev.Output, "\n")
+
+	switch {
+	case ev.Action == "build-output":
+		p.build[ev.ImportPath] = append(p.build[ev.ImportPath], output)
+	case ev.Action == "build-fail":
+		p.failed = append(p.failed, ev.ImportPath)
+	case ev.Test == "":
+		if ev.Action == "output" {
+			p.pkgLogs[ev.Package] = append(p.pkgLogs[ev.Package], output)
+		} else if ev.Action == "fail" {
+			p.failed = append(p.failed, ev.Package)
+		}
+	default:
+		key := ev.Package + " " + ev.Test
+		t := p.byKey[key]
+		if t == nil {
+			t = &goTest{pkg: ev.Package, name: ev.Test}
+			p.byKey[key] = t
+			p.tests = append(p.tests, t)
+		}
+		switch ev.Action {
+		case "output":
+			t.output = append(t.output, output)
+		case "pass", "fail", "skip":
+			t.status = ev.Action
+			switch ev.Action {
+			case "pass":
+				r.Passed++
+			case "fail":
+				r.Failed++
+			case "skip":
+				r.Skipped++
+			}
+		}
+	}
+}
+
+// finish counts leaf tests only, so a failing subtest is not counted
+// again through its parent, and reports packages that failed outside any
+// test, such as build failures
+func (p *goParser) finish(r *Result) {
+	r.Passed, r.Failed, r.Skipped = 0, 0, 0
+	parents := make(map[string]bool)
+	for _, t := range p.tests {
+		if i := strings.LastIndex(t.name, "/"); i >= 0 {
+			parents[t.pkg+" "+t.name[:i]] = true
+		}
+	}
+
+	failedTests := make(map[string]bool)
+	for _, t := range p.tests {
+		if parents[t.pkg+" "+t.name] {
+			continue
+		}
+		switch t.status {
+		case "pass":
+			r.Passed++
+		case "skip":
+			r.Skipped++
+		case "fail":
+			r.Failed++
+			failedTests[t.pkg] = true
+			r.Failures = append(r.Failures, p.failure(t))
+		case "":
+			// Still running when the output ended: a panic or timeout
+			// elsewhere in the package stopped it
+			if logs := p.pkgLogs[t.pkg]; len(logs) > 0 && hasPanic(t.output, logs) {
+				r.Failed++
+				failedTests[t.pkg] = true
+				t.output = append(t.output, logs...)
+				r.Failures = append(r.Failures, p.failure(t))
+			}
+		}
+	}
+
+	// Build failures name the package, or its test variant as
+	// "pkg [pkg.test]"; each package is reported once
+	seen := make(map[string]bool)
+	sort.Strings(p.failed)
+	for _, failed := range p.failed {
+		pkg, _, _ := strings.Cut(failed, " ")
+		if failedTests[pkg] || seen[pkg] {
+			continue
+		}
+		var lines []string
+		switch logs := p.pkgLogs[failed]; {
+		case len(p.build[failed]) > 0:
+			lines = p.build[failed]
+		case !isBuildFailed(logs):
+			lines = logs
+		case len(p.build) > 0:
+			// Reported with the build output of its test variant
+			continue
+		default:
+			lines = p.other
+		}
+		seen[pkg] = true
+		r.Errors = append(r.Errors, pkg+":\n"+strings.Join(trimLog(filterGoLog(lines)), "\n"))
+	}
+}
+
+// failure builds a failure from a test's output: the last reported
+// message, which is usually the t.Fatal that stopped it, or the panic, with
+// the output as its log
+func (p *goParser) failure(t *goTest) Failure {
+	f := Failure{Name: t.pkg + " " + t.name}
+	log := filterGoLog(t.output)
+	for i, line := range log {
+		if m := goReport.FindStringSubmatch(line); m != nil {
+			f.File = p.file(t.pkg, m[1])
+			f.Line, _ = strconv.Atoi(m[2])
+			f.Message = m[3]
+		}
+		if strings.HasPrefix(line, "panic: ") {
+			f.File, f.Line, f.Message = "", 0, line
+			for _, frame := range log[i:] {
+				if m := goFrame.FindStringSubmatch(frame); m != nil {
+					f.File = p.file(t.pkg, path.Base(m[1]))
+					f.Line, _ = strconv.Atoi(m[2])
+					break
+				}
+			}
+			break
+		}
+	}
+	f.Log = trimLog(log)
+	return f
+}
+
+// file turns a file name in a package into a path relative to where the
+// tests run
+func (p *goParser) file(pkg, name string) string {
+	if strings.Contains(name, "/") || p.module == "" {
+		return name
+	}
+	var pkgDir string
+	if pkg == p.module {
+		pkgDir = p.root
+	} else if rel, ok := strings.CutPrefix(pkg, p.module+"/"); ok {
+		pkgDir = filepath.Join(p.root, filepath.FromSlash(rel))
+	} else {
+		return name
+	}
+	if rel, err := filepath.Rel(p.dir, filepath.Join(pkgDir, name)); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return name
+}
+
+// filterGoLog drops go test's framing lines, keeping what tests printed
+func filterGoLog(lines []string) []string {
+	var kept []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "=== RUN"), strings.HasPrefix(trimmed, "=== PAUSE"),
+			strings.HasPrefix(trimmed, "=== CONT"), strings.HasPrefix(trimmed, "=== NAME"),
+			strings.HasPrefix(trimmed, "--- PASS"), strings.HasPrefix(trimmed, "--- FAIL"),
+			strings.HasPrefix(trimmed, "--- SKIP"), trimmed == "PASS", trimmed == "FAIL",
+			strings.HasPrefix(trimmed, "FAIL\t"), strings.HasPrefix(trimmed, "ok  \t"),
+			strings.HasPrefix(trimmed, "# "):
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
+
+func hasPanic(lines ...[]string) bool {
+	for _, group := range lines {
+		for _, line := range group {
+			if strings.HasPrefix(line, "panic: ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isBuildFailed(lines []string) bool {
+	for _, line := range lines {
+		if strings.Contains(line, "[build failed]") || strings.Contains(line, "[setup failed]") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package testrun
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Per-test lines of jest's verbose reporter
+	jestPass = regexp.MustCompile(`^\s+(✓|√) `)
+	jestFail = regexp.MustCompile(`^\s+(✕|×) `)
+	jestSkip = regexp.MustCompile(`^\s+(○|-) (skipped|todo) `)
+	// Stack frame, "at fn (file:line:column)" or "at file:line:column"
+	jestFrame = regexp.MustCompile(`at (?:.*? \()?(.+?):(\d+):(\d+)\)?$`)
+	ansiCodes = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+// jestReport is the part of jest's --json output that is read
+type jestReport struct {
+	NumPassedTests  int
+	NumFailedTests  int
+	NumPendingTests int
+	NumTodoTests    int
+	TestResults     []struct {
+		Name             string
+		Message          string
+		AssertionResults []struct {
+			FullName        string
+			Status          string
+			FailureMessages []string
+			Location        *struct{ Line int }
+		}
+	}
+}
+
+// jestCommand runs jest with a JSON report written to a temporary file,
+// keeping the verbose reporter on the terminal output for progress
+func jestCommand(dir string, opts Options) ([]string, parser, error) {
+	report, err := os.CreateTemp("", "taracode-jest-*.json")
+	if err != nil {
+		return nil, nil, err
+	}
+	report.Close()
+
+	args := []string{"npx", "jest", "--ci", "--verbose", "--json", "--testLocationInResults", "--outputFile=" + report.Name()}
+	if opts.Test != "" {
+		args = append(args, "--testNamePattern", opts.Test)
+	}
+	if opts.Package != "" {
+		args = append(args, opts.Package)
+	}
+	return args, &jestParser{dir: dir, report: report.Name()}, nil
+}
+
+// jestParser counts tests from the verbose reporter as they finish and
+// takes the result from the JSON report at the end
+type jestParser struct {
+	dir    string
+	report string
+	output []string // Reporter output, for errors when there is no report
+}
+
+func (p *jestParser) line(text string, r *Result) {
+	text = ansiCodes.ReplaceAllString(text, "")
+	p.output = append(p.output, text)
+	switch {
+	case jestPass.MatchString(text):
+		r.Passed++
+	case jestFail.MatchString(text):
+		r.Failed++
+	case jestSkip.MatchString(text):
+		r.Skipped++
+	}
+}
+
+func (p *jestParser) finish(r *Result) {
+	defer os.Remove(p.report)
+	data, err := os.ReadFile(p.report)
+	var report jestReport
+	if err != nil || len(data) == 0 || json.Unmarshal(data, &report) != nil {
+		r.Errors = append(r.Errors, "jest wrote no report:\n"+strings.Join(trimLog(p.output), "\n"))
+		return
+	}
+
+	r.Passed, r.Failed = report.NumPassedTests, report.NumFailedTests
+	r.Skipped = report.NumPendingTests + report.NumTodoTests
+	for _, suite := range report.TestResults {
+		file := p.rel(suite.Name)
+		if len(suite.AssertionResults) == 0 && suite.Message != "" {
+			// The suite failed to run, such as on a syntax error
+			r.Errors = append(r.Errors, file+":\n"+strings.Join(trimLog(strings.Split(ansiCodes.ReplaceAllString(suite.Message, ""), "\n")), "\n"))
+			continue
+		}
+		for _, test := range suite.AssertionResults {
+			if test.Status != "failed" {
+				continue
+			}
+			f := Failure{Name: file + " > " + test.FullName}
+			if test.Location != nil {
+				f.File, f.Line = file, test.Location.Line
+			}
+			if len(test.FailureMessages) > 0 {
+				p.describe(&f, suite.Name, ansiCodes.ReplaceAllString(test.FailureMessages[0], ""))
+			}
+			r.Failures = append(r.Failures, f)
+		}
+	}
+}
+
+// describe splits a failure message into the assertion message and the
+// stack, taking the position from the stack frame in the test file
+func (p *jestParser) describe(f *Failure, testFile, message string) {
+	var text, stack []string
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "at ") {
+			stack = append(stack, strings.TrimSpace(line))
+			continue
+		}
+		if len(stack) == 0 {
+			text = append(text, line)
+		}
+	}
+	text = trimLog(text)
+	if len(text) > 8 {
+		text = text[:8]
+	}
+	f.Message = strings.Join(text, "\n")
+	f.Log = trimLog(stack)
+
+	for _, frame := range stack {
+		if m := jestFrame.FindStringSubmatch(frame); m != nil && m[1] == testFile {
+			f.File = p.rel(m[1])
+			f.Line, _ = strconv.Atoi(m[2])
+			return
+		}
+	}
+}
+
+// rel makes a path from the report relative to where the tests run
+func (p *jestParser) rel(path string) string {
+	if rel, err := filepath.Rel(p.dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
@@ -0,0 +1,163 @@
+package testrun
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Progress line of pytest -q: one character per test
+	pytestDots = regexp.MustCompile(`^[.FEsxX]+\s*(\[\s*\d+%\])?$`)
+	// Header of a failure's traceback section
+	pytestSection = regexp.MustCompile(`^_{3,} (.+?) _{3,}$`)
+	// Frame of a --tb=short traceback
+	pytestFrame = regexp.MustCompile(`^(\S+\.py):(\d+): `)
+	// Final "1 failed, 3 passed in 0.05s" line
+	pytestTotals = regexp.MustCompile(`^=*\s*(.*\d+ \w+.*) in [\d.]+s`)
+	pytestCount  = regexp.MustCompile(`(\d+) (passed|failed|skipped|xfailed|xpassed|errors?)`)
+)
+
+// pytestCommand runs pytest quietly with short tracebacks and a summary
+// of failures and errors
+func pytestCommand(opts Options) []string {
+	args := []string{"python3", "-m", "pytest"}
+	if _, err := exec.LookPath("pytest"); err == nil {
+		args = []string{"pytest"}
+	}
+	args = append(args, "-q", "--tb=short", "-rfE", "--color=no")
+	if opts.Test != "" {
+		args = append(args, "-k", opts.Test)
+	}
+	if opts.Package != "" {
+		args = append(args, opts.Package)
+	}
+	return args
+}
+
+// pytestParser reads pytest -q --tb=short -rfE output
+type pytestParser struct {
+	section  *Failure // Traceback section being read
+	inTraces bool     // Inside the FAILURES or ERRORS block
+}
+
+func (p *pytestParser) line(text string, r *Result) {
+	switch {
+	case !p.inTraces && pytestDots.MatchString(text):
+		for _, c := range strings.Fields(text)[0] {
+			switch c {
+			case '.', 'X':
+				r.Passed++
+			case 'F', 'E':
+				r.Failed++
+			case 's', 'x':
+				r.Skipped++
+			}
+		}
+
+	case strings.HasPrefix(text, "=") && (strings.Contains(text, " FAILURES ") || strings.Contains(text, " ERRORS ")):
+		p.inTraces = true
+
+	case strings.HasPrefix(text, "=") && strings.Contains(text, "short test summary"):
+		p.closeSection(r)
+		p.inTraces = false
+
+	case p.inTraces && pytestSection.MatchString(text):
+		p.closeSection(r)
+		name := pytestSection.FindStringSubmatch(text)[1]
+		for _, prefix := range []string{"ERROR at setup of ", "ERROR at teardown of ", "ERROR collecting "} {
+			name = strings.TrimPrefix(name, prefix)
+		}
+		p.section = &Failure{Name: name}
+
+	case p.section != nil:
+		p.section.Log = append(p.section.Log, text)
+		if m := pytestFrame.FindStringSubmatch(text); m != nil && p.section.File == "" {
+			p.section.File = m[1]
+			p.section.Line, _ = strconv.Atoi(m[2])
+		}
+		if msg, ok := strings.CutPrefix(text, "E "); ok && strings.Count(p.section.Message, "\n") < 4 {
+			if p.section.Message != "" {
+				p.section.Message += "\n"
+			}
+			p.section.Message += strings.TrimSpace(msg)
+		}
+
+	case strings.HasPrefix(text, "FAILED ") || strings.HasPrefix(text, "ERROR "):
+		p.summaryLine(text, r)
+
+	default:
+		if m := pytestTotals.FindStringSubmatch(text); m != nil {
+			p.readTotals(m[1], r)
+		}
+	}
+}
+
+// closeSection files the traceback section being read as a failure
+func (p *pytestParser) closeSection(r *Result) {
+	if p.section == nil {
+		return
+	}
+	p.section.Log = trimLog(p.section.Log)
+	r.Failures = append(r.Failures, *p.section)
+	p.section = nil
+}
+
+// summaryLine names a failure by its node id ("tests/test_x.py::test_y"),
+// or reports an error outside any test, such as a failed import
+func (p *pytestParser) summaryLine(text string, r *Result) {
+	kind, rest, _ := strings.Cut(text, " ")
+	nodeID, message, _ := strings.Cut(rest, " - ")
+
+	if kind == "ERROR" && !strings.Contains(nodeID, "::") {
+		e := strings.TrimSpace(rest)
+		for i, f := range r.Failures {
+			if f.Name == nodeID {
+				if f.Message != "" {
+					e = nodeID + ":\n" + f.Message
+				}
+				r.Failures = append(r.Failures[:i], r.Failures[i+1:]...)
+				break
+			}
+		}
+		r.Errors = append(r.Errors, e)
+		return
+	}
+
+	// Section headers name the test as Class.test; node ids use ::
+	for i, f := range r.Failures {
+		if strings.HasSuffix(nodeID, "::"+strings.ReplaceAll(f.Name, ".", "::")) {
+			r.Failures[i].Name = nodeID
+			if r.Failures[i].Message == "" {
+				r.Failures[i].Message = message
+			}
+			return
+		}
+	}
+	r.Failures = append(r.Failures, Failure{Name: nodeID, Message: message})
+}
+
+// readTotals replaces the running counts with pytest's own
+func (p *pytestParser) readTotals(text string, r *Result) {
+	r.Passed, r.Failed, r.Skipped = 0, 0, 0
+	for _, m := range pytestCount.FindAllStringSubmatch(text, -1) {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "passed", "xpassed":
+			r.Passed += n
+		case "failed":
+			r.Failed += n
+		case "skipped", "xfailed":
+			r.Skipped += n
+		}
+	}
+}
+
+func (p *pytestParser) finish(r *Result) {
+	p.closeSection(r)
+	// Errors in a test's fixtures count as failures of the test
+	if len(r.Failures) > r.Failed {
+		r.Failed = len(r.Failures)
+	}
+}
@@ -0,0 +1,261 @@
+// Package testrun runs a project's tests with go test, pytest, jest or
+// cargo test and parses the output into a structured result: counts, the
+// failing tests with their file positions and messages, and trimmed logs.
+package testrun
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Frameworks supported by Run
+const (
+	Go     = "go"
+	Pytest = "pytest"
+	Jest   = "jest"
+	Cargo  = "cargo"
+)
+
+// DefaultTimeout bounds a test run when Options.Timeout is zero
+const DefaultTimeout = 10 * time.Minute
+
+// maxLogLines caps the log lines kept for each failure
+const maxLogLines = 20
+
+// maxFailures caps the failures listed in a summary
+const maxFailures = 30
+
+// Options selects what to run
+type Options struct {
+	Framework string        // Detected from the project when empty
+	Package   string        // Go package pattern, test file or directory; all tests when empty
+	Test      string        // Run only tests matching this name
+	Timeout   time.Duration // DefaultTimeout when zero
+	Progress  func(status string)
+}
+
+// Result is the outcome of a test run
+type Result struct {
+	Framework string
+	Command   string
+	Passed    int
+	Failed    int
+	Skipped   int
+	Failures  []Failure
+	Errors    []string // Build or collection errors that stopped tests from running
+	Duration  time.Duration
+	TimedOut  bool
+}
+
+// Failure is a failed test
+type Failure struct {
+	Name    string // Test name, including its package or file when the framework gives one
+	File    string // Where the failure was reported; empty when unknown
+	Line    int
+	Message string // Assertion or panic message
+	Log     []string
+}
+
+// Ok reports whether every test that ran passed
+func (r *Result) Ok() bool {
+	return r.Failed == 0 && len(r.Errors) == 0 && !r.TimedOut
+}
+
+// parser consumes a framework's output one line at a time
+type parser interface {
+	line(text string, r *Result)
+	finish(r *Result)
+}
+
+// Detect picks the test framework for a project from its manifest files
+func Detect(dir string) (string, error) {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	switch {
+	case exists("go.mod"):
+		return Go, nil
+	case exists("Cargo.toml"):
+		return Cargo, nil
+	case exists("package.json"):
+		return Jest, nil
+	case exists("pytest.ini"), exists("pyproject.toml"), exists("setup.py"), exists("setup.cfg"), exists("requirements.txt"), exists("tox.ini"):
+		return Pytest, nil
+	}
+	return "", fmt.Errorf("no supported test framework found in %s (go, pytest, jest or cargo)", dir)
+}
+
+// command returns the command line and output parser for a run in dir
+func command(dir string, opts Options) ([]string, parser, error) {
+	switch opts.Framework {
+	case Go:
+		return goCommand(opts), newGoParser(dir), nil
+	case Pytest:
+		return pytestCommand(opts), &pytestParser{}, nil
+	case Jest:
+		return jestCommand(dir, opts)
+	case Cargo:
+		return cargoCommand(opts), &cargoParser{}, nil
+	}
+	return nil, nil, fmt.Errorf("unknown test framework %q (use go, pytest, jest or cargo)", opts.Framework)
+}
+
+// Run runs tests in dir and parses the results. The error is for a run that
+// could not start; failing tests are reported in the result.
+func Run(dir string, opts Options) (*Result, error) {
+	if opts.Framework == "" {
+		framework, err := Detect(dir)
+		if err != nil {
+			return nil, err
+		}
+		opts.Framework = framework
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	args, p, err := command(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, fmt.Errorf("%s is not installed: %w", args[0], err)
+	}
+
+	result := &Result{Framework: opts.Framework, Command: strings.Join(args, " ")}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+	go func() {
+		writer.CloseWithError(cmd.Wait())
+	}()
+
+	// Output is parsed as it arrives so progress can be shown while the
+	// tests run
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	reported := ""
+	var tail []string // The last maxLogLines lines, for output the parser does not understand
+	lines := 0
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		p.line(text, result)
+		if status := result.progress(); opts.Progress != nil && status != reported {
+			opts.Progress(status)
+			reported = status
+		}
+		if lines++; len(tail) == maxLogLines {
+			tail = append(tail[1:], text)
+		} else {
+			tail = append(tail, text)
+		}
+	}
+	// A line too long to scan stops the scanner; keep reading so the
+	// command does not block writing the rest
+	io.Copy(io.Discard, reader)
+	p.finish(result)
+	result.Duration = time.Since(start)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		result.TimedOut = true
+	} else if err := scanner.Err(); err != nil && result.Passed+result.Failed+result.Skipped == 0 && len(result.Errors) == 0 {
+		// The command failed without output the parser understood, so its
+		// last lines are all there is to go on
+		log := trimLog(tail)
+		if lines > len(tail) {
+			log = append([]string{fmt.Sprintf("... %d earlier lines", lines-len(tail))}, log...)
+		}
+		result.Errors = append(result.Errors, strings.Join(append([]string{err.Error()}, log...), "\n"))
+	}
+	return result, nil
+}
+
+// progress is the status shown while tests run
+func (r *Result) progress() string {
+	if r.Passed+r.Failed+r.Skipped == 0 {
+		return ""
+	}
+	return fmt.Sprintf("(%d passed, %d failed)", r.Passed, r.Failed)
+}
+
+// Summary renders the result for the model: counts first, then each
+// failure with its position, message and log
+func (r *Result) Summary() string {
+	var sb strings.Builder
+	status := "PASSED"
+	switch {
+	case r.TimedOut:
+		status = "TIMED OUT"
+	case !r.Ok():
+		status = "FAILED"
+	}
+	sb.WriteString(fmt.Sprintf("%s: %s (%d passed, %d failed, %d skipped in %s)\n",
+		r.Command, status, r.Passed, r.Failed, r.Skipped, r.Duration.Round(100*time.Millisecond)))
+	if r.Passed+r.Failed+r.Skipped == 0 && len(r.Errors) == 0 && !r.TimedOut {
+		sb.WriteString("No tests ran.\n")
+	}
+
+	for _, e := range r.Errors {
+		sb.WriteString("\nERROR " + e + "\n")
+	}
+	for i, f := range r.Failures {
+		if i == maxFailures {
+			sb.WriteString(fmt.Sprintf("\n... and %d more failures\n", len(r.Failures)-i))
+			break
+		}
+		sb.WriteString("\nFAIL " + f.Name + "\n")
+		if f.File != "" {
+			sb.WriteString(fmt.Sprintf("  at %s:%d\n", f.File, f.Line))
+		}
+		if f.Message != "" {
+			sb.WriteString("  " + strings.ReplaceAll(f.Message, "\n", "\n  ") + "\n")
+		}
+		if len(f.Log) > 0 {
+			sb.WriteString("  log:\n")
+			for _, line := range f.Log {
+				sb.WriteString("    " + line + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// trimLog keeps the last maxLogLines of a log, dropping blank edges
+func trimLog(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > maxLogLines {
+		trimmed := []string{fmt.Sprintf("... %d earlier lines", len(lines)-maxLogLines)}
+		return append(trimmed, lines[len(lines)-maxLogLines:]...)
+	}
+	return lines
+}
+
+// decodeJSON decodes a line that may be JSON, reporting whether it was
+func decodeJSON(text string, v interface{}) bool {
+	if !strings.HasPrefix(text, "{") {
+		return false
+	}
+	return json.Unmarshal([]byte(text), v) == nil
+}
@@ -0,0 +1,248 @@
+package testrun
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parse feeds recorded output through a parser
+func parse(p parser, output string) *Result {
+	r := &Result{}
+	for _, line := range strings.Split(output, "\n") {
+		p.line(line, r)
+	}
+	p.finish(r)
+	return r
+}
+
+func TestRunGo(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":       "module example.com/calc\n\ngo 1.23\n",
+		"calc/calc.go": "package calc\n\nfunc Add(a, b int) int { return a + b }\n",
+		"calc/calc_test.go": `package calc
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	if got := Add(1, 2); got != 4 {
+		t.Errorf("Add(1, 2) = %d, want 4", got)
+	}
+}
+
+func TestCases(t *testing.T) {
+	t.Run("zero", func(t *testing.T) {})
+	t.Run("neg", func(t *testing.T) {
+		t.Log("checking")
+		t.Fatal("wrong sign")
+	})
+}
+
+func TestSkip(t *testing.T) { t.Skip("later") }
+
+func TestOk(t *testing.T) {}
+`,
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+
+	var progress []string
+	r, err := Run(dir, Options{Progress: func(s string) { progress = append(progress, s) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Framework != Go || r.Passed != 2 || r.Failed != 2 || r.Skipped != 1 {
+		t.Fatalf("result = %+v", r)
+	}
+	if len(r.Failures) != 2 {
+		t.Fatalf("failures = %+v", r.Failures)
+	}
+	add := r.Failures[0]
+	if add.Name != "example.com/calc/calc TestAdd" || add.File != "calc/calc_test.go" || add.Line != 7 || add.Message != "Add(1, 2) = 3, want 4" {
+		t.Errorf("TestAdd failure = %+v", add)
+	}
+	if neg := r.Failures[1]; neg.Name != "example.com/calc/calc TestCases/neg" || neg.Message != "wrong sign" {
+		t.Errorf("subtest failure = %+v", neg)
+	}
+	if len(progress) == 0 {
+		t.Error("no progress reported")
+	}
+
+	summary := r.Summary()
+	for _, want := range []string{"go test -json ./...: FAILED (2 passed, 2 failed, 1 skipped", "FAIL example.com/calc/calc TestAdd\n  at calc/calc_test.go:7\n  Add(1, 2) = 3, want 4"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+
+	// A single test, and a build failure
+	r, _ = Run(dir, Options{Package: "./calc", Test: "TestOk"})
+	if !r.Ok() || r.Passed != 1 || r.Failed != 0 {
+		t.Errorf("single test = %+v", r)
+	}
+	os.WriteFile(filepath.Join(dir, "calc", "calc.go"), []byte("package calc\n\nfunc Add(a, b int) int { return a + c }\n"), 0644)
+	r, _ = Run(dir, Options{})
+	if r.Ok() || len(r.Errors) != 1 || !strings.Contains(r.Errors[0], "undefined: c") {
+		t.Errorf("build failure = %+v", r)
+	}
+}
+
+func TestGoRunPattern(t *testing.T) {
+	for name, want := range map[string]string{"TestAdd": "^TestAdd$", "TestCases/neg": "^TestCases$/^neg$", "Test.*Add": "Test.*Add"} {
+		if got := goRunPattern(name); got != want {
+			t.Errorf("goRunPattern(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestParsePytest(t *testing.T) {
+	output := `..F.s.E                                                                  [100%]
+==================================== ERRORS ====================================
+_________________________ ERROR at setup of test_db ____________________________
+tests/test_db.py:5: in conn
+    raise RuntimeError("no database")
+E   RuntimeError: no database
+=================================== FAILURES ===================================
+_______________________________ TestOrder.test_total _______________________________
+tests/test_order.py:12: in test_total
+    assert total([1, 2]) == 4
+E   assert 3 == 4
+E    +  where 3 = total([1, 2])
+=========================== short test summary info ============================
+FAILED tests/test_order.py::TestOrder::test_total - assert 3 == 4
+ERROR tests/test_db.py::test_db - RuntimeError: no database
+1 failed, 4 passed, 1 skipped, 1 error in 0.12s`
+
+	r := parse(&pytestParser{}, output)
+	if r.Passed != 4 || r.Failed != 2 || r.Skipped != 1 || len(r.Errors) != 0 {
+		t.Fatalf("result = %+v", r)
+	}
+	total := r.Failures[1]
+	if total.Name != "tests/test_order.py::TestOrder::test_total" || total.File != "tests/test_order.py" || total.Line != 12 || total.Message != "assert 3 == 4\n+  where 3 = total([1, 2])" {
+		t.Errorf("failure = %+v", total)
+	}
+	if db := r.Failures[0]; db.Name != "tests/test_db.py::test_db" || db.Message != "RuntimeError: no database" {
+		t.Errorf("setup error = %+v", db)
+	}
+
+	collection := `
+==================================== ERRORS ====================================
+____________________ ERROR collecting tests/test_api.py _____________________
+tests/test_api.py:1: in <module>
+    import missing
+E   ModuleNotFoundError: No module named 'missing'
+=========================== short test summary info ============================
+ERROR tests/test_api.py
+1 error in 0.05s`
+	r = parse(&pytestParser{}, collection)
+	if len(r.Failures) != 0 || len(r.Errors) != 1 || r.Errors[0] != "tests/test_api.py:\nModuleNotFoundError: No module named 'missing'" {
+		t.Errorf("collection error = %+v", r)
+	}
+}
+
+func TestParseJest(t *testing.T) {
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report.json")
+	testFile := filepath.Join(dir, "src", "sum.test.js")
+	os.WriteFile(report, []byte(`{"numPassedTests": 1, "numFailedTests": 1, "numPendingTests": 1, "testResults": [
+		{"name": "`+testFile+`", "message": "", "assertionResults": [
+			{"fullName": "sum adds", "status": "passed", "failureMessages": []},
+			{"fullName": "sum subtracts", "status": "failed", "location": {"line": 8, "column": 3},
+			 "failureMessages": ["Error: expect(received).toBe(expected)\n\nExpected: 1\nReceived: 3\n    at Object.<anonymous> (`+testFile+`:9:21)\n    at Promise.then.completed (node_modules/jest-circus/build/utils.js:298:28)"]}]},
+		{"name": "`+filepath.Join(dir, "src", "bad.test.js")+`", "message": "SyntaxError: Unexpected token (3:4)", "assertionResults": []}
+	]}`), 0644)
+
+	p := &jestParser{dir: dir, report: report}
+	r := parse(p, "PASS src/other.test.js\n  sum\n    ✓ adds (2 ms)\n    ✕ subtracts (3 ms)")
+	if r.Passed != 1 || r.Failed != 1 || r.Skipped != 1 {
+		t.Fatalf("result = %+v", r)
+	}
+	f := r.Failures[0]
+	if f.Name != "src/sum.test.js > sum subtracts" || f.File != "src/sum.test.js" || f.Line != 9 || f.Message != "Error: expect(received).toBe(expected)\n\nExpected: 1\nReceived: 3" {
+		t.Errorf("failure = %+v", f)
+	}
+	if len(r.Errors) != 1 || !strings.HasPrefix(r.Errors[0], "src/bad.test.js:\nSyntaxError") {
+		t.Errorf("errors = %q", r.Errors)
+	}
+	if _, err := os.Stat(report); !os.IsNotExist(err) {
+		t.Error("report not removed")
+	}
+}
+
+func TestParseCargo(t *testing.T) {
+	output := `running 3 tests
+test tests::adds ... ok
+test tests::skipped ... ignored
+test tests::subtracts ... FAILED
+
+failures:
+
+---- tests::subtracts stdout ----
+
+thread 'tests::subtracts' panicked at src/lib.rs:17:9:
+assertion ` + "`left == right`" + ` failed
+  left: 3
+ right: 1
+note: run with ` + "`RUST_BACKTRACE=1`" + ` environment variable to display a backtrace
+
+
+failures:
+    tests::subtracts
+
+test result: FAILED. 1 passed; 1 failed; 1 ignored; 0 measured; 0 filtered out`
+
+	r := parse(&cargoParser{}, output)
+	if r.Passed != 1 || r.Failed != 1 || r.Skipped != 1 || len(r.Failures) != 1 {
+		t.Fatalf("result = %+v", r)
+	}
+	f := r.Failures[0]
+	if f.Name != "tests::subtracts" || f.File != "src/lib.rs" || f.Line != 17 || f.Message != "assertion `left == right` failed\n  left: 3\n right: 1" {
+		t.Errorf("failure = %+v", f)
+	}
+
+	build := "   Compiling calc v0.1.0\nerror[E0425]: cannot find value `c` in this scope\n --> src/lib.rs:2:9\n  |\n\nerror: could not compile `calc`"
+	r = parse(&cargoParser{}, build)
+	if len(r.Errors) != 1 || r.Errors[0] != "src/lib.rs:2: error[E0425]: cannot find value `c` in this scope" {
+		t.Errorf("errors = %q", r.Errors)
+	}
+}
+
+func TestRunUnparsedFailure(t *testing.T) {
+	bin := t.TempDir()
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "pytest.ini"), []byte("[pytest]\n"), 0644)
+
+	// Output the parser does not recognize comes back as its last lines
+	os.WriteFile(filepath.Join(bin, "pytest"), []byte("#!/bin/sh\nfor i in $(seq 1 30); do echo \"setup line $i\"; done\necho 'plugin cov not found'\nexit 4\n"), 0755)
+	r, err := Run(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) != 1 {
+		t.Fatalf("errors = %q", r.Errors)
+	}
+	for _, want := range []string{"exit status 4\n", "... 11 earlier lines\n", "setup line 30\nplugin cov not found"} {
+		if !strings.Contains(r.Errors[0], want) {
+			t.Errorf("error missing %q:\n%s", want, r.Errors[0])
+		}
+	}
+	if strings.Contains(r.Errors[0], "setup line 11\n") {
+		t.Errorf("error keeps more than the last lines:\n%s", r.Errors[0])
+	}
+
+	// A line too long to scan does not leave the command blocked on output
+	done := filepath.Join(dir, "done")
+	os.WriteFile(filepath.Join(bin, "pytest"), []byte("#!/bin/sh\nhead -c 5000000 /dev/zero | tr '\\0' x\necho\nseq 1 100000\ntouch "+done+"\n"), 0755)
+	if _, err := Run(dir, Options{Timeout: 30 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(done); err != nil {
+		t.Error("command did not finish writing its output")
+	}
+}
@@ -0,0 +1,136 @@
+// Package tokens counts tokens for prompts and responses, using the
+// server's tokenizer when it has one and a local estimate otherwise.
+package tokens
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/tara-vision/taracode/internal/provider"
+)
+
+const (
+	// messageOverhead covers chat template tokens around each message
+	// (e.g. <|im_start|>role\n ... <|im_end|>\n)
+	messageOverhead = 4
+	// replyPriming covers the template tokens that open the assistant reply
+	replyPriming = 3
+	// imageTokens is a rough allowance for an attached image; vision models
+	// charge from a few hundred to a few thousand tokens depending on size
+	imageTokens = 1000
+
+	tokenizeTimeout = 5 * time.Second
+	maxCacheEntries = 4096
+)
+
+// Counter counts tokens for a provider's active model. Counts are exact
+// when the server exposes a tokenizer and estimated otherwise; results are
+// cached by content so repeated conversation messages are counted once.
+type Counter struct {
+	tokenizer provider.Tokenizer // nil when the server has no tokenizer
+
+	mu           sync.Mutex
+	cache        map[uint64]int
+	remoteFailed bool // Server tokenizer failed once; stop asking it
+}
+
+// NewCounter creates a counter for the given provider
+func NewCounter(p provider.Provider) *Counter {
+	c := &Counter{cache: make(map[uint64]int)}
+	if t, ok := p.(provider.Tokenizer); ok {
+		c.tokenizer = t
+	}
+	return c
+}
+
+// Exact reports whether counts come from the server's tokenizer
+func (c *Counter) Exact() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokenizer != nil && !c.remoteFailed
+}
+
+// Count returns the number of tokens in text and whether it is exact
+func (c *Counter) Count(ctx context.Context, text string) (int, bool) {
+	if text == "" {
+		return 0, true
+	}
+	if c == nil {
+		return Estimate(text), false
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	key := h.Sum64()
+
+	c.mu.Lock()
+	if n, ok := c.cache[key]; ok {
+		exact := c.tokenizer != nil && !c.remoteFailed
+		c.mu.Unlock()
+		return n, exact
+	}
+	useRemote := c.tokenizer != nil && !c.remoteFailed
+	c.mu.Unlock()
+
+	if useRemote {
+		tctx, cancel := context.WithTimeout(ctx, tokenizeTimeout)
+		n, err := c.tokenizer.CountTokens(tctx, text)
+		cancel()
+		if err == nil {
+			c.store(key, n)
+			return n, true
+		}
+		c.mu.Lock()
+		c.remoteFailed = true
+		c.cache = make(map[uint64]int) // Drop exact counts so results stay consistent
+		c.mu.Unlock()
+	}
+
+	n := Estimate(text)
+	c.store(key, n)
+	return n, false
+}
+
+// CountMessages returns the prompt size of a conversation, including chat
+// template overhead, and whether every part was counted exactly
+func (c *Counter) CountMessages(ctx context.Context, messages []openai.ChatCompletionMessage) (int, bool) {
+	total, exact := replyPriming, true
+	for _, msg := range messages {
+		n, ok := c.CountMessage(ctx, msg)
+		total += n
+		exact = exact && ok
+	}
+	return total, exact
+}
+
+// CountMessage returns the tokens one message adds to the prompt
+func (c *Counter) CountMessage(ctx context.Context, msg openai.ChatCompletionMessage) (int, bool) {
+	n, exact := c.Count(ctx, msg.Content)
+	for _, part := range msg.MultiContent {
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			pn, ok := c.Count(ctx, part.Text)
+			n += pn
+			exact = exact && ok
+		case openai.ChatMessagePartTypeImageURL:
+			n += imageTokens
+			exact = false
+		}
+	}
+	return n + messageOverhead, exact
+}
+
+func (c *Counter) store(key uint64, n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.cache) >= maxCacheEntries {
+		c.cache = make(map[uint64]int)
+	}
+	c.cache[key] = n
+}
@@ -0,0 +1,72 @@
+package tokens
+
+import (
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pretokenizer splits text the way GPT-style byte-level BPE tokenizers
+// (including Qwen and Llama 3) do before merging: contractions, words with
+// their leading space, digit runs, punctuation runs and whitespace
+var pretokenizer = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+`)
+
+// shortWordLen is the longest Latin word assumed to be in the vocabulary
+const shortWordLen = 6
+
+// Estimate guesses the token count of text with a heuristic, not a
+// tokenizer: there is no vocabulary or merge table. Each pre-token is costed
+// by its class: short Latin words are a single token and longer ones about
+// four characters per token, digits are grouped in threes, punctuation in
+// pairs and other scripts cost roughly one token per character. Use it only
+// when the server cannot count tokens.
+func Estimate(text string) int {
+	count := 0
+	for _, piece := range pretokenizer.FindAllString(text, -1) {
+		count += estimatePiece(piece)
+	}
+	return count
+}
+
+func estimatePiece(piece string) int {
+	first, _ := utf8.DecodeRuneInString(piece)
+	body := piece
+	if first == ' ' && len(piece) > 1 {
+		body = piece[1:] // The leading space merges into the word
+		first, _ = utf8.DecodeRuneInString(body)
+	}
+	n := utf8.RuneCountInString(body)
+
+	switch {
+	case unicode.IsLetter(first):
+		if !isLatin(body) {
+			return n
+		}
+		if n <= shortWordLen {
+			return 1
+		}
+		return ceilDiv(n, 4)
+	case unicode.IsDigit(first):
+		return ceilDiv(n, 3)
+	case unicode.IsSpace(first):
+		return ceilDiv(n, 4)
+	default:
+		return ceilDiv(n, 2)
+	}
+}
+
+func isLatin(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxLatin1 && !unicode.Is(unicode.Latin, r) {
+			return false
+		}
+	}
+	return true
+}
+
+func ceilDiv(n, d int) int {
+	if n <= 0 {
+		return 0
+	}
+	return (n + d - 1) / d
+}
@@ -0,0 +1,87 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestEstimate(t *testing.T) {
+	cases := []struct {
+		text     string
+		min, max int
+	}{
+		{"", 0, 0},
+		{"Hello, world!", 3, 5},
+		{"The quick brown fox jumps over the lazy dog.", 9, 13},
+		{"func main() {\n\tfmt.Println(\"hi\")\n}", 10, 20},
+		{"1234567890", 3, 5},
+		{"你好世界", 2, 4},
+	}
+	for _, tc := range cases {
+		if got := Estimate(tc.text); got < tc.min || got > tc.max {
+			t.Errorf("Estimate(%q) = %d, want %d-%d", tc.text, got, tc.min, tc.max)
+		}
+	}
+}
+
+type fakeTokenizer struct {
+	calls int
+	err   error
+}
+
+func (f *fakeTokenizer) CountTokens(ctx context.Context, text string) (int, error) {
+	f.calls++
+	if f.err != nil {
+		return 0, f.err
+	}
+	return len(text), nil
+}
+
+func TestCounterUsesTokenizerAndCaches(t *testing.T) {
+	tok := &fakeTokenizer{}
+	c := &Counter{tokenizer: tok, cache: make(map[uint64]int)}
+
+	for i := 0; i < 2; i++ {
+		n, exact := c.Count(context.Background(), "hello world")
+		if n != 11 || !exact {
+			t.Fatalf("Count = (%d, %v), want (11, true)", n, exact)
+		}
+	}
+	if tok.calls != 1 {
+		t.Errorf("tokenizer called %d times, want 1", tok.calls)
+	}
+}
+
+func TestCounterFallsBackToEstimate(t *testing.T) {
+	tok := &fakeTokenizer{err: errors.New("404")}
+	c := &Counter{tokenizer: tok, cache: make(map[uint64]int)}
+
+	n, exact := c.Count(context.Background(), "hello world")
+	if exact || n != Estimate("hello world") {
+		t.Errorf("Count = (%d, %v), want estimate", n, exact)
+	}
+	c.Count(context.Background(), "another message")
+	if tok.calls != 1 {
+		t.Errorf("tokenizer called %d times after failure, want 1", tok.calls)
+	}
+	if c.Exact() {
+		t.Error("Exact() = true after tokenizer failure")
+	}
+}
+
+func TestCountMessages(t *testing.T) {
+	c := &Counter{tokenizer: &fakeTokenizer{}, cache: make(map[uint64]int)}
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "abc"},
+		{Role: openai.ChatMessageRoleUser, Content: "hello"},
+	}
+
+	n, exact := c.CountMessages(context.Background(), messages)
+	want := replyPriming + 3 + 5 + 2*messageOverhead
+	if n != want || !exact {
+		t.Errorf("CountMessages = (%d, %v), want (%d, true)", n, exact, want)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
-	"path/filepath"
 	"time"
 )
 
@@ -70,79 +69,3 @@ func ExecuteCommand(params map[string]interface{}, workingDir string) (string, e
 
 	return result.String(), nil
 }
-
-func SearchFiles(params map[string]interface{}, workingDir string) (string, error) {
-	pattern, ok := params["pattern"].(string)
-	if !ok {
-		return "", fmt.Errorf("pattern parameter is required")
-	}
-
-	directory := workingDir
-	if dir, ok := params["directory"].(string); ok && dir != "" {
-		if !filepath.IsAbs(dir) {
-			directory = filepath.Join(workingDir, dir)
-		} else {
-			directory = dir
-		}
-	}
-
-	// Build grep command with options
-	args := []string{"-r", "-n", "-H"}
-
-	// Add context lines if specified
-	if contextLines, ok := params["context_lines"]; ok {
-		var ctx int
-		switch v := contextLines.(type) {
-		case int:
-			ctx = v
-		case float64:
-			ctx = int(v)
-		}
-		if ctx > 0 {
-			args = append(args, fmt.Sprintf("-C%d", ctx))
-		}
-	}
-
-	// Add regex flag if specified
-	if useRegex, ok := params["regex"].(bool); ok && useRegex {
-		args = append(args, "-E")
-	}
-
-	// Add file type filters if specified
-	if fileTypes, ok := params["file_types"].([]interface{}); ok && len(fileTypes) > 0 {
-		for _, ft := range fileTypes {
-			if ftStr, ok := ft.(string); ok {
-				args = append(args, "--include=*"+ftStr)
-			}
-		}
-	}
-
-	// Add exclude directories if specified
-	if excludeDirs, ok := params["exclude_dirs"].([]interface{}); ok && len(excludeDirs) > 0 {
-		for _, ed := range excludeDirs {
-			if edStr, ok := ed.(string); ok {
-				args = append(args, "--exclude-dir="+edStr)
-			}
-		}
-	}
-
-	// Add pattern and directory
-	args = append(args, pattern, directory)
-
-	cmd := exec.Command("grep", args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-
-	// grep returns exit code 1 if no matches found, which is not an error
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-			return "No matches found", nil
-		}
-		return "", fmt.Errorf("grep failed: %w\n%s", err, stderr.String())
-	}
-
-	return stdout.String(), nil
-}
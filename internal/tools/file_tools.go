@@ -2,38 +2,97 @@ package tools
 
 import (
 	"fmt"
+	"io/fs"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/tara-vision/taracode/internal/formatter"
+	"github.com/tara-vision/taracode/internal/ignore"
 )
 
+// ReadFile reads a text file, whole or by start_line and end_line, decoding
+// UTF-16 and Latin-1 to UTF-8. Binary files and images are summarized
+// instead, and text files over maxReadSize must be read by range.
 func ReadFile(params map[string]interface{}, workingDir string) (string, error) {
-	filePath, ok := params["file_path"].(string)
+	return readFile(params, workingDir, nil)
+}
+
+// readFile is ReadFile, passing images to attach when it is set instead of
+// summarizing them
+func readFile(params map[string]interface{}, workingDir string, attach func(Image)) (string, error) {
+	name, ok := params["file_path"].(string)
 	if !ok {
 		return "", fmt.Errorf("file_path parameter is required")
 	}
 
 	// Resolve path relative to working directory
+	filePath := name
 	if !filepath.IsAbs(filePath) {
 		filePath = filepath.Join(workingDir, filePath)
 	}
 
-	content, err := os.ReadFile(filePath)
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory: use list_files", name)
+	}
+	head, err := readHead(filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
 
+	// Images are attached for models that can view them, other binary
+	// files only described
+	if mimeType := http.DetectContentType(head); imageTypes[mimeType] != "" {
+		desc := describeFile(filePath, info.Size(), head)
+		switch {
+		case attach == nil:
+			return fmt.Sprintf("%s is an image (%s). Its contents are shown only to models that accept image input", name, desc), nil
+		case info.Size() > maxImageSize:
+			return fmt.Sprintf("%s is an image (%s), too large to attach (over %s)", name, desc, formatSize(maxImageSize)), nil
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		attach(Image{Path: name, MIMEType: mimeType, Data: data})
+		return fmt.Sprintf("Attached %s (%s) for you to view", name, desc), nil
+	}
+	encoding := sniffEncoding(head)
+	if encoding == encodingBinary {
+		return fmt.Sprintf("%s is a binary file (%s). Its contents are not shown", name, describeFile(filePath, info.Size(), head)), nil
+	}
+
 	// Check for line range parameters
 	startLine, hasStart := params["start_line"]
 	endLine, hasEnd := params["end_line"]
+	if !hasStart && !hasEnd && info.Size() > maxReadSize {
+		return "", fmt.Errorf("%s is %s, too large to read whole: read it in parts with start_line and end_line, or find what you need with search_files", name, formatSize(info.Size()))
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	content, encoding := decodeText(data, encoding)
 
 	// If no range specified, return full content
 	if !hasStart && !hasEnd {
-		return string(content), nil
+		if encoding != encodingUTF8 {
+			if !strings.HasSuffix(content, "\n") {
+				content += "\n"
+			}
+			content += fmt.Sprintf("[Decoded from %s; writing the file back saves it as UTF-8]\n", encoding)
+		}
+		return content, nil
 	}
 
 	// Parse line ranges
-	lines := strings.Split(string(content), "\n")
+	lines := strings.Split(content, "\n")
 	totalLines := len(lines)
 
 	var start, end int
@@ -74,7 +133,11 @@ func ReadFile(params map[string]interface{}, workingDir string) (string, error)
 
 	// Add line numbers for context
 	var result strings.Builder
-	result.WriteString(fmt.Sprintf("=== %s (lines %d-%d of %d) ===\n", filepath.Base(filePath), start, end, totalLines))
+	decoded := ""
+	if encoding != encodingUTF8 {
+		decoded = ", " + encoding
+	}
+	result.WriteString(fmt.Sprintf("=== %s (lines %d-%d of %d%s) ===\n", filepath.Base(filePath), start, end, totalLines, decoded))
 	for i, line := range selectedLines {
 		lineNum := start + i
 		result.WriteString(fmt.Sprintf("%4d: %s\n", lineNum, line))
@@ -582,18 +645,17 @@ func ListFiles(params map[string]interface{}, workingDir string) (string, error)
 
 	var result strings.Builder
 
+	matcher := projectMatcher(workingDir, directory)
 	if recursive {
-		err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
+		// Recursive listings leave out ignored files and directories
+		err := matcher.Walk(directory, func(path string, d fs.DirEntry, err error) error {
 			relPath, _ := filepath.Rel(directory, path)
 			if relPath == "." {
 				return nil
 			}
-			if info.IsDir() {
+			if d.IsDir() {
 				result.WriteString(fmt.Sprintf("[DIR]  %s\n", relPath))
-			} else {
+			} else if info, err := d.Info(); err == nil {
 				result.WriteString(fmt.Sprintf("[FILE] %s (%d bytes)\n", relPath, info.Size()))
 			}
 			return nil
@@ -607,7 +669,11 @@ func ListFiles(params map[string]interface{}, workingDir string) (string, error)
 			return "", fmt.Errorf("failed to read directory: %w", err)
 		}
 
+		// A single directory shows ignored entries too, but never hidden ones
 		for _, entry := range entries {
+			if matcher.Hidden(filepath.Join(directory, entry.Name())) {
+				continue
+			}
 			if entry.IsDir() {
 				result.WriteString(fmt.Sprintf("[DIR]  %s\n", entry.Name()))
 			} else {
@@ -646,11 +712,7 @@ func FindFiles(params map[string]interface{}, workingDir string) (string, error)
 	}
 
 	var matches []string
-	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Continue on errors
-		}
-
+	err := projectMatcher(workingDir, directory).Walk(directory, func(path string, d fs.DirEntry, err error) error {
 		// Get relative path
 		relPath, _ := filepath.Rel(directory, path)
 		if relPath == "." {
@@ -661,7 +723,7 @@ func FindFiles(params map[string]interface{}, workingDir string) (string, error)
 		for _, exclude := range excludes {
 			matched, _ := filepath.Match(exclude, filepath.Base(path))
 			if matched || strings.Contains(relPath, exclude) {
-				if info.IsDir() {
+				if d.IsDir() {
 					return filepath.SkipDir
 				}
 				return nil
@@ -669,7 +731,7 @@ func FindFiles(params map[string]interface{}, workingDir string) (string, error)
 		}
 
 		// Check if matches pattern
-		if !info.IsDir() {
+		if !d.IsDir() {
 			matched, _ := filepath.Match(pattern, filepath.Base(path))
 			if matched {
 				matches = append(matches, relPath)
@@ -705,3 +767,28 @@ func FindFiles(params map[string]interface{}, workingDir string) (string, error)
 
 	return result.String(), nil
 }
+
+// projectMatcher returns the ignore rules for walking dir: the project's
+// when dir is inside the working directory, or dir's own otherwise
+func projectMatcher(workingDir, dir string) *ignore.Matcher {
+	rel, err := filepath.Rel(workingDir, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ignore.New(dir)
+	}
+	return ignore.New(workingDir)
+}
+
+// formatEdited runs the project's formatter on the file an edit tool wrote.
+// A formatter failure is returned as an error so syntax mistakes surface at
+// once; the edit itself is kept for the model to fix.
+func formatEdited(result string, params map[string]interface{}, workingDir string) (string, error) {
+	filePath, _ := params["file_path"].(string)
+	name, changed, err := formatter.File(workingDir, resolvePath(workingDir, filePath))
+	if err != nil {
+		return "", fmt.Errorf("%s, but %v. The file was written as given: fix it and check the result", result, err)
+	}
+	if changed {
+		result += fmt.Sprintf(" (formatted with %s)", name)
+	}
+	return result, nil
+}
@@ -3,13 +3,39 @@ package tools
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+
+	"github.com/tara-vision/taracode/internal/ignore"
 )
 
 // GitStatus shows the status of the git repository
 func GitStatus(params map[string]interface{}, workingDir string) (string, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
+	// List what status shows, so hidden files can be left out of it
+	var names []string
+	if out, err := gitOutput(workingDir, "status", "--porcelain", "-z"); err == nil {
+		entries := strings.Split(out, "\x00")
+		for i := 0; i < len(entries); i++ {
+			if len(entries[i]) < 4 {
+				continue
+			}
+			names = append(names, entries[i][3:])
+			if entries[i][0] == 'R' || entries[i][0] == 'C' {
+				i++ // The original path of a rename or copy follows
+				if i < len(entries) {
+					names = append(names, entries[i])
+				}
+			}
+		}
+	}
+
+	args := []string{"status", "--porcelain"}
+	if excludes := hiddenPathspecs(workingDir, names); len(excludes) > 0 {
+		args = append(append(args, "--", ":/"), excludes...)
+	}
+	cmd := exec.Command("git", args...)
 	cmd.Dir = workingDir
 
 	var stdout, stderr bytes.Buffer
@@ -37,14 +63,21 @@ func GitStatus(params map[string]interface{}, workingDir string) (string, error)
 func GitDiff(params map[string]interface{}, workingDir string) (string, error) {
 	args := []string{"diff"}
 
-	// Add file path if specified
-	if filePath, ok := params["file_path"].(string); ok && filePath != "" {
-		args = append(args, filePath)
-	}
-
 	// Add --staged flag if specified
 	if staged, ok := params["staged"].(bool); ok && staged {
-		args = append([]string{"diff", "--staged"}, args[1:]...)
+		args = append(args, "--staged")
+	}
+
+	// Add file path if specified, leaving out the files it holds that are
+	// hidden from the assistant
+	pathspec := ":/"
+	if filePath, ok := params["file_path"].(string); ok && filePath != "" {
+		pathspec = filePath
+	}
+	out, _ := gitOutput(workingDir, append(args, "--name-only", "-z", "--", pathspec)...)
+	excludes := hiddenPathspecs(workingDir, strings.Split(out, "\x00"))
+	if pathspec != ":/" || len(excludes) > 0 {
+		args = append(append(args, "--", pathspec), excludes...)
 	}
 
 	cmd := exec.Command("git", args...)
@@ -163,3 +196,38 @@ func GitBranch(params map[string]interface{}, workingDir string) (string, error)
 
 	return stdout.String(), nil
 }
+
+// gitOutput runs git in workingDir and returns its standard output
+func gitOutput(workingDir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = workingDir
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// hiddenPathspecs returns pathspecs excluding the files among names, paths
+// relative to the repository root as git prints them, that the project's
+// .taracodeignore hides from the assistant
+func hiddenPathspecs(workingDir string, names []string) []string {
+	if _, err := os.Stat(filepath.Join(workingDir, ignore.FileName)); err != nil {
+		return nil
+	}
+	prefix, err := gitOutput(workingDir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil
+	}
+	prefix = strings.TrimSpace(prefix)
+
+	matcher := ignore.New(workingDir)
+	var excludes []string
+	for _, name := range names {
+		rel, ok := strings.CutPrefix(name, prefix)
+		if name == "" || !ok {
+			continue
+		}
+		if matcher.Hidden(filepath.Join(workingDir, filepath.FromSlash(rel))) {
+			excludes = append(excludes, ":(top,exclude,literal)"+name)
+		}
+	}
+	return excludes
+}
@@ -0,0 +1,323 @@
+package tools
+
+import (
+	"fmt"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tara-vision/taracode/internal/gocode"
+)
+
+// maxGoReferences caps the references listed by go_references
+const maxGoReferences = 200
+
+// resolvePath makes a tool path parameter absolute
+func resolvePath(workingDir, path string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.Join(workingDir, path)
+	}
+	return path
+}
+
+// displayPath shortens a path relative to the working directory when it
+// lies inside it
+func displayPath(workingDir, path string) string {
+	if rel, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// intParam reads an optional numeric parameter
+func intParam(params map[string]interface{}, name string) int {
+	switch v := params[name].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// GoSymbols lists the functions, methods, types, variables and constants
+// of a Go file or package directory with signatures and line ranges
+func GoSymbols(params map[string]interface{}, workingDir string) (string, error) {
+	path, _ := params["path"].(string)
+	if path == "" {
+		path = "."
+	}
+
+	symbols, err := gocode.Symbols(resolvePath(workingDir, path))
+	if err != nil {
+		return "", fmt.Errorf("failed to list symbols: %w", err)
+	}
+	if len(symbols) == 0 {
+		return fmt.Sprintf("No declarations in %s", path), nil
+	}
+
+	var result strings.Builder
+	for _, s := range symbols {
+		s.File = displayPath(workingDir, s.File)
+		result.WriteString(s.String() + "\n")
+	}
+	return result.String(), nil
+}
+
+// goObjects resolves the object a go_definition or go_references call is
+// about: a symbol name such as "Server.Start", or the identifier at a
+// file position given by line and either column or name
+func goObjects(ws *gocode.Workspace, params map[string]interface{}, workingDir string) ([]types.Object, error) {
+	if file, ok := params["file"].(string); ok && file != "" {
+		line := intParam(params, "line")
+		column := intParam(params, "column")
+		name, _ := params["name"].(string)
+		if line == 0 || (column == 0 && name == "") {
+			return nil, fmt.Errorf("with file, give line and either column or name")
+		}
+		obj, err := ws.ObjectAt(resolvePath(workingDir, file), line, column, name)
+		if err != nil {
+			return nil, err
+		}
+		return []types.Object{obj}, nil
+	}
+
+	symbol, ok := params["symbol"].(string)
+	if !ok || symbol == "" {
+		return nil, fmt.Errorf("symbol parameter is required (or file, line and name)")
+	}
+	objs := ws.Lookup(symbol)
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("no declaration named %s in module %s", symbol, ws.Module)
+	}
+	return objs, nil
+}
+
+// GoDefinition shows where a Go symbol is declared along with its source
+func GoDefinition(params map[string]interface{}, workingDir string) (string, error) {
+	ws, err := gocode.Load(workingDir)
+	if err != nil {
+		return "", err
+	}
+	objs, err := goObjects(ws, params, workingDir)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	for i, obj := range objs {
+		if i > 0 {
+			result.WriteString("\n")
+		}
+		loc, err := ws.Definition(obj)
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(fmt.Sprintf("%s\n%s:%d\n", ws.Describe(obj), displayPath(workingDir, loc.File), loc.Line))
+		if source, ok := gocode.DeclAt(loc.File, loc.Line); ok {
+			result.WriteString("\n" + source + "\n")
+		}
+	}
+	return result.String(), nil
+}
+
+// GoReferences lists every use of a Go symbol across the module
+func GoReferences(params map[string]interface{}, workingDir string) (string, error) {
+	ws, err := gocode.Load(workingDir)
+	if err != nil {
+		return "", err
+	}
+	objs, err := goObjects(ws, params, workingDir)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	for _, obj := range objs {
+		refs := ws.References(obj)
+		result.WriteString(fmt.Sprintf("%d references to %s:\n", len(refs), ws.Describe(obj)))
+		for i, ref := range refs {
+			if i == maxGoReferences {
+				result.WriteString(fmt.Sprintf("... and %d more\n", len(refs)-i))
+				break
+			}
+			ref.File = displayPath(workingDir, ref.File)
+			result.WriteString(ref.String() + "\n")
+		}
+	}
+	return result.String(), nil
+}
+
+// GoReplaceSymbol replaces a whole function, method, type, variable or
+// constant declaration, found by name, with new source
+func GoReplaceSymbol(params map[string]interface{}, workingDir string) (string, error) {
+	symbol, ok := params["symbol"].(string)
+	if !ok || symbol == "" {
+		return "", fmt.Errorf("symbol parameter is required")
+	}
+	content, ok := params["content"].(string)
+	if !ok || strings.TrimSpace(content) == "" {
+		return "", fmt.Errorf("content parameter is required")
+	}
+	file, _ := params["file"].(string)
+	if file != "" {
+		file = resolvePath(workingDir, file)
+	}
+
+	decls, err := gocode.FindDecls(workingDir, file, symbol)
+	if err != nil {
+		return "", err
+	}
+	switch len(decls) {
+	case 0:
+		return "", fmt.Errorf("no declaration named %s", symbol)
+	case 1:
+	default:
+		var found []string
+		for _, d := range decls {
+			found = append(found, fmt.Sprintf("%s:%d", displayPath(workingDir, d.File), d.StartLine))
+		}
+		return "", fmt.Errorf("%s is declared %d times (%s): pass file to choose one", symbol, len(decls), strings.Join(found, ", "))
+	}
+
+	d := decls[0]
+	updated, err := gocode.ReplaceDecl(d, content)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(d.File)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(d.File, updated, info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	return fmt.Sprintf("Replaced %s %s in %s (was lines %d-%d)", d.Kind, d.Name, displayPath(workingDir, d.File), d.StartLine, d.EndLine), nil
+}
+
+// applyGoChange type-checks a refactoring, writes it and returns its diff
+func applyGoChange(ws *gocode.Workspace, change *gocode.Change, summary string) (string, error) {
+	if len(change.Files()) == 0 {
+		if len(change.Notes) > 0 {
+			return "No changes\n" + strings.Join(change.Notes, "\n"), nil
+		}
+		return "No changes", nil
+	}
+	if err := change.Verify(ws); err != nil {
+		return "", err
+	}
+	if err := change.Apply(); err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	result.WriteString(summary + "\n")
+	for _, note := range change.Notes {
+		result.WriteString(note + "\n")
+	}
+	result.WriteString("\n" + change.Diff())
+	return result.String(), nil
+}
+
+// GoRename renames a Go identifier and every reference to it across the
+// module, refusing renames that would break the build
+func GoRename(params map[string]interface{}, workingDir string) (string, error) {
+	newName, ok := params["new_name"].(string)
+	if !ok || newName == "" {
+		return "", fmt.Errorf("new_name parameter is required")
+	}
+
+	ws, err := gocode.Load(workingDir)
+	if err != nil {
+		return "", err
+	}
+	objs, err := goObjects(ws, params, workingDir)
+	if err != nil {
+		return "", err
+	}
+	if len(objs) > 1 {
+		var found []string
+		for _, obj := range objs {
+			loc, _ := ws.Definition(obj)
+			found = append(found, fmt.Sprintf("%s:%d", displayPath(workingDir, loc.File), loc.Line))
+		}
+		return "", fmt.Errorf("%d declarations match (%s): give file, line and name to choose one", len(objs), strings.Join(found, ", "))
+	}
+
+	change, err := gocode.Rename(ws, objs[0], newName)
+	if err != nil {
+		return "", err
+	}
+	return applyGoChange(ws, change, fmt.Sprintf("Renamed %s to %s in %d files", objs[0].Name(), newName, len(change.Files())))
+}
+
+// GoOrganizeImports removes unused imports from Go files and adds missing
+// ones, for a file or every file of a package directory
+func GoOrganizeImports(params map[string]interface{}, workingDir string) (string, error) {
+	path, _ := params["path"].(string)
+	if path == "" {
+		return "", fmt.Errorf("path parameter is required (a Go file or package directory)")
+	}
+	path = resolvePath(workingDir, path)
+
+	files := []string{path}
+	if info, err := os.Stat(path); err != nil {
+		return "", err
+	} else if info.IsDir() {
+		matches, err := filepath.Glob(filepath.Join(path, "*.go"))
+		if err != nil || len(matches) == 0 {
+			return "", fmt.Errorf("no Go files in %s", displayPath(workingDir, path))
+		}
+		files = matches
+	}
+
+	ws, err := gocode.Load(workingDir)
+	if err != nil {
+		return "", err
+	}
+	change, err := gocode.OrganizeImports(ws, files...)
+	if err != nil {
+		return "", err
+	}
+	return applyGoChange(ws, change, fmt.Sprintf("Organized imports in %d files", len(change.Files())))
+}
+
+// GoMoveDecl moves a top-level Go declaration to another file of the same
+// package, fixing the imports of both files
+func GoMoveDecl(params map[string]interface{}, workingDir string) (string, error) {
+	symbol, ok := params["symbol"].(string)
+	if !ok || symbol == "" {
+		return "", fmt.Errorf("symbol parameter is required")
+	}
+	to, ok := params["to"].(string)
+	if !ok || to == "" {
+		return "", fmt.Errorf("to parameter is required (the destination file)")
+	}
+	file, _ := params["file"].(string)
+	if file != "" {
+		file = resolvePath(workingDir, file)
+	}
+
+	decls, err := gocode.FindDecls(workingDir, file, symbol)
+	if err != nil {
+		return "", err
+	}
+	switch len(decls) {
+	case 0:
+		return "", fmt.Errorf("no declaration named %s", symbol)
+	case 1:
+	default:
+		return "", fmt.Errorf("%s is declared %d times: pass file to choose one", symbol, len(decls))
+	}
+
+	ws, err := gocode.Load(workingDir)
+	if err != nil {
+		return "", err
+	}
+	change, err := gocode.MoveDecl(ws, decls[0], resolvePath(workingDir, to))
+	if err != nil {
+		return "", err
+	}
+	return applyGoChange(ws, change, fmt.Sprintf("Moved %s %s to %s", decls[0].Kind, decls[0].Name, to))
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+// memoryStores returns the project and user memory stores. The user store
+// is nil when the home directory cannot be found.
+func memoryStores(workingDir string) []*storage.MemoryStore {
+	stores := []*storage.MemoryStore{storage.ProjectMemory(workingDir)}
+	if user, err := storage.UserMemory(); err == nil {
+		stores = append(stores, user)
+	}
+	return stores
+}
+
+// stringList reads a parameter given as a JSON array or comma-separated string
+func stringList(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+	case string:
+		items = strings.Split(v, ",")
+	}
+
+	var list []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Remember stores a fact in project memory, or in user memory with
+// scope "user", so it is included in future sessions
+func Remember(params map[string]interface{}, workingDir string) (string, error) {
+	content, ok := params["content"].(string)
+	if !ok || strings.TrimSpace(content) == "" {
+		return "", fmt.Errorf("content parameter is required")
+	}
+
+	scope, _ := params["scope"].(string)
+	var store *storage.MemoryStore
+	switch storage.MemoryScope(scope) {
+	case "", storage.MemoryProject:
+		store = storage.ProjectMemory(workingDir)
+	case storage.MemoryUser:
+		user, err := storage.UserMemory()
+		if err != nil {
+			return "", err
+		}
+		store = user
+	default:
+		return "", fmt.Errorf("scope must be \"project\" or \"user\"")
+	}
+
+	memory, err := store.Add(content, stringList(params["tags"]))
+	if err != nil {
+		return "", fmt.Errorf("failed to save memory: %w", err)
+	}
+	return fmt.Sprintf("Remembered in %s memory [%s]: %s", store.Scope(), memory.ID, memory.Content), nil
+}
+
+// Forget deletes a memory by ID from either scope
+func Forget(params map[string]interface{}, workingDir string) (string, error) {
+	id, ok := params["id"].(string)
+	if !ok || id == "" {
+		return "", fmt.Errorf("id parameter is required (use recall to find it)")
+	}
+
+	for _, store := range memoryStores(workingDir) {
+		memory, err := store.Remove(id)
+		if err != nil {
+			return "", fmt.Errorf("failed to remove memory: %w", err)
+		}
+		if memory != nil {
+			return fmt.Sprintf("Forgot %s memory [%s]: %s", store.Scope(), memory.ID, memory.Content), nil
+		}
+	}
+	return "", fmt.Errorf("no memory with id %s", id)
+}
+
+// Recall lists the memories of both scopes matching an optional query
+func Recall(params map[string]interface{}, workingDir string) (string, error) {
+	query, _ := params["query"].(string)
+
+	var result strings.Builder
+	found := 0
+	for _, store := range memoryStores(workingDir) {
+		memories, err := store.List()
+		if err != nil {
+			return "", fmt.Errorf("failed to read memories: %w", err)
+		}
+		for _, m := range memories {
+			if !m.Matches(query) {
+				continue
+			}
+			found++
+			result.WriteString(fmt.Sprintf("[%s] (%s) %s", m.ID, m.Scope, m.Content))
+			if len(m.Tags) > 0 {
+				result.WriteString(fmt.Sprintf(" #%s", strings.Join(m.Tags, " #")))
+			}
+			result.WriteString("\n")
+		}
+	}
+
+	if found == 0 {
+		if query != "" {
+			return fmt.Sprintf("No memories matching %q", query), nil
+		}
+		return "No memories saved", nil
+	}
+	return result.String(), nil
+}
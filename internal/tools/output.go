@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultOutputLimit is the size in bytes of the largest tool result passed
+// on whole (see SetOutputLimit)
+const DefaultOutputLimit = 32 * 1024
+
+// maxSavedOutputs is how many full results the output directory keeps; the
+// oldest are removed first
+const maxSavedOutputs = 50
+
+// outputHints say how to get a narrower result from tools whose results
+// are commonly truncated
+var outputHints = map[string]string{
+	"list_files":      "list a subdirectory, or set recursive to false",
+	"find_files":      "use a narrower pattern or directory",
+	"search_files":    "narrow the pattern, directory or file_types, or lower max_results",
+	"execute_command": "filter the command's output, e.g. with grep, head or tail",
+	"git_diff":        "pass file_path to diff one file",
+	"git_log":         "lower limit",
+}
+
+// SetOutputLimit sets the size in bytes of the largest tool result passed
+// on whole. Longer results keep their beginning and end around a notice of
+// what was left out; 0 turns the limit off.
+func (r *Registry) SetOutputLimit(limit int) {
+	r.outputLimit = limit
+}
+
+// SetOutputDir sets where the full text of truncated results is saved for
+// the model to read in parts. Without one, nothing is saved.
+func (r *Registry) SetOutputDir(dir string) {
+	r.outputDir = dir
+}
+
+// limitOutput truncates a tool's result to the output limit. The notice
+// in place of the left-out lines says how to get them: read_file results
+// point back at the file itself, others at a saved copy of the full result.
+func (r *Registry) limitOutput(name string, params map[string]interface{}, result, workingDir string) string {
+	if r.outputLimit <= 0 || len(result) <= r.outputLimit {
+		return result
+	}
+	head, tail := cutOutput(result, r.outputLimit)
+
+	omitted := fmt.Sprintf("%d bytes", len(result)-len(head)-len(tail))
+	total := countLines(result)
+	from, to := strings.Count(head, "\n")+1, total-countLines(tail)
+	if to >= from {
+		omitted = fmt.Sprintf("lines %d-%d of %d (%s)", from, to, total, omitted)
+	}
+
+	var more []string
+	if name == "read_file" {
+		filePath, _ := params["file_path"].(string)
+		_, ranged := params["start_line"]
+		if _, hasEnd := params["end_line"]; ranged || hasEnd {
+			more = append(more, "request a smaller start_line/end_line range")
+		} else {
+			more = append(more, fmt.Sprintf("read the omitted lines of %s with start_line and end_line", filePath))
+		}
+	} else {
+		if hint := outputHints[name]; hint != "" {
+			more = append(more, hint)
+		}
+		if path, err := r.saveOutput(name, result, workingDir); err == nil {
+			more = append(more, fmt.Sprintf("the full output is saved in %s: read it in parts with read_file start_line and end_line", path))
+		}
+	}
+
+	notice := fmt.Sprintf("[... output truncated: %s omitted", omitted)
+	if len(more) > 0 {
+		notice += ". To see more, " + strings.Join(more, "; or ")
+	}
+	if !strings.HasSuffix(head, "\n") {
+		head += "\n"
+	}
+	return head + notice + " ...]\n" + tail
+}
+
+// cutOutput returns the beginning and end of s that fit in limit bytes,
+// two thirds to the beginning, cut at line breaks where lines are short
+// enough
+func cutOutput(s string, limit int) (head, tail string) {
+	headSize := limit * 2 / 3
+	head = s[:headSize]
+	if i := strings.LastIndexByte(head, '\n'); i >= 0 {
+		head = head[:i+1]
+	} else {
+		for headSize > 0 && !utf8.RuneStart(s[headSize]) {
+			headSize--
+		}
+		head = s[:headSize]
+	}
+
+	tailStart := len(s) - (limit - headSize)
+	tail = s[tailStart:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	} else {
+		for tailStart < len(s) && !utf8.RuneStart(s[tailStart]) {
+			tailStart++
+		}
+		tail = s[tailStart:]
+	}
+	return head, tail
+}
+
+// countLines counts lines, including a last one without a line break
+func countLines(s string) int {
+	n := strings.Count(s, "\n")
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		n++
+	}
+	return n
+}
+
+// saveOutput writes a full result to the output directory, named by tool
+// and content so an identical result is saved once, and returns its path
+// relative to workingDir when it is inside it
+func (r *Registry) saveOutput(name, result, workingDir string) (string, error) {
+	if r.outputDir == "" {
+		return "", fmt.Errorf("no output directory")
+	}
+	if err := os.MkdirAll(r.outputDir, 0755); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(result))
+	path := filepath.Join(r.outputDir, fmt.Sprintf("%s-%x.txt", name, sum[:6]))
+	if err := os.WriteFile(path, []byte(result), 0644); err != nil {
+		return "", err
+	}
+	pruneOutputs(r.outputDir)
+
+	if rel, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel), nil
+	}
+	return path, nil
+}
+
+// pruneOutputs removes the oldest saved results past maxSavedOutputs
+func pruneOutputs(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) <= maxSavedOutputs {
+		return
+	}
+	type saved struct {
+		path string
+		mod  int64
+	}
+	var files []saved
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			files = append(files, saved{filepath.Join(dir, entry.Name()), info.ModTime().UnixNano()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mod > files[j].mod })
+	for _, f := range files[min(len(files), maxSavedOutputs):] {
+		os.Remove(f.path)
+	}
+}
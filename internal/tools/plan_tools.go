@@ -0,0 +1,202 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tara-vision/taracode/internal/storage"
+)
+
+// RegisterPlanTools adds the task plan tools. They share the assistant's
+// storage manager, which tracks the active plan, so they are registered
+// only when project storage is available.
+func (r *Registry) RegisterPlanTools(plans *storage.Manager) {
+	r.RegisterTool("plan_create", planCreate(plans))
+	r.RegisterTool("plan_update_task", planUpdateTask(plans))
+	r.RegisterTool("plan_add_task", planAddTask(plans))
+	r.RegisterTool("plan_complete", planComplete(plans))
+}
+
+// planTasks reads a tasks parameter: a list of strings or of objects with
+// content, optional notes and optional subtasks
+func planTasks(value interface{}) ([]storage.Task, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("tasks must be a list")
+	}
+
+	var tasks []storage.Task
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			tasks = append(tasks, storage.NewTask(v, ""))
+		case map[string]interface{}:
+			content, _ := v["content"].(string)
+			if content == "" {
+				return nil, fmt.Errorf("each task needs content")
+			}
+			notes, _ := v["notes"].(string)
+			var subTasks []storage.Task
+			if sub, ok := v["subtasks"]; ok {
+				var err error
+				if subTasks, err = planTasks(sub); err != nil {
+					return nil, err
+				}
+			}
+			tasks = append(tasks, storage.NewTask(content, notes, subTasks...))
+		default:
+			return nil, fmt.Errorf("each task must be a string or an object with content")
+		}
+	}
+	return tasks, nil
+}
+
+// taskRef reads a task number given as "2.1" or as a JSON number
+func taskRef(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	}
+	return ""
+}
+
+// planSummary renders the plan for a tool result
+func planSummary(plan *storage.Plan) string {
+	done, total := plan.Progress()
+	return fmt.Sprintf("Plan: %s (%d/%d done)\n%s", plan.Title, done, total, plan.Outline())
+}
+
+func planCreate(plans *storage.Manager) ToolExecutor {
+	return func(params map[string]interface{}, workingDir string) (string, error) {
+		title, ok := params["title"].(string)
+		if !ok || title == "" {
+			return "", fmt.Errorf("title parameter is required")
+		}
+		tasks, err := planTasks(params["tasks"])
+		if err != nil {
+			return "", err
+		}
+		if len(tasks) == 0 {
+			return "", fmt.Errorf("tasks parameter is required")
+		}
+		description, _ := params["description"].(string)
+
+		// A new plan replaces the active one, which is kept in the archive
+		var result strings.Builder
+		if previous, _ := plans.GetActivePlan(); previous != nil {
+			if err := plans.ArchivePlan(previous.ID); err != nil {
+				return "", fmt.Errorf("failed to archive previous plan: %w", err)
+			}
+			result.WriteString(fmt.Sprintf("Archived previous plan: %s\n", previous.Title))
+		}
+
+		plan, err := plans.CreatePlanWithTasks(title, description, tasks)
+		if err != nil {
+			return "", fmt.Errorf("failed to create plan: %w", err)
+		}
+		result.WriteString(planSummary(plan))
+		return result.String(), nil
+	}
+}
+
+func planUpdateTask(plans *storage.Manager) ToolExecutor {
+	return func(params map[string]interface{}, workingDir string) (string, error) {
+		ref := taskRef(params["task"])
+		if ref == "" {
+			return "", fmt.Errorf("task parameter is required (a task number like 2 or 2.1)")
+		}
+		statusName, hasStatus := params["status"].(string)
+		notes, hasNotes := params["notes"].(string)
+		if !hasStatus && !hasNotes {
+			return "", fmt.Errorf("status or notes parameter is required")
+		}
+
+		var status storage.TaskStatus
+		if hasStatus {
+			var err error
+			if status, err = storage.ParseTaskStatus(statusName); err != nil {
+				return "", err
+			}
+		}
+
+		plan, err := plans.UpdateActivePlan(func(plan *storage.Plan) error {
+			task, _, err := plan.TaskAt(ref)
+			if err != nil {
+				return err
+			}
+			if hasNotes {
+				task.Notes = notes
+			}
+			if hasStatus {
+				_, err = plan.SetTaskStatus(ref, status)
+			}
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Updated task %s\n%s", ref, planSummary(plan)), nil
+	}
+}
+
+func planAddTask(plans *storage.Manager) ToolExecutor {
+	return func(params map[string]interface{}, workingDir string) (string, error) {
+		content, ok := params["content"].(string)
+		if !ok || content == "" {
+			return "", fmt.Errorf("content parameter is required")
+		}
+		notes, _ := params["notes"].(string)
+		parentRef := taskRef(params["parent"])
+
+		var added string
+		plan, err := plans.UpdateActivePlan(func(plan *storage.Plan) error {
+			task := storage.NewTask(content, notes)
+			if parentRef == "" {
+				plan.Tasks = append(plan.Tasks, task)
+				added = strconv.Itoa(len(plan.Tasks))
+				return nil
+			}
+			parent, _, err := plan.TaskAt(parentRef)
+			if err != nil {
+				return err
+			}
+			parent.SubTasks = append(parent.SubTasks, task)
+			added = fmt.Sprintf("%s.%d", parentRef, len(parent.SubTasks))
+			// An open subtask reopens a finished parent
+			if parent.Status == storage.TaskStatusCompleted {
+				parent.Status, parent.CompletedAt = storage.TaskStatusInProgress, nil
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Added task %s\n%s", added, planSummary(plan)), nil
+	}
+}
+
+func planComplete(plans *storage.Manager) ToolExecutor {
+	return func(params map[string]interface{}, workingDir string) (string, error) {
+		plan, err := plans.GetActivePlan()
+		if err != nil {
+			return "", err
+		}
+		if plan == nil {
+			return "", fmt.Errorf("no active plan")
+		}
+		if err := plans.CompletePlan(plan.ID); err != nil {
+			return "", fmt.Errorf("failed to complete plan: %w", err)
+		}
+
+		done, total := plan.Progress()
+		if done < total {
+			return fmt.Sprintf("Completed plan %s with %d of %d tasks still open", plan.Title, total-done, total), nil
+		}
+		return fmt.Sprintf("Completed plan %s (%d tasks)", plan.Title, total), nil
+	}
+}
@@ -0,0 +1,214 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	maxReadSize  = 256 * 1024      // Larger text files are read by line range
+	maxImageSize = 5 * 1024 * 1024 // Larger images are described instead of attached
+	sniffSize    = 8000            // Bytes examined to tell text from binary
+)
+
+// Text encodings read_file decodes to UTF-8
+const (
+	encodingUTF8    = "UTF-8"
+	encodingUTF16LE = "UTF-16LE"
+	encodingUTF16BE = "UTF-16BE"
+	encodingLatin1  = "Latin-1"
+	encodingBinary  = "binary"
+)
+
+// Image is an image file read_file attaches for a model that accepts image
+// input
+type Image struct {
+	Path     string // As given to read_file
+	MIMEType string
+	Data     []byte
+}
+
+// DataURL returns the image as a data: URL, as image_url message parts
+// carry it
+func (img Image) DataURL() string {
+	return "data:" + img.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// imageTypes are the image formats vision models accept, by MIME type
+var imageTypes = map[string]string{
+	"image/png":  "PNG",
+	"image/jpeg": "JPEG",
+	"image/gif":  "GIF",
+	"image/webp": "WebP",
+}
+
+// binaryTypes name common binary formats http.DetectContentType does not
+// know, by their leading bytes
+var binaryTypes = []struct {
+	magic string
+	name  string
+}{
+	{"\x7fELF", "ELF executable"},
+	{"\xcf\xfa\xed\xfe", "Mach-O executable"},
+	{"\xce\xfa\xed\xfe", "Mach-O executable"},
+	{"MZ", "Windows executable"},
+	{"SQLite format 3\x00", "SQLite database"},
+	{"!<arch>\n", "ar archive"},
+}
+
+// sniffEncoding tells text from binary by a file's first bytes, and the
+// text's encoding: UTF-16 by its byte order mark or by the zero bytes of
+// mostly-ASCII text, binary by other zero bytes or many control characters.
+// Text that is not valid UTF-8 is found when it is decoded.
+func sniffEncoding(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xff, 0xfe}):
+		return encodingUTF16LE
+	case bytes.HasPrefix(head, []byte{0xfe, 0xff}):
+		return encodingUTF16BE
+	}
+
+	var zeros [2]int
+	controls := 0
+	for i, b := range head {
+		switch {
+		case b == 0:
+			zeros[i%2]++
+		case b < 0x20 && !strings.ContainsRune("\t\n\v\f\r\x1b", rune(b)):
+			controls++
+		}
+	}
+	if pairs := len(head) / 2; pairs > 0 {
+		if zeros[1] > pairs*4/10 && zeros[0] <= pairs/20 {
+			return encodingUTF16LE
+		}
+		if zeros[0] > pairs*4/10 && zeros[1] <= pairs/20 {
+			return encodingUTF16BE
+		}
+	}
+	if zeros[0]+zeros[1] > 0 || controls > len(head)/10 {
+		return encodingBinary
+	}
+	return encodingUTF8
+}
+
+// decodeText converts text in the sniffed encoding to UTF-8, falling back
+// to Latin-1 for text that is not valid UTF-8, and returns the encoding it
+// was decoded from
+func decodeText(data []byte, encoding string) (string, string) {
+	switch encoding {
+	case encodingUTF16LE, encodingUTF16BE:
+		var order binary.ByteOrder = binary.LittleEndian
+		if encoding == encodingUTF16BE {
+			order = binary.BigEndian
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		if len(units) > 0 && units[0] == 0xfeff {
+			units = units[1:]
+		}
+		return string(utf16.Decode(units)), encoding
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data), encodingUTF8
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes), encodingLatin1
+}
+
+// describeFile summarizes a file whose contents are not shown: its type,
+// dimensions for images, size and SHA-256
+func describeFile(path string, size int64, head []byte) string {
+	kind := http.DetectContentType(head)
+	if name, ok := imageTypes[kind]; ok {
+		kind = name + " image"
+		if f, err := os.Open(path); err == nil {
+			if cfg, _, err := image.DecodeConfig(f); err == nil {
+				kind += fmt.Sprintf(", %dx%d", cfg.Width, cfg.Height)
+			}
+			f.Close()
+		}
+	} else {
+		for _, t := range binaryTypes {
+			if bytes.HasPrefix(head, []byte(t.magic)) {
+				kind = t.name
+				break
+			}
+		}
+		kind = strings.TrimSuffix(kind, "; charset=utf-8")
+	}
+
+	desc := fmt.Sprintf("%s, %s", kind, formatSize(size))
+	if f, err := os.Open(path); err == nil {
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err == nil {
+			desc += fmt.Sprintf(", sha256 %x", h.Sum(nil))
+		}
+		f.Close()
+	}
+	return desc
+}
+
+// formatSize renders a byte count in B, KB or MB
+func formatSize(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+}
+
+// readHead returns the first sniffSize bytes of a file
+func readHead(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// SetVision lets read_file attach images for a model that accepts image
+// input, or makes it describe them instead
+func (r *Registry) SetVision(vision bool) {
+	r.vision = vision
+}
+
+// TakeImages returns the images attached since it was last called
+func (r *Registry) TakeImages() []Image {
+	images := r.images
+	r.images = nil
+	return images
+}
+
+func (r *Registry) attachImage(img Image) {
+	r.images = append(r.images, img)
+}
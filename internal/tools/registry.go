@@ -2,22 +2,94 @@ package tools
 
 import (
 	"fmt"
+
+	"github.com/tara-vision/taracode/internal/ignore"
 )
 
 type ToolExecutor func(params map[string]interface{}, workingDir string) (string, error)
 
 type Registry struct {
-	tools map[string]ToolExecutor
+	tools       map[string]ToolExecutor
+	readOnly    bool                // Refuse tools that change the workspace (see SetReadOnly)
+	progress    func(status string) // Status updates from long-running tools (see SetProgress)
+	outputLimit int                 // Largest result in bytes passed on whole (see SetOutputLimit)
+	outputDir   string              // Where full truncated results are saved (see SetOutputDir)
+	vision      bool                // read_file attaches images (see SetVision)
+	images      []Image             // Attached images not yet taken (see TakeImages)
+}
+
+// readOnlyTools can run while the registry is read-only. They inspect the
+// workspace without changing it; the plan tools only write the plan itself,
+// which is what read-only planning produces.
+var readOnlyTools = map[string]bool{
+	"read_file":        true,
+	"list_files":       true,
+	"find_files":       true,
+	"search_files":     true,
+	"semantic_search":  true,
+	"git_status":       true,
+	"git_diff":         true,
+	"git_log":          true,
+	"git_branch":       true,
+	"go_symbols":       true,
+	"go_definition":    true,
+	"go_references":    true,
+	"recall":           true,
+	"plan_create":      true,
+	"plan_add_task":    true,
+	"plan_update_task": true,
+}
+
+// editTools change the file named by their file_path parameter, which is
+// formatted after each successful edit
+var editTools = map[string]bool{
+	"write_file":    true,
+	"append_file":   true,
+	"edit_file":     true,
+	"insert_lines":  true,
+	"replace_lines": true,
+	"delete_lines":  true,
+}
+
+// fileTools change files in the workspace: the edit tools and the file
+// management and Go refactoring tools
+var fileTools = map[string]bool{
+	"copy_file":           true,
+	"move_file":           true,
+	"delete_file":         true,
+	"go_replace_symbol":   true,
+	"go_rename":           true,
+	"go_organize_imports": true,
+	"go_move_decl":        true,
+}
+
+// pathParams are the parameters tools take file and directory paths in
+var pathParams = []string{"file_path", "source_path", "dest_path", "path", "directory", "file", "to"}
+
+// ChangesFiles reports whether a tool edits files in the workspace
+func ChangesFiles(name string) bool {
+	return editTools[name] || fileTools[name]
+}
+
+// IsReadOnly reports whether a tool may run while the registry is read-only
+func IsReadOnly(name string) bool {
+	return readOnlyTools[name]
 }
 
 func NewRegistry() *Registry {
 	r := &Registry{
-		tools: make(map[string]ToolExecutor),
+		tools:       make(map[string]ToolExecutor),
+		outputLimit: DefaultOutputLimit,
 	}
 
 	// Register all tools
 	// File operations
-	r.RegisterTool("read_file", ReadFile)
+	r.RegisterTool("read_file", func(params map[string]interface{}, workingDir string) (string, error) {
+		if r.vision {
+			return readFile(params, workingDir, r.attachImage)
+		}
+		return ReadFile(params, workingDir)
+	})
 	r.RegisterTool("write_file", WriteFile)
 	r.RegisterTool("append_file", AppendFile)
 	r.RegisterTool("edit_file", EditFile)
@@ -34,6 +106,9 @@ func NewRegistry() *Registry {
 	// Command execution
 	r.RegisterTool("execute_command", ExecuteCommand)
 	r.RegisterTool("search_files", SearchFiles)
+	r.RegisterTool("run_tests", func(params map[string]interface{}, workingDir string) (string, error) {
+		return RunTests(params, workingDir, r.reportProgress)
+	})
 
 	// Git operations
 	r.RegisterTool("git_status", GitStatus)
@@ -43,6 +118,20 @@ func NewRegistry() *Registry {
 	r.RegisterTool("git_commit", GitCommit)
 	r.RegisterTool("git_branch", GitBranch)
 
+	// Go code intelligence
+	r.RegisterTool("go_symbols", GoSymbols)
+	r.RegisterTool("go_definition", GoDefinition)
+	r.RegisterTool("go_references", GoReferences)
+	r.RegisterTool("go_replace_symbol", GoReplaceSymbol)
+	r.RegisterTool("go_rename", GoRename)
+	r.RegisterTool("go_organize_imports", GoOrganizeImports)
+	r.RegisterTool("go_move_decl", GoMoveDecl)
+
+	// Memory
+	r.RegisterTool("remember", Remember)
+	r.RegisterTool("forget", Forget)
+	r.RegisterTool("recall", Recall)
+
 	return r
 }
 
@@ -50,11 +139,64 @@ func (r *Registry) RegisterTool(name string, executor ToolExecutor) {
 	r.tools[name] = executor
 }
 
+// Has reports whether a tool is registered
+func (r *Registry) Has(name string) bool {
+	_, ok := r.tools[name]
+	return ok
+}
+
+// SetReadOnly restricts the registry to tools that do not change the
+// workspace, or lifts the restriction
+func (r *Registry) SetReadOnly(readOnly bool) {
+	r.readOnly = readOnly
+}
+
+// SetProgress sets where long-running tools, such as run_tests, report
+// their status while they run
+func (r *Registry) SetProgress(progress func(status string)) {
+	r.progress = progress
+}
+
+func (r *Registry) reportProgress(status string) {
+	if r.progress != nil {
+		r.progress(status)
+	}
+}
+
 func (r *Registry) ExecuteTool(name string, params map[string]interface{}, workingDir string) (string, error) {
 	executor, exists := r.tools[name]
 	if !exists {
 		return "", fmt.Errorf("unknown tool: %s", name)
 	}
+	if r.readOnly && !IsReadOnly(name) {
+		return "", fmt.Errorf("%s is not available in plan mode: only read-only tools can run until the plan is approved", name)
+	}
+	if err := checkHidden(params, workingDir); err != nil {
+		return "", err
+	}
 
-	return executor(params, workingDir)
+	result, err := executor(params, workingDir)
+	if err == nil && editTools[name] {
+		result, err = formatEdited(result, params, workingDir)
+	}
+	return r.limitOutput(name, params, result, workingDir), err
+}
+
+// checkHidden refuses paths that the project's .taracodeignore hides from
+// the assistant
+func checkHidden(params map[string]interface{}, workingDir string) error {
+	var matcher *ignore.Matcher
+	for _, name := range pathParams {
+		path, _ := params[name].(string)
+		if path == "" {
+			continue
+		}
+		if matcher == nil {
+			matcher = ignore.New(workingDir)
+		}
+		if matcher.Hidden(resolvePath(workingDir, path)) {
+			return fmt.Errorf("%s is hidden from the assistant by %s", path, ignore.FileName)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,295 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/tara-vision/taracode/internal/ignore"
+)
+
+const (
+	defaultSearchResults = 100             // Matching lines shown unless max_results says otherwise
+	maxSearchFileSize    = 4 * 1024 * 1024 // Larger files are skipped
+	maxSearchLineLength  = 300             // Longer lines are cut
+)
+
+// searchOptions configures a search
+type searchOptions struct {
+	re           *regexp.Regexp
+	contextLines int
+	include      []string        // File name globs; all files when empty
+	excludeDirs  map[string]bool // Directory names skipped besides the defaults
+	maxResults   int
+}
+
+// fileMatches are the matches in one file
+type fileMatches struct {
+	path  string   // Relative to the search directory
+	lines []int    // Matching line indexes, up to maxResults
+	count int      // All matching lines
+	text  []string // The file's lines
+}
+
+// SearchFiles searches file contents for a literal string or, with regex
+// set, an RE2 regular expression. It skips binary files, directories such
+// as node_modules and .git, and whatever the project's ignore files leave
+// out, and returns matches grouped by file, up to max_results matching
+// lines.
+func SearchFiles(params map[string]interface{}, workingDir string) (string, error) {
+	pattern, ok := params["pattern"].(string)
+	if !ok || pattern == "" {
+		return "", fmt.Errorf("pattern parameter is required")
+	}
+
+	directory := workingDir
+	if dir, ok := params["directory"].(string); ok && dir != "" {
+		directory = resolvePath(workingDir, dir)
+	}
+
+	expr := regexp.QuoteMeta(pattern)
+	if useRegex, _ := params["regex"].(bool); useRegex {
+		expr = pattern
+	}
+	if insensitive, _ := params["case_insensitive"].(bool); insensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", fmt.Errorf("invalid regular expression (RE2 syntax): %w", err)
+	}
+
+	opts := searchOptions{
+		re:           re,
+		contextLines: intParam(params, "context_lines"),
+		excludeDirs:  make(map[string]bool),
+		maxResults:   intParam(params, "max_results"),
+	}
+	if opts.maxResults <= 0 {
+		opts.maxResults = defaultSearchResults
+	}
+	if fileTypes, ok := params["file_types"].([]interface{}); ok {
+		for _, ft := range fileTypes {
+			if s, ok := ft.(string); ok && s != "" {
+				if !strings.ContainsAny(s, "*?[") {
+					s = "*" + s
+				}
+				opts.include = append(opts.include, s)
+			}
+		}
+	}
+	if excludeDirs, ok := params["exclude_dirs"].([]interface{}); ok {
+		for _, ed := range excludeDirs {
+			if s, ok := ed.(string); ok && s != "" {
+				opts.excludeDirs[s] = true
+			}
+		}
+	}
+
+	info, err := os.Stat(directory)
+	if err != nil {
+		return "", fmt.Errorf("cannot search %s: %w", directory, err)
+	}
+	var files []fileMatches
+	if info.IsDir() {
+		files = searchTree(directory, projectMatcher(workingDir, directory), opts)
+	} else if m, ok := searchFile(directory, filepath.Base(directory), opts); ok {
+		// A file named directly is searched whatever the exclusions say
+		files = []fileMatches{m}
+	}
+	if len(files) == 0 {
+		return "No matches found", nil
+	}
+	return formatMatches(files, opts), nil
+}
+
+// searchTree searches every file under root, walking directories
+// concurrently. Results are sorted by path.
+func searchTree(root string, matcher *ignore.Matcher, opts searchOptions) []fileMatches {
+	workers := runtime.NumCPU()
+	paths := make(chan [2]string, 256) // Absolute and relative path
+	var (
+		mu      sync.Mutex
+		results []fileMatches
+		walkers sync.WaitGroup
+		readers sync.WaitGroup
+	)
+	dirSlots := make(chan struct{}, workers)
+
+	for i := 0; i < workers; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for p := range paths {
+				if m, ok := searchFile(p[0], p[1], opts); ok {
+					mu.Lock()
+					results = append(results, m)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	// walk reads one directory and starts a walker for each subdirectory;
+	// rel is relative to the search root
+	var walk func(dir, rel string)
+	walk = func(dir, rel string) {
+		defer walkers.Done()
+		dirSlots <- struct{}{}
+		entries, err := os.ReadDir(dir)
+		<-dirSlots
+		if err != nil {
+			return
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			path, childRel := filepath.Join(dir, name), joinRel(rel, name)
+			if entry.IsDir() {
+				if opts.excludeDirs[name] || matcher.Skip(path, true) {
+					continue
+				}
+				walkers.Add(1)
+				go walk(path, childRel)
+				continue
+			}
+			if !entry.Type().IsRegular() || matchesAny(name, ignore.DefaultExcludePatterns) ||
+				(len(opts.include) > 0 && !matchesAny(name, opts.include)) || matcher.Skip(path, false) {
+				continue
+			}
+			paths <- [2]string{path, childRel}
+		}
+	}
+
+	walkers.Add(1)
+	go walk(root, "")
+	walkers.Wait()
+	close(paths)
+	readers.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].path < results[j].path })
+	return results
+}
+
+// searchFile searches one file, skipping binary and very large files
+func searchFile(path, rel string, opts searchOptions) (fileMatches, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxSearchFileSize {
+		return fileMatches{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return fileMatches{}, false
+	}
+
+	m := fileMatches{path: filepath.ToSlash(rel)}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i, line := range lines {
+		if !opts.re.MatchString(line) {
+			continue
+		}
+		m.count++
+		if len(m.lines) < opts.maxResults {
+			m.lines = append(m.lines, i)
+		}
+	}
+	if m.count == 0 {
+		return fileMatches{}, false
+	}
+	m.text = lines
+	return m, true
+}
+
+// formatMatches renders matches grouped by file: the file, then each
+// matching line as "line: text", with context lines as "line- text" and
+// "--" between separate groups. Lines past maxResults are counted in a
+// closing notice.
+func formatMatches(files []fileMatches, opts searchOptions) string {
+	total := 0
+	for _, f := range files {
+		total += f.count
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d %s in %d %s\n", total, plural(total, "match", "matches"), len(files), plural(len(files), "file", "files"))
+
+	shown := 0
+	for _, f := range files {
+		if shown == opts.maxResults {
+			break
+		}
+		lines := f.lines
+		if len(lines) > opts.maxResults-shown {
+			lines = lines[:opts.maxResults-shown]
+		}
+		shown += len(lines)
+
+		fmt.Fprintf(&b, "\n%s\n", f.path)
+		isMatch := make(map[int]bool, len(lines))
+		for _, i := range lines {
+			isMatch[i] = true
+		}
+		last := -1
+		for _, i := range lines {
+			from, to := max(i-opts.contextLines, last+1), min(i+opts.contextLines, len(f.text)-1)
+			if opts.contextLines > 0 && last >= 0 && from > last+1 {
+				b.WriteString("  --\n")
+			}
+			for n := from; n <= to; n++ {
+				sep := "-"
+				if isMatch[n] {
+					sep = ":"
+				}
+				fmt.Fprintf(&b, "%4d%s %s\n", n+1, sep, cutLine(f.text[n]))
+			}
+			last = max(last, to)
+		}
+	}
+
+	if shown < total {
+		fmt.Fprintf(&b, "\n... %d more %s not shown (max_results %d): narrow the pattern, directory or file_types, or raise max_results\n",
+			total-shown, plural(total-shown, "match", "matches"), opts.maxResults)
+	}
+	return b.String()
+}
+
+func joinRel(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func cutLine(line string) string {
+	if len(line) <= maxSearchLineLength {
+		return line
+	}
+	cut := maxSearchLineLength
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	return line[:cut] + "..."
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
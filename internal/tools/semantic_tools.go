@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tara-vision/taracode/internal/semantic"
+)
+
+const (
+	semanticLimit        = 8  // Results returned by default
+	semanticMaxLimit     = 30 // Results returned at most
+	semanticSnippet      = 15 // Lines of each result shown
+	semanticIndexTimeout = 10 * time.Minute
+)
+
+// RegisterSemanticSearch adds semantic_search, which embeds code through
+// embedder. It keeps its index under .taracode, so it is registered only
+// when project storage is available.
+func (r *Registry) RegisterSemanticSearch(embedder semantic.Embedder) {
+	r.RegisterTool("semantic_search", func(params map[string]interface{}, workingDir string) (string, error) {
+		return SemanticSearch(embedder, params, workingDir, r.reportProgress)
+	})
+}
+
+// SemanticSearch finds the code closest in meaning to a natural-language
+// query, bringing the project's embedding index up to date first. Results
+// are ranked snippets with their file and line range. progress, when set,
+// receives the indexing progress.
+func SemanticSearch(embedder semantic.Embedder, params map[string]interface{}, workingDir string, progress func(status string)) (string, error) {
+	query, _ := params["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return "", fmt.Errorf("query parameter is required")
+	}
+	limit := intParam(params, "limit")
+	if limit <= 0 {
+		limit = semanticLimit
+	}
+	limit = min(limit, semanticMaxLimit)
+	prefix, _ := params["path"].(string)
+	prefix = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(prefix)), "./")
+	if prefix == "." {
+		prefix = ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), semanticIndexTimeout)
+	defer cancel()
+
+	index := semantic.Load(workingDir)
+	stats, err := index.Update(ctx, embedder, progress)
+	if err != nil {
+		return "", fmt.Errorf("failed to index the project: %w", err)
+	}
+	results, err := index.Search(ctx, embedder, query, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to embed the query: %w", err)
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Index: %d files, %d chunks", len(index.Files), index.Chunks())
+	if stats.Indexed > 0 || stats.Removed > 0 {
+		fmt.Fprintf(&out, " (%d files embedded, %d removed)", stats.Indexed, stats.Removed)
+	}
+	out.WriteString("\n")
+
+	shown := 0
+	for _, res := range results {
+		if shown == limit {
+			break
+		}
+		if prefix != "" && res.File != prefix && !strings.HasPrefix(res.File, prefix+"/") {
+			continue
+		}
+		shown++
+		fmt.Fprintf(&out, "\n%d. %s:%d-%d", shown, res.File, res.StartLine, res.EndLine)
+		if res.Name != "" {
+			fmt.Fprintf(&out, " %s", res.Name)
+		}
+		fmt.Fprintf(&out, " (score %.2f)\n", res.Score)
+		out.WriteString(snippet(filepath.Join(workingDir, filepath.FromSlash(res.File)), res.StartLine, res.EndLine))
+	}
+	if shown == 0 {
+		out.WriteString("\nNo matches")
+		if prefix != "" {
+			fmt.Fprintf(&out, " under %s", prefix)
+		}
+		out.WriteString("\n")
+	}
+	return out.String(), nil
+}
+
+// snippet returns the first lines of a result, numbered as read_file
+// numbers them
+func snippet(path string, start, end int) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Sprintf("  (could not read: %v)\n", err)
+	}
+	lines := strings.Split(string(data), "\n")
+	end = min(end, len(lines))
+
+	var b strings.Builder
+	for n := start; n <= end && n < start+semanticSnippet; n++ {
+		fmt.Fprintf(&b, "%4d: %s\n", n, lines[n-1])
+	}
+	if end >= start+semanticSnippet {
+		fmt.Fprintf(&b, "  ... %d more lines\n", end-start-semanticSnippet+1)
+	}
+	return b.String()
+}
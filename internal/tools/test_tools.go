@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tara-vision/taracode/internal/testrun"
+)
+
+// RunTests runs the project's tests with go test, pytest, jest or cargo
+// test and returns a summary: counts, then each failing test with its
+// position, message and trimmed log. progress, when set, receives the
+// counts as tests finish.
+func RunTests(params map[string]interface{}, workingDir string, progress func(status string)) (string, error) {
+	opts := testrun.Options{Progress: progress}
+	opts.Framework, _ = params["framework"].(string)
+	opts.Package, _ = params["package"].(string)
+	opts.Test, _ = params["test"].(string)
+	if seconds := intParam(params, "timeout"); seconds > 0 {
+		opts.Timeout = time.Duration(seconds) * time.Second
+	}
+
+	dir := workingDir
+	if path, ok := params["directory"].(string); ok && path != "" {
+		dir = resolvePath(workingDir, path)
+	}
+
+	result, err := testrun.Run(dir, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to run tests: %w", err)
+	}
+	return result.Summary(), nil
+}
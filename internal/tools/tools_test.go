@@ -1,10 +1,20 @@
 package tools
 
 import (
+	"bytes"
+	"fmt"
+	"image"
+	gopng "image/png"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf8"
+
+	"github.com/tara-vision/taracode/internal/llmtest"
+	"github.com/tara-vision/taracode/internal/provider"
+	"github.com/tara-vision/taracode/internal/semantic"
+	"github.com/tara-vision/taracode/internal/storage"
 )
 
 func setupTestDir(t *testing.T) string {
@@ -56,6 +66,70 @@ func TestReadFile(t *testing.T) {
 	}
 }
 
+func TestReadFileContent(t *testing.T) {
+	dir := setupTestDir(t)
+	defer os.RemoveAll(dir)
+	write := func(name string, data []byte) {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var png bytes.Buffer
+	if err := gopng.Encode(&png, image.NewRGBA(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal(err)
+	}
+	write("logo.png", png.Bytes())
+	write("app", append([]byte("\x7fELF\x02\x01\x01\x00"), make([]byte, 64)...))
+	write("le.txt", []byte("\xff\xfeh\x00i\x00\n\x00\xe9\x00\n\x00"))
+	write("be.txt", []byte("\x00h\x00i\x00\n\x00o\x00k"))
+	write("latin1.txt", []byte("caf\xe9\n"))
+	write("big.txt", bytes.Repeat([]byte("0123456789abcdef\n"), maxReadSize/16))
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"app", "app is a binary file (ELF executable, 72 B, sha256 "},
+		{"logo.png", "logo.png is an image (PNG image, 3x2, "},
+		{"le.txt", "hi\né\n[Decoded from UTF-16LE; writing the file back saves it as UTF-8]\n"},
+		{"be.txt", "hi\nok\n[Decoded from UTF-16BE;"},
+		{"latin1.txt", "café\n[Decoded from Latin-1;"},
+	}
+	for _, tt := range tests {
+		result, err := ReadFile(map[string]interface{}{"file_path": tt.file}, dir)
+		if err != nil || !strings.HasPrefix(result, tt.want) {
+			t.Errorf("read_file %s = %q, %v; want prefix %q", tt.file, result, err, tt.want)
+		}
+	}
+
+	// Ranges of decoded text, and large files only by range
+	result, err := ReadFile(map[string]interface{}{"file_path": "le.txt", "start_line": float64(2), "end_line": float64(2)}, dir)
+	if err != nil || result != "=== le.txt (lines 2-2 of 3, UTF-16LE) ===\n   2: é\n" {
+		t.Errorf("ranged UTF-16 read = %q, %v", result, err)
+	}
+	if _, err := ReadFile(map[string]interface{}{"file_path": "big.txt"}, dir); err == nil || !strings.Contains(err.Error(), "too large to read whole") {
+		t.Errorf("reading a large file whole: %v", err)
+	}
+	if _, err := ReadFile(map[string]interface{}{"file_path": "big.txt", "start_line": float64(5), "end_line": float64(6)}, dir); err != nil {
+		t.Errorf("reading a large file by range: %v", err)
+	}
+
+	// With vision, images are attached for the next message
+	r := NewRegistry()
+	r.SetVision(true)
+	result, err = r.ExecuteTool("read_file", map[string]interface{}{"file_path": "logo.png"}, dir)
+	if err != nil || !strings.HasPrefix(result, "Attached logo.png (PNG image, 3x2, ") {
+		t.Errorf("read_file with vision = %q, %v", result, err)
+	}
+	images := r.TakeImages()
+	if len(images) != 1 || images[0].MIMEType != "image/png" || !strings.HasPrefix(images[0].DataURL(), "data:image/png;base64,iVBOR") {
+		t.Errorf("attached images = %+v", images)
+	}
+	if len(r.TakeImages()) != 0 {
+		t.Error("images taken twice")
+	}
+}
+
 func TestWriteFile(t *testing.T) {
 	dir := setupTestDir(t)
 	defer os.RemoveAll(dir)
@@ -335,16 +409,149 @@ func TestSearchFiles(t *testing.T) {
 	defer os.RemoveAll(dir)
 
 	os.WriteFile(filepath.Join(dir, "test.txt"), []byte("hello world\nfoo bar\nhello again"), 0644)
+	os.MkdirAll(filepath.Join(dir, "src"), 0755)
+	os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n\n// hello.*\nfunc hello() {}\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "node_modules", "lib"), 0755)
+	os.WriteFile(filepath.Join(dir, "node_modules", "lib", "index.js"), []byte("hello\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "image.bin.dat"), []byte("hello\x00\x01"), 0644)
+	os.MkdirAll(filepath.Join(dir, "gen"), 0755)
+	os.WriteFile(filepath.Join(dir, "gen", "out.go"), []byte("hello\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "gen", "keep.go"), []byte("hello\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("gen/*\n!gen/keep.go\n"), 0644)
+
+	result, err := SearchFiles(map[string]interface{}{"pattern": "hello"}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "5 matches in 3 files\n\ngen/keep.go\n   1: hello\n\nsrc/main.go\n   3: // hello.*\n   4: func hello() {}\n\ntest.txt\n   1: hello world\n   3: hello again\n"
+	if result != want {
+		t.Errorf("SearchFiles =\n%s\nwant\n%s", result, want)
+	}
 
-	result, err := SearchFiles(map[string]interface{}{
-		"pattern":   "hello",
-		"directory": dir,
-	}, dir)
+	// Literal by default, RE2 with regex
+	result, _ = SearchFiles(map[string]interface{}{"pattern": "hello.*", "directory": "src"}, dir)
+	if !strings.HasPrefix(result, "1 match in 1 file\n") {
+		t.Errorf("literal search =\n%s", result)
+	}
+	result, _ = SearchFiles(map[string]interface{}{"pattern": `^func \w+\(`, "regex": true, "file_types": []interface{}{".go"}}, dir)
+	if !strings.Contains(result, "src/main.go\n   4: func hello() {}") || strings.Contains(result, "gen/") {
+		t.Errorf("regex search =\n%s", result)
+	}
+	if _, err := SearchFiles(map[string]interface{}{"pattern": "(", "regex": true}, dir); err == nil || !strings.Contains(err.Error(), "RE2") {
+		t.Errorf("invalid regex error = %v", err)
+	}
+
+	// Context lines and the results cap
+	result, _ = SearchFiles(map[string]interface{}{"pattern": "HELLO", "case_insensitive": true, "context_lines": float64(1), "max_results": float64(2), "directory": "test.txt"}, dir)
+	want = "2 matches in 1 file\n\ntest.txt\n   1: hello world\n   2- foo bar\n   3: hello again\n"
+	if result != want {
+		t.Errorf("search with context =\n%s", result)
+	}
+	result, _ = SearchFiles(map[string]interface{}{"pattern": "hello", "max_results": float64(2)}, dir)
+	if !strings.Contains(result, "src/main.go\n   3: // hello.*\n\n... 3 more matches not shown (max_results 2)") || strings.Contains(result, "test.txt") {
+		t.Errorf("capped search =\n%s", result)
+	}
+
+	result, _ = SearchFiles(map[string]interface{}{"pattern": "missing"}, dir)
+	if result != "No matches found" {
+		t.Errorf("search without matches = %q", result)
+	}
+}
+
+func TestHiddenPaths(t *testing.T) {
+	dir := setupTestDir(t)
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "secrets"), 0755)
+	os.WriteFile(filepath.Join(dir, "secrets", "prod.env"), []byte("TOKEN=abc\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "server.pem"), []byte("TOKEN\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // TOKEN\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".taracodeignore"), []byte("secrets/\n*.pem\n"), 0644)
+
+	r := NewRegistry()
+	for _, params := range []map[string]interface{}{
+		{"file_path": "secrets/prod.env"},
+		{"file_path": "server.pem"},
+	} {
+		if _, err := r.ExecuteTool("read_file", params, dir); err == nil || !strings.Contains(err.Error(), "hidden from the assistant by .taracodeignore") {
+			t.Errorf("read_file %v error = %v", params, err)
+		}
+	}
+	if _, err := r.ExecuteTool("list_files", map[string]interface{}{"directory": "secrets"}, dir); err == nil {
+		t.Error("list_files of a hidden directory succeeded")
+	}
+
+	for _, recursive := range []bool{false, true} {
+		result, _ := r.ExecuteTool("list_files", map[string]interface{}{"recursive": recursive}, dir)
+		if strings.Contains(result, "secrets") || strings.Contains(result, "server.pem") || !strings.Contains(result, "main.go") {
+			t.Errorf("list_files (recursive %v) =\n%s", recursive, result)
+		}
+	}
+	result, _ := r.ExecuteTool("search_files", map[string]interface{}{"pattern": "TOKEN"}, dir)
+	if !strings.HasPrefix(result, "1 match in 1 file\n\nmain.go") {
+		t.Errorf("search_files =\n%s", result)
+	}
+	result, _ = r.ExecuteTool("find_files", map[string]interface{}{"pattern": "*"}, dir)
+	if strings.Contains(result, "prod.env") || strings.Contains(result, "server.pem") {
+		t.Errorf("find_files =\n%s", result)
+	}
+}
+
+func TestOutputLimit(t *testing.T) {
+	dir := setupTestDir(t)
+	defer os.RemoveAll(dir)
+	var lines []string
+	for i := 1; i <= 200; i++ {
+		lines = append(lines, fmt.Sprintf("line %03d", i))
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	os.WriteFile(filepath.Join(dir, "big.txt"), []byte(content), 0644)
+
+	r := NewRegistry()
+	r.SetOutputLimit(300)
+	r.SetOutputDir(filepath.Join(dir, ".taracode", "outputs"))
+
+	// read_file keeps the first and last lines and points back at the file
+	result, err := r.ExecuteTool("read_file", map[string]interface{}{"file_path": "big.txt"}, dir)
 	if err != nil {
-		t.Errorf("SearchFiles failed: %v", err)
+		t.Fatal(err)
 	}
-	if !strings.Contains(result, "hello") {
-		t.Errorf("Search pattern not found in result: %s", result)
+	if !strings.HasPrefix(result, "line 001\n") || !strings.HasSuffix(result, "line 200\n") {
+		t.Errorf("read_file does not keep head and tail:\n%s", result)
+	}
+	if !strings.Contains(result, "line 022\n[... output truncated: lines 23-189 of 200 (1503 bytes) omitted. To see more, read the omitted lines of big.txt with start_line and end_line ...]\nline 190\n") {
+		t.Errorf("read_file notice:\n%s", result)
+	}
+
+	// Other results are saved whole for read_file to page through
+	result, err = r.ExecuteTool("execute_command", map[string]interface{}{"command": "cat big.txt"}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) > 600 || !strings.Contains(result, "filter the command's output") {
+		t.Errorf("execute_command =\n%s", result)
+	}
+	i := strings.Index(result, ".taracode/outputs/execute_command-")
+	if i < 0 {
+		t.Fatalf("no saved output in:\n%s", result)
+	}
+	saved := result[i : i+strings.IndexByte(result[i:], ':')]
+	data, err := os.ReadFile(filepath.Join(dir, saved))
+	if err != nil || !strings.Contains(string(data), content) {
+		t.Errorf("saved output %s = %q, %v", saved, data, err)
+	}
+	page, err := r.ExecuteTool("read_file", map[string]interface{}{"file_path": saved, "start_line": float64(100), "end_line": float64(101)}, dir)
+	if err != nil || !strings.Contains(page, "line 096\n") {
+		t.Errorf("reading the saved output = %q, %v", page, err)
+	}
+
+	// Short results and a single long line
+	if result, _ = r.ExecuteTool("read_file", map[string]interface{}{"file_path": "big.txt", "start_line": float64(1), "end_line": float64(3)}, dir); strings.Contains(result, "truncated") {
+		t.Errorf("short result truncated:\n%s", result)
+	}
+	os.WriteFile(filepath.Join(dir, "long.txt"), []byte(strings.Repeat("é", 500)), 0644)
+	result, _ = r.ExecuteTool("read_file", map[string]interface{}{"file_path": "long.txt"}, dir)
+	if !utf8.ValidString(result) || !strings.Contains(result, "[... output truncated: 700 bytes omitted.") {
+		t.Errorf("long line =\n%s", result)
 	}
 }
 
@@ -507,6 +714,44 @@ func TestGitDiff(t *testing.T) {
 	}
 }
 
+func TestGitHiddenPaths(t *testing.T) {
+	dir := setupTestDir(t)
+	defer os.RemoveAll(dir)
+
+	ExecuteCommand(map[string]interface{}{"command": "git init"}, dir)
+	ExecuteCommand(map[string]interface{}{"command": "git config user.email 'test@test.com'"}, dir)
+	ExecuteCommand(map[string]interface{}{"command": "git config user.name 'Test'"}, dir)
+	os.MkdirAll(filepath.Join(dir, "config"), 0755)
+	os.WriteFile(filepath.Join(dir, "config", "app.yaml"), []byte("port: 80\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "config", "prod.env"), []byte("TOKEN=old\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".taracodeignore"), []byte("*.env\nsecrets/\n"), 0644)
+	GitAdd(map[string]interface{}{"files": []interface{}{"."}}, dir)
+	GitCommit(map[string]interface{}{"message": "initial commit"}, dir)
+
+	os.WriteFile(filepath.Join(dir, "config", "app.yaml"), []byte("port: 8080\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "config", "prod.env"), []byte("TOKEN=new\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "secrets"), 0755)
+	os.WriteFile(filepath.Join(dir, "secrets", "key"), []byte("TOKEN\n"), 0644)
+
+	result, err := GitStatus(map[string]interface{}{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "config/app.yaml") || strings.Contains(result, "prod.env") || strings.Contains(result, "secrets") {
+		t.Errorf("git_status =\n%s", result)
+	}
+
+	for _, params := range []map[string]interface{}{{}, {"file_path": "config"}} {
+		result, err := GitDiff(params, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(result, "+port: 8080") || strings.Contains(result, "TOKEN") {
+			t.Errorf("git_diff %v =\n%s", params, result)
+		}
+	}
+}
+
 func TestCopyFile(t *testing.T) {
 	dir := setupTestDir(t)
 	defer os.RemoveAll(dir)
@@ -687,3 +932,272 @@ func TestDeleteFile(t *testing.T) {
 		t.Errorf("Expected success message for idempotent delete: %s", result)
 	}
 }
+
+func TestMemoryTools(t *testing.T) {
+	dir := setupTestDir(t)
+	defer os.RemoveAll(dir)
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := Remember(map[string]interface{}{"content": "Use make test"}, dir); err != nil {
+		t.Fatalf("Remember failed: %v", err)
+	}
+	result, err := Remember(map[string]interface{}{"content": "Prefer table-driven tests", "scope": "user", "tags": []interface{}{"go"}}, dir)
+	if err != nil || !strings.Contains(result, "user memory") {
+		t.Fatalf("Remember user = %q, %v", result, err)
+	}
+	if _, err := Remember(map[string]interface{}{"content": "x", "scope": "team"}, dir); err == nil {
+		t.Error("Expected error for unknown scope")
+	}
+
+	result, err = Recall(map[string]interface{}{}, dir)
+	if err != nil || !strings.Contains(result, "(project) Use make test") || !strings.Contains(result, "(user) Prefer table-driven tests #go") {
+		t.Fatalf("Recall = %q, %v", result, err)
+	}
+	result, _ = Recall(map[string]interface{}{"query": "table"}, dir)
+	if strings.Contains(result, "make test") {
+		t.Errorf("Recall query matched too much: %q", result)
+	}
+
+	// Forget by the ID shown in recall, from either scope
+	id := result[1:strings.Index(result, "]")]
+	if _, err := Forget(map[string]interface{}{"id": id}, dir); err != nil {
+		t.Fatalf("Forget failed: %v", err)
+	}
+	if _, err := Forget(map[string]interface{}{"id": id}, dir); err == nil {
+		t.Error("Expected error forgetting twice")
+	}
+	if result, _ := Recall(map[string]interface{}{"query": "table"}, dir); !strings.HasPrefix(result, "No memories") {
+		t.Errorf("memory not forgotten: %q", result)
+	}
+}
+
+func TestPlanTools(t *testing.T) {
+	dir := setupTestDir(t)
+	defer os.RemoveAll(dir)
+
+	plans, err := storage.NewManager(dir)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	r := NewRegistry()
+	r.RegisterPlanTools(plans)
+	run := func(tool string, params map[string]interface{}) (string, error) {
+		return r.ExecuteTool(tool, params, dir)
+	}
+
+	if _, err := run("plan_update_task", map[string]interface{}{"task": "1", "status": "done"}); err == nil {
+		t.Error("Expected error without an active plan")
+	}
+
+	result, err := run("plan_create", map[string]interface{}{
+		"title": "Add auth",
+		"tasks": []interface{}{
+			"Add middleware",
+			map[string]interface{}{"content": "Write tests", "subtasks": []interface{}{"Unit"}},
+		},
+	})
+	if err != nil || !strings.Contains(result, "Plan: Add auth (0/3 done)") || !strings.Contains(result, "2.1. [ ] Unit") {
+		t.Fatalf("plan_create = %q, %v", result, err)
+	}
+
+	result, err = run("plan_add_task", map[string]interface{}{"content": "Integration", "parent": float64(2), "notes": "needs db"})
+	if err != nil || !strings.Contains(result, "Added task 2.2") || !strings.Contains(result, "Note: needs db") {
+		t.Fatalf("plan_add_task = %q, %v", result, err)
+	}
+
+	if _, err := run("plan_update_task", map[string]interface{}{"task": "2.1", "status": "finished"}); err == nil {
+		t.Error("Expected error for unknown status")
+	}
+	for _, ref := range []string{"1", "2.1", "2.2"} {
+		result, err = run("plan_update_task", map[string]interface{}{"task": ref, "status": "completed"})
+		if err != nil {
+			t.Fatalf("plan_update_task %s failed: %v", ref, err)
+		}
+	}
+	if !strings.Contains(result, "(4/4 done)") {
+		t.Errorf("parent not completed with its subtasks: %q", result)
+	}
+
+	// Creating another plan archives this one
+	result, err = run("plan_create", map[string]interface{}{"title": "Next", "tasks": []interface{}{"One"}})
+	if err != nil || !strings.HasPrefix(result, "Archived previous plan: Add auth") {
+		t.Fatalf("second plan_create = %q, %v", result, err)
+	}
+	result, err = run("plan_complete", map[string]interface{}{})
+	if err != nil || !strings.Contains(result, "1 of 1 tasks still open") {
+		t.Fatalf("plan_complete = %q, %v", result, err)
+	}
+	if plan, _ := plans.GetActivePlan(); plan != nil {
+		t.Errorf("plan still active after plan_complete: %+v", plan)
+	}
+}
+
+func TestGoTools(t *testing.T) {
+	dir := setupTestDir(t)
+	defer os.RemoveAll(dir)
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/calc\n\ngo 1.23\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "calc.go"), []byte("package calc\n\n// Add sums two numbers\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc Double(n int) int {\n\treturn Add(n, n)\n}\n"), 0644)
+
+	result, err := GoSymbols(map[string]interface{}{}, dir)
+	if err != nil || !strings.Contains(result, "func Add(a, b int) int  calc.go:4-6") {
+		t.Fatalf("GoSymbols = %q, %v", result, err)
+	}
+
+	result, err = GoDefinition(map[string]interface{}{"file": "calc.go", "line": float64(9), "name": "Add"}, dir)
+	if err != nil || !strings.Contains(result, "calc.go:4\n\n// Add sums two numbers\nfunc Add") {
+		t.Fatalf("GoDefinition = %q, %v", result, err)
+	}
+
+	result, err = GoReferences(map[string]interface{}{"symbol": "Add"}, dir)
+	if err != nil || !strings.HasPrefix(result, "2 references to func Add(a int, b int) int") || !strings.Contains(result, "calc.go:9:9: return Add(n, n)") {
+		t.Fatalf("GoReferences = %q, %v", result, err)
+	}
+	if _, err := GoReferences(map[string]interface{}{"symbol": "Subtract"}, dir); err == nil {
+		t.Error("Expected error for unknown symbol")
+	}
+
+	result, err = GoReplaceSymbol(map[string]interface{}{"symbol": "Double", "content": "func Double(n int) int { return 2 * n }"}, dir)
+	if err != nil || !strings.Contains(result, "Replaced func Double in calc.go") {
+		t.Fatalf("GoReplaceSymbol = %q, %v", result, err)
+	}
+	content, _ := os.ReadFile(filepath.Join(dir, "calc.go"))
+	if !strings.HasSuffix(string(content), "func Double(n int) int { return 2 * n }\n") {
+		t.Errorf("declaration not replaced:\n%s", content)
+	}
+}
+
+func TestGoRefactorTools(t *testing.T) {
+	dir := setupTestDir(t)
+	defer os.RemoveAll(dir)
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/calc\n\ngo 1.23\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "calc.go"), []byte("package calc\n\nimport \"fmt\"\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc Show(n int) string {\n\treturn fmt.Sprint(Add(n, n))\n}\n"), 0644)
+
+	result, err := GoRename(map[string]interface{}{"symbol": "Add", "new_name": "Sum"}, dir)
+	if err != nil || !strings.Contains(result, "Renamed Add to Sum in 1 files") || !strings.Contains(result, "+\treturn fmt.Sprint(Sum(n, n))") {
+		t.Fatalf("GoRename = %q, %v", result, err)
+	}
+	if _, err := GoRename(map[string]interface{}{"symbol": "Sum", "new_name": "Show"}, dir); err == nil {
+		t.Error("Expected error renaming to an existing name")
+	}
+
+	result, err = GoMoveDecl(map[string]interface{}{"symbol": "Show", "to": "show.go"}, dir)
+	if err != nil || !strings.Contains(result, "Moved func Show to show.go") {
+		t.Fatalf("GoMoveDecl = %q, %v", result, err)
+	}
+	calc, _ := os.ReadFile(filepath.Join(dir, "calc.go"))
+	show, _ := os.ReadFile(filepath.Join(dir, "show.go"))
+	if strings.Contains(string(calc), "fmt") || !strings.Contains(string(show), "import \"fmt\"") {
+		t.Errorf("imports not moved with the code:\ncalc.go:\n%s\nshow.go:\n%s", calc, show)
+	}
+
+	os.WriteFile(filepath.Join(dir, "show.go"), []byte("package calc\n\nimport \"os\"\n\nfunc Show(n int) string {\n\treturn strconv.Itoa(Sum(n, n))\n}\n"), 0644)
+	result, err = GoOrganizeImports(map[string]interface{}{"path": "show.go"}, dir)
+	if err != nil || !strings.Contains(result, "-import \"os\"") || !strings.Contains(result, "+import \"strconv\"") {
+		t.Fatalf("GoOrganizeImports = %q, %v", result, err)
+	}
+}
+
+func TestEditToolsFormat(t *testing.T) {
+	dir := setupTestDir(t)
+	defer os.RemoveAll(dir)
+	registry := NewRegistry()
+
+	result, err := registry.ExecuteTool("write_file", map[string]interface{}{"file_path": "main.go", "content": "package main\nfunc main(){}\n"}, dir)
+	if err != nil || !strings.HasSuffix(result, "(formatted with gofmt)") {
+		t.Fatalf("write_file = %q, %v", result, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "main.go")); string(data) != "package main\n\nfunc main() {}\n" {
+		t.Errorf("main.go =\n%s", data)
+	}
+
+	// A syntax mistake is reported but the edit is kept
+	_, err = registry.ExecuteTool("edit_file", map[string]interface{}{"file_path": "main.go", "old_string": "{}", "new_string": "{"}, dir)
+	if err == nil || !strings.Contains(err.Error(), "gofmt rejected main.go:3:") {
+		t.Errorf("edit_file with a syntax error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "main.go")); !strings.HasSuffix(string(data), "func main() {\n") {
+		t.Errorf("edit not kept:\n%s", data)
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	output := `# example.com/shop
+./order.go:12:2: undefined: total
+vet: ./main.go:5:9: fmt.Sprintf call needs 1 arg but has 2 args
+src/app.ts(3,7): error TS2322: Type 'string' is not assignable to type 'number'.
+error[E0425]: cannot find value ` + "`x`" + ` in this scope
+ --> src/main.rs:2:5
+main.c:7: warning: unused variable
+make: *** [build] Error 1`
+	want := []string{
+		"order.go:12:2: undefined: total",
+		"main.go:5:9: fmt.Sprintf call needs 1 arg but has 2 args",
+		"src/app.ts:3:7: error TS2322: Type 'string' is not assignable to type 'number'.",
+		"src/main.rs:2:5: error[E0425]: cannot find value `x` in this scope",
+		"main.c:7: warning: unused variable",
+	}
+
+	diagnostics := ParseDiagnostics(output)
+	if len(diagnostics) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %v", len(diagnostics), len(want), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.String() != want[i] {
+			t.Errorf("diagnostic %d = %q, want %q", i, d.String(), want[i])
+		}
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	dir := setupTestDir(t)
+	defer os.RemoveAll(dir)
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/calc\n\ngo 1.23\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "calc_test.go"), []byte("package calc\n\nimport \"testing\"\n\nfunc TestOk(t *testing.T) {}\n\nfunc TestBad(t *testing.T) {\n\tt.Fatal(\"boom\")\n}\n"), 0644)
+
+	var progress []string
+	result, err := RunTests(map[string]interface{}{}, dir, func(status string) { progress = append(progress, status) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "FAILED (1 passed, 1 failed, 0 skipped") || !strings.Contains(result, "FAIL example.com/calc TestBad\n  at calc_test.go:8\n  boom") {
+		t.Errorf("RunTests =\n%s", result)
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != "(1 passed, 1 failed)" {
+		t.Errorf("progress = %v", progress)
+	}
+
+	result, err = RunTests(map[string]interface{}{"test": "TestOk"}, dir, nil)
+	if err != nil || !strings.Contains(result, "-run ^TestOk$ ./...: PASSED (1 passed") {
+		t.Errorf("single test = %q, %v", result, err)
+	}
+}
+
+func TestSemanticSearch(t *testing.T) {
+	dir := setupTestDir(t)
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "auth"), 0755)
+	os.WriteFile(filepath.Join(dir, "auth", "session.go"), []byte("package auth\n\n// Expired reports whether the session has expired\nfunc Expired(session Session) bool {\n\treturn session.Expires.Before(now())\n}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.md"), []byte("# Release notes\n\nNothing yet.\n"), 0644)
+
+	server := llmtest.New(t)
+	r := NewRegistry()
+	r.RegisterSemanticSearch(semantic.NewEmbedder(provider.NewVLLMProvider(server.URL, "").CreateClient(), "test-embed"))
+
+	result, err := r.ExecuteTool("semantic_search", map[string]interface{}{"query": "has the session expired", "limit": float64(1)}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "Index: 2 files, 2 chunks (2 files embedded, 0 removed)") ||
+		!strings.Contains(result, "1. auth/session.go:3-6 Expired (score ") ||
+		!strings.Contains(result, "   4: func Expired(session Session) bool {") || strings.Contains(result, "2. ") {
+		t.Errorf("semantic_search =\n%s", result)
+	}
+
+	result, err = r.ExecuteTool("semantic_search", map[string]interface{}{"query": "has the session expired", "path": "docs"}, dir)
+	if err != nil || !strings.HasPrefix(result, "Index: 2 files, 2 chunks\n") || !strings.Contains(result, "No matches under docs") {
+		t.Errorf("semantic_search under docs = %q, %v", result, err)
+	}
+	if _, err := r.ExecuteTool("semantic_search", map[string]interface{}{}, dir); err == nil {
+		t.Error("semantic_search without a query succeeded")
+	}
+}
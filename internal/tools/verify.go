@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// verifyTimeout bounds a verification run, which may build the whole project
+const verifyTimeout = 5 * time.Minute
+
+// Diagnostic is a compiler or linter message about a source position
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int // 0 when the tool gives none
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+}
+
+var (
+	// file:line[:column]: message, as printed by go, gcc, clang and most linters
+	colonDiagnostic = regexp.MustCompile(`^\s*(?:vet: )?([^\s:]+\.\w+):(\d+)(?::(\d+))?:\s*(.+)$`)
+	// file(line,column): message, as printed by tsc
+	parenDiagnostic = regexp.MustCompile(`^\s*([^\s(]+\.\w+)\((\d+),(\d+)\):\s*(.+)$`)
+	// --> file:line:column under an error line, as printed by rustc
+	arrowDiagnostic = regexp.MustCompile(`^\s*--> ([^\s:]+):(\d+):(\d+)$`)
+)
+
+// ParseDiagnostics picks the file positions and messages out of build
+// output. Paths are cleaned but left relative to where the command ran.
+func ParseDiagnostics(output string) []Diagnostic {
+	var diagnostics []Diagnostic
+	var lastMessage string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		m := colonDiagnostic.FindStringSubmatch(line)
+		if m == nil {
+			m = parenDiagnostic.FindStringSubmatch(line)
+		}
+		if m == nil {
+			if a := arrowDiagnostic.FindStringSubmatch(line); a != nil && lastMessage != "" {
+				m = append(a, lastMessage)
+			}
+		}
+		if m == nil {
+			if strings.HasPrefix(line, "error") || strings.HasPrefix(line, "warning") {
+				lastMessage = line
+			}
+			continue
+		}
+
+		lineNum, _ := strconv.Atoi(m[2])
+		column, _ := strconv.Atoi(m[3])
+		diagnostics = append(diagnostics, Diagnostic{
+			File:    filepath.Clean(m[1]),
+			Line:    lineNum,
+			Column:  column,
+			Message: strings.TrimSpace(m[4]),
+		})
+		lastMessage = ""
+	}
+	return diagnostics
+}
+
+// RunCheck runs a verification command such as "go build ./..." in the
+// working directory and returns its combined output. The error reports a
+// check that failed or could not run.
+func RunCheck(command, workingDir string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = workingDir
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return output.String(), fmt.Errorf("%s timed out after %v", command, verifyTimeout)
+	}
+	if err != nil {
+		return output.String(), fmt.Errorf("%s failed: %w", command, err)
+	}
+	return output.String(), nil
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/tara-vision/taracode/internal/cassette"
 	"github.com/tara-vision/taracode/internal/provider"
 	"github.com/tara-vision/taracode/internal/storage"
 )
@@ -85,17 +86,31 @@ func (r *Renderer) FormatToolStatus(tool string, params map[string]interface{},
 	switch tool {
 	case "read_file":
 		filePath, _ := params["file_path"].(string)
+		if strings.HasPrefix(result, "Attached ") {
+			return ToolRead.Render(fmt.Sprintf("%s Attached %s (image)", IconArrow, filepath.Base(filePath)))
+		}
+		if !strings.Contains(result, "\n") && (strings.Contains(result, " is a binary file (") || strings.Contains(result, " is an image (")) {
+			return ToolRead.Render(fmt.Sprintf("%s Read %s (binary, not shown)", IconArrow, filepath.Base(filePath)))
+		}
 		lines := strings.Count(result, "\n") + 1
 		return ToolRead.Render(fmt.Sprintf("%s Read %s (%d lines)", IconArrow, filepath.Base(filePath), lines))
 
 	case "search_files":
 		pattern, _ := params["pattern"].(string)
-		matches := strings.Count(result, "\n")
-		if strings.Contains(result, "No matches") {
+		var matches int
+		fmt.Sscanf(result, "%d", &matches)
+		if strings.HasPrefix(result, "No matches") {
 			return ToolRead.Render(fmt.Sprintf("%s Searched for \"%s\" (no matches)", IconArrow, pattern))
 		}
 		return ToolRead.Render(fmt.Sprintf("%s Searched for \"%s\" (%d matches)", IconArrow, pattern, matches))
 
+	case "semantic_search":
+		query, _ := params["query"].(string)
+		if len(query) > 40 {
+			query = query[:37] + "..."
+		}
+		return ToolRead.Render(fmt.Sprintf("%s Searched for \"%s\" by meaning (%d results)", IconArrow, query, strings.Count(result, " (score ")))
+
 	case "list_files":
 		dir, _ := params["directory"].(string)
 		if dir == "" || dir == "." {
@@ -198,6 +213,79 @@ func (r *Renderer) FormatToolStatus(tool string, params map[string]interface{},
 		branches := strings.Count(result, "\n") + 1
 		return ToolRead.Render(fmt.Sprintf("%s Git branches: %d", IconArrow, branches))
 
+	case "go_symbols":
+		return ToolRead.Render(fmt.Sprintf("%s Go symbols: %d", IconArrow, strings.Count(result, "\n")))
+
+	case "go_definition":
+		symbol, _ := params["symbol"].(string)
+		if symbol == "" {
+			symbol, _ = params["name"].(string)
+		}
+		return ToolRead.Render(fmt.Sprintf("%s Definition of %s", IconArrow, symbol))
+
+	case "go_references":
+		count, _, _ := strings.Cut(result, " ")
+		return ToolRead.Render(fmt.Sprintf("%s References: %s", IconArrow, count))
+
+	case "go_replace_symbol":
+		symbol, _ := params["symbol"].(string)
+		return ToolWrite.Render(fmt.Sprintf("%s Replaced %s", IconSuccess, symbol))
+
+	case "go_rename":
+		symbol, _ := params["symbol"].(string)
+		if symbol == "" {
+			symbol, _ = params["name"].(string)
+		}
+		newName, _ := params["new_name"].(string)
+		return ToolWrite.Render(fmt.Sprintf("%s Renamed %s to %s", IconSuccess, symbol, newName))
+
+	case "go_organize_imports", "go_move_decl":
+		summary, _, _ := strings.Cut(result, "\n")
+		return ToolWrite.Render(fmt.Sprintf("%s %s", IconSuccess, summary))
+
+	case "remember":
+		scope, _ := params["scope"].(string)
+		if scope == "" {
+			scope = "project"
+		}
+		return ToolWrite.Render(fmt.Sprintf("%s Remembered (%s memory)", IconSuccess, scope))
+
+	case "forget":
+		id, _ := params["id"].(string)
+		return ToolWrite.Render(fmt.Sprintf("%s Forgot memory %s", IconSuccess, id))
+
+	case "recall":
+		if strings.HasPrefix(result, "No memories") {
+			return ToolRead.Render(fmt.Sprintf("%s Recalled no memories", IconArrow))
+		}
+		return ToolRead.Render(fmt.Sprintf("%s Recalled %d memories", IconArrow, strings.Count(result, "\n")))
+
+	case "plan_create", "plan_update_task", "plan_add_task":
+		// Show the "Plan: title (n/m done)" line of the result
+		for _, line := range strings.Split(result, "\n") {
+			if strings.HasPrefix(line, "Plan: ") {
+				return ToolWrite.Render(fmt.Sprintf("%s %s", IconSuccess, line))
+			}
+		}
+		return ToolWrite.Render(fmt.Sprintf("%s Plan updated", IconSuccess))
+
+	case "run_tests":
+		summary, _, _ := strings.Cut(result, "\n")
+		if i := strings.LastIndex(summary, ": "); i >= 0 {
+			summary = summary[i+2:]
+		}
+		if strings.HasPrefix(summary, "PASSED") {
+			return ToolRead.Render(fmt.Sprintf("%s Tests %s", IconSuccess, summary))
+		}
+		return ToolError.Render(fmt.Sprintf("%s Tests %s", IconError, summary))
+
+	case "verify":
+		cmd, _ := params["command"].(string)
+		return ToolRead.Render(fmt.Sprintf("%s Verified: %s", IconSuccess, cmd))
+
+	case "plan_complete":
+		return ToolWrite.Render(fmt.Sprintf("%s %s", IconSuccess, result))
+
 	default:
 		return ToolRead.Render(fmt.Sprintf("%s %s completed", IconArrow, tool))
 	}
@@ -235,14 +323,167 @@ func (r *Renderer) FormatUsage(usage *storage.TokenUsage) string {
 	}
 
 	var sb strings.Builder
+	approx := ""
+	if usage.Estimated {
+		approx = "~"
+	}
+
 	sb.WriteString(SessionStyle.Render(IconInfo+" Token Usage") + "\n")
-	sb.WriteString(fmt.Sprintf("  Prompt tokens:     %d\n", usage.PromptTokens))
-	sb.WriteString(fmt.Sprintf("  Completion tokens: %d\n", usage.CompletionTokens))
-	sb.WriteString(fmt.Sprintf("  Total tokens:      %d\n", usage.TotalTokens))
+	sb.WriteString(fmt.Sprintf("  Prompt tokens:     %s%d\n", approx, usage.PromptTokens))
+	sb.WriteString(fmt.Sprintf("  Completion tokens: %s%d\n", approx, usage.CompletionTokens))
+	sb.WriteString(fmt.Sprintf("  Total tokens:      %s%d\n", approx, usage.TotalTokens))
+	if tps := usage.TokensPerSecond(); tps > 0 {
+		sb.WriteString(fmt.Sprintf("  Throughput:        %.1f tokens/sec\n", tps))
+	}
+	if usage.Estimated {
+		sb.WriteString(Subtle.Render("  Includes estimated counts (~): the server did not report usage for some responses") + "\n")
+	}
+
+	return sb.String()
+}
+
+// FormatUsageBreakdown formats per-turn and per-iteration usage, showing
+// the most recent maxTurns turns
+func (r *Renderer) FormatUsageBreakdown(turns []storage.TurnUsage, maxTurns int) string {
+	if len(turns) == 0 {
+		return ""
+	}
+	skipped := 0
+	if maxTurns > 0 && len(turns) > maxTurns {
+		skipped = len(turns) - maxTurns
+		turns = turns[skipped:]
+	}
+
+	var sb strings.Builder
+	sb.WriteString(SessionStyle.Render(IconInfo+" By Turn") + "\n")
+	if skipped > 0 {
+		sb.WriteString(Subtle.Render(fmt.Sprintf("  (%d earlier turns not shown)", skipped)) + "\n")
+	}
+	for _, turn := range turns {
+		prompt := strings.Join(strings.Fields(turn.Prompt), " ")
+		if len(prompt) > 50 {
+			prompt = prompt[:47] + "..."
+		}
+		sb.WriteString(fmt.Sprintf("  Turn %d: %s\n", turn.Turn, Subtle.Render(prompt)))
+		for _, it := range turn.Iterations {
+			marker := " "
+			if it.Usage.Estimated {
+				marker = "~"
+			}
+			line := fmt.Sprintf("    #%d %s%6d prompt  %5d completion", it.Iteration, marker, it.Usage.PromptTokens, it.Usage.CompletionTokens)
+			if tps := it.Usage.TokensPerSecond(); tps > 0 {
+				line += fmt.Sprintf("  %6.1f tok/s", tps)
+			}
+			if len(it.Tools) > 0 {
+				line += "  " + Subtle.Render(IconArrow+" "+strings.Join(it.Tools, ", "))
+			}
+			sb.WriteString(line + "\n")
+		}
+		if len(turn.Iterations) > 1 {
+			sb.WriteString(Subtle.Render(fmt.Sprintf("    total %d tokens over %d iterations", turn.Total.TotalTokens, len(turn.Iterations))) + "\n")
+		}
+	}
 
 	return sb.String()
 }
 
+// FormatUsageReport formats usage aggregated across sessions
+func (r *Renderer) FormatUsageReport(report *storage.UsageReport) string {
+	if report == nil || report.Total.TotalTokens == 0 {
+		return Subtle.Render("No token usage recorded in this period.")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(SessionStyle.Render(fmt.Sprintf("%s Token usage since %s", IconInfo, report.Since.Format("2006-01-02 15:04"))) + "\n")
+	sb.WriteString(fmt.Sprintf("  Sessions: %d   Turns: %d\n", report.Sessions, report.Turns))
+	sb.WriteString(fmt.Sprintf("  Prompt: %d   Completion: %d   Total: %d\n",
+		report.Total.PromptTokens, report.Total.CompletionTokens, report.Total.TotalTokens))
+	if tps := report.Total.TokensPerSecond(); tps > 0 {
+		sb.WriteString(fmt.Sprintf("  Throughput: %.1f tokens/sec\n", tps))
+	}
+
+	sb.WriteString("\n" + SessionStyle.Render("  By model") + "\n")
+	for _, model := range report.Models() {
+		usage := report.ByModel[model]
+		sb.WriteString(fmt.Sprintf("    %-30s %10d tokens  %6.1f tok/s\n", model, usage.TotalTokens, usage.TokensPerSecond()))
+	}
+
+	sb.WriteString("\n" + SessionStyle.Render("  By day") + "\n")
+	for _, day := range report.Days() {
+		usage := report.ByDay[day]
+		sb.WriteString(fmt.Sprintf("    %s %10d tokens\n", day, usage.TotalTokens))
+	}
+
+	return sb.String()
+}
+
+// FormatReplayReport formats the differences between a recorded session
+// and its replay
+func (r *Renderer) FormatReplayReport(report *cassette.Report) string {
+	var sb strings.Builder
+	sb.WriteString(SessionStyle.Render(fmt.Sprintf("%s Replayed %d turns", IconInfo, len(report.Turns)+report.Missing)) + "\n")
+	if report.PromptChanged {
+		sb.WriteString("  " + WarningStyle.Render(IconWarning+" System prompt changed since the recording") + "\n")
+	}
+
+	for _, turn := range report.Turns {
+		title := fmt.Sprintf("Turn %d: %s", turn.Turn, snippet(turn.Input, 60))
+		if turn.Same() {
+			sb.WriteString("  " + SuccessStyle.Render(IconSuccess+" "+title) + "\n")
+			continue
+		}
+		sb.WriteString("  " + ToolError.Render(IconError+" "+title) + "\n")
+		if turn.ToolsDiffer {
+			sb.WriteString("      Tools recorded: " + formatCalls(turn.RecordedTools) + "\n")
+			sb.WriteString("      Tools replayed: " + formatCalls(turn.ReplayedTools) + "\n")
+		}
+		if turn.AnswerDiffers {
+			sb.WriteString("      Answer recorded: " + formatAnswer(turn.RecordedAnswer, turn.RecordedError) + "\n")
+			sb.WriteString("      Answer replayed: " + formatAnswer(turn.ReplayedAnswer, turn.ReplayedError) + "\n")
+		}
+	}
+	if report.Missing > 0 {
+		sb.WriteString("  " + ToolError.Render(fmt.Sprintf("%s %d recorded turns were not replayed", IconError, report.Missing)) + "\n")
+	}
+
+	if n := report.Differences(); n > 0 {
+		sb.WriteString(WarningStyle.Render(fmt.Sprintf("%d of %d turns differ", n, len(report.Turns)+report.Missing)) + "\n")
+	} else {
+		sb.WriteString(SuccessStyle.Render("All turns match the recording") + "\n")
+	}
+	return sb.String()
+}
+
+func formatCalls(calls []string) string {
+	if len(calls) == 0 {
+		return Subtle.Render("(none)")
+	}
+	parts := make([]string, len(calls))
+	for i, call := range calls {
+		parts[i] = snippet(call, 60)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatAnswer(answer, err string) string {
+	if err != "" {
+		return ToolError.Render("error: " + snippet(err, 80))
+	}
+	if answer == "" {
+		return Subtle.Render("(none)")
+	}
+	return snippet(answer, 80)
+}
+
+// snippet shortens text to one line of at most limit runes
+func snippet(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > limit {
+		return string(runes[:limit-3]) + "..."
+	}
+	return text
+}
+
 // ProviderMessage formats provider information for display
 func (r *Renderer) ProviderMessage(info *provider.Info) string {
 	if info == nil {
@@ -250,3 +491,48 @@ func (r *Renderer) ProviderMessage(info *provider.Info) string {
 	}
 	return SuccessStyle.Render(fmt.Sprintf("%s Connected to %s", IconSuccess, info.Name)) + "\n"
 }
+
+// CapabilityReport formats a capability probe report for display
+func (r *Renderer) CapabilityReport(caps *provider.Capabilities) string {
+	if caps == nil {
+		return Subtle.Render("No capabilities probed yet.")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(SessionStyle.Render(fmt.Sprintf("%s Capabilities for %s", IconInfo, caps.Model)) + "\n")
+	for _, check := range caps.Checks {
+		line := fmt.Sprintf("%s %-18s %s", IconSuccess, check.Name, check.Detail)
+		style := SuccessStyle
+		if !check.Supported {
+			line = fmt.Sprintf("%s %-18s %s", IconError, check.Name, check.Detail)
+			style = WarningStyle
+		}
+		sb.WriteString("  " + style.Render(line) + Subtle.Render(fmt.Sprintf(" (%dms)", check.Duration)) + "\n")
+	}
+	if caps.MaxContext > 0 {
+		sb.WriteString(fmt.Sprintf("  Context window: %d tokens\n", caps.MaxContext))
+	}
+	sb.WriteString(Subtle.Render(fmt.Sprintf("  Probed %s", caps.ProbedAt.Format("2006-01-02 15:04"))) + "\n")
+
+	return sb.String()
+}
+
+// FormatReasoning formats model reasoning dimmed, showing at most maxLines
+// lines (0 for all)
+func (r *Renderer) FormatReasoning(reasoning string, maxLines int) string {
+	lines := strings.Split(strings.TrimSpace(reasoning), "\n")
+	hidden := 0
+	if maxLines > 0 && len(lines) > maxLines {
+		hidden = len(lines) - maxLines
+		lines = lines[:maxLines]
+	}
+
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(Subtle.Render("│ "+line) + "\n")
+	}
+	if hidden > 0 {
+		sb.WriteString(Subtle.Render(fmt.Sprintf("│ … %d more lines (/think show full to expand)", hidden)) + "\n")
+	}
+	return sb.String()
+}